@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+func (ctx *Context) AccountHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleAccountGet(w, &userID)
+	case http.MethodPost:
+		ctx.HandleAccountInsert(w, r, &userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) AccountHandlerByID(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	idStr := strings.TrimPrefix(r.URL.Path, "/account/id/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	accountID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleAccountGetByID(w, &userID, &accountID)
+	case http.MethodPut:
+		ctx.HandleAccountUpdate(w, r, &userID, &accountID)
+	case http.MethodDelete:
+		ctx.HandleAccountDelete(w, &userID, &accountID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) NetWorthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	netWorth, errorResp := logic.GetNetWorth(ctx.Db, &userID, r.URL.Query().Get("currency"))
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(netWorth)
+	fmt.Println("Retrieved net worth for user ID:", userID)
+}
+
+func (ctx *Context) HandleAccountGet(w http.ResponseWriter, userID *uuid.UUID) {
+	accounts, errorResp := logic.GetAccounts(ctx.Db, userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+	fmt.Println("Retrieved accounts for user ID:", userID)
+}
+
+func (ctx *Context) HandleAccountGetByID(w http.ResponseWriter, userID *uuid.UUID, accountID *uuid.UUID) {
+	account, errorResp := logic.GetAccountByID(ctx.Db, userID, accountID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+	fmt.Println("Retrieved account with ID:", accountID)
+}
+
+func (ctx *Context) HandleAccountInsert(w http.ResponseWriter, r *http.Request, userID *uuid.UUID) {
+	var account database.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	newAccount, errorResp := logic.CreateAccount(ctx.Db, userID, &account)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newAccount)
+	fmt.Println("Inserted account with ID:", newAccount.ID)
+}
+
+func (ctx *Context) HandleAccountUpdate(w http.ResponseWriter, r *http.Request, userID *uuid.UUID, accountID *uuid.UUID) {
+	var accountForUpdate logic.AccountForUpdate
+	if err := json.NewDecoder(r.Body).Decode(&accountForUpdate); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	account, errorResp := logic.UpdateAccount(ctx.Db, userID, accountID, &accountForUpdate)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+	fmt.Println("Updated account with ID:", accountID)
+}
+
+func (ctx *Context) HandleAccountDelete(w http.ResponseWriter, userID *uuid.UUID, accountID *uuid.UUID) {
+	errorResp := logic.DeleteAccount(ctx.Db, userID, accountID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Deleted account with ID:", accountID)
+}
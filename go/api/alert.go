@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+func (ctx *Context) AlertHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleAlertGet(w, &userID)
+	case http.MethodPost:
+		ctx.HandleAlertInsert(w, r, &userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) AlertHandlerByID(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	idStr := strings.TrimPrefix(r.URL.Path, "/alert/id/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ruleID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleAlertGetByID(w, &userID, &ruleID)
+	case http.MethodPut:
+		ctx.HandleAlertUpdate(w, r, &userID, &ruleID)
+	case http.MethodDelete:
+		ctx.HandleAlertDelete(w, &userID, &ruleID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) AlertDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/alert/deliveries/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ruleID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, errorResp := logic.GetAlertRuleDeliveries(ctx.Db, &userID, &ruleID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+	fmt.Println("Retrieved", len(deliveries), "deliveries of alert rule with ID:", ruleID)
+}
+
+func (ctx *Context) HandleAlertGet(w http.ResponseWriter, userID *uuid.UUID) {
+	rules, errorResp := logic.GetAlertRules(ctx.Db, userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+	fmt.Println("Retrieved alert rules for user ID:", userID)
+}
+
+func (ctx *Context) HandleAlertGetByID(w http.ResponseWriter, userID *uuid.UUID, ruleID *uuid.UUID) {
+	rule, errorResp := logic.GetAlertRuleByID(ctx.Db, userID, ruleID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+	fmt.Println("Retrieved alert rule with ID:", ruleID)
+}
+
+func (ctx *Context) HandleAlertInsert(w http.ResponseWriter, r *http.Request, userID *uuid.UUID) {
+	var rule database.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	newRule, errorResp := logic.CreateAlertRule(ctx.Db, userID, &rule)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newRule)
+	fmt.Println("Inserted alert rule with ID:", newRule.ID)
+}
+
+func (ctx *Context) HandleAlertUpdate(w http.ResponseWriter, r *http.Request, userID *uuid.UUID, ruleID *uuid.UUID) {
+	var ruleForUpdate logic.AlertRuleForUpdate
+	if err := json.NewDecoder(r.Body).Decode(&ruleForUpdate); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	rule, errorResp := logic.UpdateAlertRule(ctx.Db, userID, ruleID, &ruleForUpdate)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+	fmt.Println("Updated alert rule with ID:", ruleID)
+}
+
+func (ctx *Context) HandleAlertDelete(w http.ResponseWriter, userID *uuid.UUID, ruleID *uuid.UUID) {
+	errorResp := logic.DeleteAlertRule(ctx.Db, userID, ruleID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Deleted alert rule with ID:", ruleID)
+}
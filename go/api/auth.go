@@ -21,7 +21,7 @@ func (ctx *Context) WithAuth(next http.Handler) http.Handler {
 			return
 		}
 		auth = strings.TrimPrefix(auth, "Bearer ")
-		userID, err := logic.ValidateToken(ctx.Db, auth)
+		token, err := logic.ValidateAccessToken(ctx.Db, ctx.AccessTokens, auth)
 		if err != nil {
 			fmt.Println("Could not validate token:", err.Error())
 			http.Error(w, err.Error(), http.StatusUnauthorized)
@@ -29,7 +29,11 @@ func (ctx *Context) WithAuth(next http.Handler) http.Handler {
 		}
 
 		// If authentication succeeds, proceed to the next handler
-		ctx := context.WithValue(r.Context(), "userID", userID)
+		ctx := context.WithValue(r.Context(), "userID", token.UserID)
+		ctx = context.WithValue(ctx, "tokenExpiry", token.Expiry)
+		if token.SessionID != nil {
+			ctx = context.WithValue(ctx, "sessionID", *token.SessionID)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -41,8 +45,10 @@ func (ctx *Context) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	var loginReq logic.LoginRequest
 	json.NewDecoder(r.Body).Decode(&loginReq)
+	loginReq.IPAddress = ctx.clientIP(r)
+	loginReq.UserAgent = r.UserAgent()
 
-	token, err := logic.Login(ctx.Db, &loginReq)
+	token, err := logic.Login(ctx.Db, ctx.AccessTokens, &loginReq)
 	if err.Code != http.StatusOK {
 		http.Error(w, err.Message, err.Code)
 		return
@@ -52,6 +58,28 @@ func (ctx *Context) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(token)
 }
 
+// RefreshHandler exchanges a refresh token for new tokens of its session.
+func (ctx *Context) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var request logic.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	tokens, errorResp := logic.RefreshSession(ctx.Db, ctx.AccessTokens, &request)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
 func (ctx *Context) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -110,7 +138,7 @@ func (ctx *Context) ResetPasswordHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	errorResp := logic.ResetPassword(ctx.Db, &request.Token, request.NewPassword)
+	errorResp := logic.ResetPassword(ctx.Db, ctx.AccessTokens, request.Token, request.NewPassword)
 	if errorResp.Code != http.StatusOK {
 		http.Error(w, errorResp.Message, errorResp.Code)
 		return
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+func (ctx *Context) BudgetHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleBudgetGet(w, &userID)
+	case http.MethodPost:
+		ctx.HandleBudgetInsert(w, r, &userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) BudgetHandlerByID(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	idStr := strings.TrimPrefix(r.URL.Path, "/budget/id/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	budgetID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleBudgetGetByID(w, &userID, &budgetID)
+	case http.MethodPut:
+		ctx.HandleBudgetUpdate(w, r, &userID, &budgetID)
+	case http.MethodDelete:
+		ctx.HandleBudgetDelete(w, &userID, &budgetID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// BudgetStatusHandler reports the current period of all budgets, or all
+// periods of a single budget when its ID follows /budget/status/.
+func (ctx *Context) BudgetStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	idStr := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/budget/status"), "/")
+	if idStr == "" {
+		status, errorResp := logic.GetAllBudgetStatus(ctx.Db, &userID)
+		if errorResp.Code != http.StatusOK {
+			http.Error(w, errorResp.Message, errorResp.Code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+		fmt.Println("Retrieved status of", len(status), "budgets for user ID:", userID)
+		return
+	}
+
+	budgetID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	status, errorResp := logic.GetBudgetStatus(ctx.Db, &userID, &budgetID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+	fmt.Println("Retrieved status of budget with ID:", budgetID)
+}
+
+func (ctx *Context) HandleBudgetGet(w http.ResponseWriter, userID *uuid.UUID) {
+	budgets, errorResp := logic.GetBudgets(ctx.Db, userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budgets)
+	fmt.Println("Retrieved budgets for user ID:", userID)
+}
+
+func (ctx *Context) HandleBudgetGetByID(w http.ResponseWriter, userID *uuid.UUID, budgetID *uuid.UUID) {
+	budget, errorResp := logic.GetBudgetByID(ctx.Db, userID, budgetID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+	fmt.Println("Retrieved budget with ID:", budgetID)
+}
+
+func (ctx *Context) HandleBudgetInsert(w http.ResponseWriter, r *http.Request, userID *uuid.UUID) {
+	var budget database.Budget
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	newBudget, errorResp := logic.CreateBudget(ctx.Db, userID, &budget)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newBudget)
+	fmt.Println("Inserted budget with ID:", newBudget.ID)
+}
+
+func (ctx *Context) HandleBudgetUpdate(w http.ResponseWriter, r *http.Request, userID *uuid.UUID, budgetID *uuid.UUID) {
+	var budgetForUpdate logic.BudgetForUpdate
+	if err := json.NewDecoder(r.Body).Decode(&budgetForUpdate); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	budget, errorResp := logic.UpdateBudget(ctx.Db, userID, budgetID, &budgetForUpdate)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+	fmt.Println("Updated budget with ID:", budgetID)
+}
+
+func (ctx *Context) HandleBudgetDelete(w http.ResponseWriter, userID *uuid.UUID, budgetID *uuid.UUID) {
+	errorResp := logic.DeleteBudget(ctx.Db, userID, budgetID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Deleted budget with ID:", budgetID)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+// DigestHandler gets the user's digest subscription, subscribes or changes
+// the frequency with PUT, and unsubscribes with DELETE.
+func (ctx *Context) DigestHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleDigestGet(w, r)
+	case http.MethodPut:
+		ctx.HandleDigestPut(w, r)
+	case http.MethodDelete:
+		ctx.HandleDigestDelete(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) HandleDigestGet(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	digest, errorResp := logic.GetDigest(ctx.Db, &userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(digest)
+	fmt.Println("Retrieved digest for user ID:", userID)
+}
+
+func (ctx *Context) HandleDigestPut(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var digestForUpdate logic.DigestForUpdate
+	if err := json.NewDecoder(r.Body).Decode(&digestForUpdate); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	digest, errorResp := logic.SubscribeDigest(ctx.Db, &userID, &digestForUpdate)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(digest)
+	fmt.Println("Subscribed user ID:", userID, "to", digest.Frequency, "digests")
+}
+
+func (ctx *Context) HandleDigestDelete(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	errorResp := logic.UnsubscribeDigest(ctx.Db, &userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Unsubscribed user ID:", userID, "from digests")
+}
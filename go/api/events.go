@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+// How often a comment is sent on an idle stream, so proxies keep it open
+const eventsKeepAlive = 30 * time.Second
+
+// EventsHandler streams the user's balance changes as Server-Sent Events
+// until the client disconnects. Each event holds the recomputed entries of
+// the changed account. The stream is closed when the access token it was
+// opened with expires or its session is revoked, so the client reconnects
+// with a fresh token.
+func (ctx *Context) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+	expiry := r.Context().Value("tokenExpiry").(time.Time)
+	sessionID, hasSession := r.Context().Value("sessionID").(uuid.UUID)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := ctx.Events.Subscribe(userID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	fmt.Println("Opened event stream for user ID:", userID)
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	expired := time.NewTimer(time.Until(expiry))
+	defer expired.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			fmt.Println("Closed event stream for user ID:", userID)
+			return
+		case event := <-events:
+			if err := logic.WriteStreamEvent(w, event); err != nil {
+				fmt.Println("Error writing event:", err)
+				return
+			}
+			flusher.Flush()
+		case <-expired.C:
+			fmt.Println("Closed event stream with expired token for user ID:", userID)
+			return
+		case <-keepAlive.C:
+			// The session is checked as often as the stream is kept alive
+			if hasSession && !logic.SessionActive(ctx.Db, &sessionID) {
+				fmt.Println("Closed event stream of revoked session for user ID:", userID)
+				return
+			}
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+func (ctx *Context) ExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleExchangeRateGet(w, r)
+	case http.MethodPost:
+		ctx.HandleExchangeRateImport(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) HandleExchangeRateGet(w http.ResponseWriter, r *http.Request) {
+	date := time.Now()
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		parsed, err := time.Parse(time.DateOnly, dateStr)
+		if err != nil {
+			http.Error(w, "Invalid date", http.StatusBadRequest)
+			return
+		}
+		date = parsed
+	}
+
+	rates, errorResp := logic.GetExchangeRates(ctx.Db, date)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+	fmt.Println("Retrieved", len(rates), "exchange rates")
+}
+
+// HandleExchangeRateImport loads the rate file sent as request body. The
+// format query parameter selects between csv (default) and ecb.
+func (ctx *Context) HandleExchangeRateImport(w http.ResponseWriter, r *http.Request) {
+	actorID := r.Context().Value("userID").(uuid.UUID)
+	admin, err := logic.CheckRole(ctx.Db, &actorID, "admin")
+	if err != nil {
+		http.Error(w, "Error checking user roles", http.StatusInternalServerError)
+		return
+	}
+	if !admin {
+		http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = logic.ExchangeRateFormatCSV
+	}
+
+	count, errorResp := logic.ImportExchangeRates(ctx.Db, r.Body, format)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Imported", count, "exchange rates")
+}
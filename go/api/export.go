@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+// ExportHandler streams all data of the user as ?format=csv, json or ofx,
+// limited to one account with ?account_id=. JSON is the default.
+func (ctx *Context) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+	query := r.URL.Query()
+
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = logic.ExportFormatJSON
+	}
+
+	var accountID *uuid.UUID
+	if accountStr := query.Get("account_id"); accountStr != "" {
+		parsed, err := uuid.Parse(accountStr)
+		if err != nil {
+			http.Error(w, "Invalid account ID", http.StatusBadRequest)
+			return
+		}
+		accountID = &parsed
+	}
+
+	export, errorResp := logic.NewExport(ctx.Db, &userID, accountID, format)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.FileName()))
+	// The status is sent with the first write, so errors can only cut the
+	// export short
+	if err := export.Write(w); err != nil {
+		fmt.Println("Error writing export:", err)
+		return
+	}
+	fmt.Println("Exported data as", format, "for user ID:", userID)
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+func (ctx *Context) GoalHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleGoalGet(w, &userID)
+	case http.MethodPost:
+		ctx.HandleGoalInsert(w, r, &userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) GoalHandlerByID(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	idStr := strings.TrimPrefix(r.URL.Path, "/goal/id/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	goalID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleGoalGetByID(w, &userID, &goalID)
+	case http.MethodPut:
+		ctx.HandleGoalUpdate(w, r, &userID, &goalID)
+	case http.MethodDelete:
+		ctx.HandleGoalDelete(w, &userID, &goalID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GoalProgressHandler reports the progress of all goals, or of a single goal
+// when its ID follows /goal/progress/.
+func (ctx *Context) GoalProgressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	idStr := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/goal/progress"), "/")
+	if idStr == "" {
+		progress, errorResp := logic.GetAllGoalProgress(ctx.Db, &userID)
+		if errorResp.Code != http.StatusOK {
+			http.Error(w, errorResp.Message, errorResp.Code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(progress)
+		fmt.Println("Retrieved progress of", len(progress), "goals for user ID:", userID)
+		return
+	}
+
+	goalID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	progress, errorResp := logic.GetGoalProgress(ctx.Db, &userID, &goalID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+	fmt.Println("Retrieved progress of goal with ID:", goalID)
+}
+
+func (ctx *Context) HandleGoalGet(w http.ResponseWriter, userID *uuid.UUID) {
+	goals, errorResp := logic.GetGoals(ctx.Db, userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goals)
+	fmt.Println("Retrieved goals for user ID:", userID)
+}
+
+func (ctx *Context) HandleGoalGetByID(w http.ResponseWriter, userID *uuid.UUID, goalID *uuid.UUID) {
+	goal, errorResp := logic.GetGoalByID(ctx.Db, userID, goalID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goal)
+	fmt.Println("Retrieved goal with ID:", goalID)
+}
+
+func (ctx *Context) HandleGoalInsert(w http.ResponseWriter, r *http.Request, userID *uuid.UUID) {
+	var goal database.Goal
+	if err := json.NewDecoder(r.Body).Decode(&goal); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	newGoal, errorResp := logic.CreateGoal(ctx.Db, userID, &goal)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newGoal)
+	fmt.Println("Inserted goal with ID:", newGoal.ID)
+}
+
+func (ctx *Context) HandleGoalUpdate(w http.ResponseWriter, r *http.Request, userID *uuid.UUID, goalID *uuid.UUID) {
+	var goalForUpdate logic.GoalForUpdate
+	if err := json.NewDecoder(r.Body).Decode(&goalForUpdate); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	goal, errorResp := logic.UpdateGoal(ctx.Db, userID, goalID, &goalForUpdate)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goal)
+	fmt.Println("Updated goal with ID:", goalID)
+}
+
+func (ctx *Context) HandleGoalDelete(w http.ResponseWriter, userID *uuid.UUID, goalID *uuid.UUID) {
+	errorResp := logic.DeleteGoal(ctx.Db, userID, goalID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Deleted goal with ID:", goalID)
+}
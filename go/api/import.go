@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+// Largest statement accepted as request body
+const maxStatementSize = 10 << 20
+
+// BalanceImportHandler imports the bank statement sent as request body. The
+// query selects the account, the format (csv, ofx, qfx, qif, camt053 or mt940)
+// and, for CSV, the columns holding date, amount, balance and description. QIF
+// dates are read as D/M/Y with date_order=dmy. With preview=true the parsed
+// rows are returned without storing them.
+func (ctx *Context) BalanceImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+	query := r.URL.Query()
+
+	options := logic.ImportOptions{
+		Format: strings.ToLower(query.Get("format")),
+		Mapping: logic.CSVMapping{
+			Date:         query.Get("date"),
+			Amount:       query.Get("amount"),
+			Balance:      query.Get("balance"),
+			Description:  query.Get("description"),
+			DateFormat:   query.Get("date_format"),
+			DecimalComma: query.Get("decimal") == "comma",
+		},
+		DayFirst: query.Get("date_order") == "dmy",
+	}
+	if options.Format == "" {
+		options.Format = logic.StatementFormatCSV
+	}
+	if accountStr := query.Get("account_id"); accountStr != "" {
+		accountID, err := uuid.Parse(accountStr)
+		if err != nil {
+			http.Error(w, "Invalid account ID", http.StatusBadRequest)
+			return
+		}
+		options.AccountID = accountID
+	}
+	if delimiter := query.Get("delimiter"); delimiter != "" {
+		if utf8.RuneCountInString(delimiter) != 1 {
+			http.Error(w, "Invalid delimiter", http.StatusBadRequest)
+			return
+		}
+		options.Mapping.Delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	}
+	if previewStr := query.Get("preview"); previewStr != "" {
+		preview, err := strconv.ParseBool(previewStr)
+		if err != nil {
+			http.Error(w, "Invalid preview", http.StatusBadRequest)
+			return
+		}
+		options.Preview = preview
+	}
+
+	result, errorResp := logic.ImportStatement(ctx.Db, &userID, &options, http.MaxBytesReader(w, r.Body, maxStatementSize))
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+	fmt.Println("Imported", result.Imported, "balances, skipped", result.Duplicates, "duplicates for user ID:", userID)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Leander-s/money_manager/db"
 	"github.com/Leander-s/money_manager/logic"
@@ -16,7 +17,7 @@ func (ctx *Context) BalanceHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(uuid.UUID)
 	switch r.Method {
 	case http.MethodGet:
-		ctx.HandleBalanceGet(w, &userID)
+		ctx.HandleBalanceGet(w, r, &userID)
 	case http.MethodPost:
 		ctx.HandleBalanceInsert(w, r, &userID)
 	default:
@@ -41,7 +42,7 @@ func (ctx *Context) BalanceHandlerByID(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		ctx.HandleBalanceGetByID(w, &balanceID)
 	case http.MethodDelete:
-		ctx.HandleBalanceDelete(w, &balanceID)
+		ctx.HandleBalanceDelete(w, r, &balanceID)
 	case http.MethodPut:
 		ctx.HandleBalanceUpdate(w, r, &balanceID)
 	default:
@@ -72,9 +73,44 @@ func (ctx *Context) BalanceHandlerByCount(w http.ResponseWriter, r *http.Request
 	}
 }
 
-func (ctx *Context) BudgetHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("Received balance", r.Method, "request from:", r.RemoteAddr)
-	fmt.Fprintln(w, "Budget Path Accessed with method:", r.Method)
+// BalanceForecastHandler projects balance and budget of an account over the
+// next ?days=, the default account is used without ?account_id=.
+func (ctx *Context) BalanceForecastHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var days int
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		var err error
+		days, err = strconv.Atoi(daysStr)
+		if err != nil {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var accountID uuid.UUID
+	if accountStr := r.URL.Query().Get("account_id"); accountStr != "" {
+		var err error
+		accountID, err = uuid.Parse(accountStr)
+		if err != nil {
+			http.Error(w, "Invalid account ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	forecast, errorResp := logic.GetForecast(ctx.Db, &userID, &accountID, days)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast)
+	fmt.Println("Retrieved", forecast.Days, "day forecast for account ID:", forecast.AccountID)
 }
 
 func (ctx *Context) HandleBalanceUpdate(w http.ResponseWriter, r *http.Request, balanceID *uuid.UUID) {
@@ -103,8 +139,9 @@ func (ctx *Context) HandleBalanceUpdate(w http.ResponseWriter, r *http.Request,
 	fmt.Println("Updated entry with ID:", balanceID)
 }
 
-func (ctx *Context) HandleBalanceDelete(w http.ResponseWriter, balanceID *uuid.UUID) {
-	entries, errorResp := logic.DeleteBalance(ctx.Db, balanceID)
+func (ctx *Context) HandleBalanceDelete(w http.ResponseWriter, r *http.Request, balanceID *uuid.UUID) {
+	actorID := r.Context().Value("userID").(uuid.UUID)
+	entries, errorResp := logic.DeleteBalance(ctx.Db, &actorID, balanceID)
 	if errorResp.Code != http.StatusOK {
 		http.Error(w, errorResp.Message, errorResp.Code)
 		return
@@ -127,12 +164,69 @@ func (ctx *Context) HandleBalanceGetByID(w http.ResponseWriter, balanceID *uuid.
 	fmt.Println("Retrieved balance with ID:", balanceID)
 }
 
-func (ctx *Context) HandleBalanceGet(w http.ResponseWriter, id *uuid.UUID) { 
-	balances, errorResp := logic.GetAllBalances(ctx.Db, id)
+// HandleBalanceGet returns all balances, or a page of them when any of from,
+// to, cursor or limit is given. The cursor of the next page is sent in the
+// X-Next-Cursor header.
+func (ctx *Context) HandleBalanceGet(w http.ResponseWriter, r *http.Request, id *uuid.UUID) {
+	var balances []*database.MoneyEntry
+	var errorResp logic.ErrorResponse
+	query := r.URL.Query()
+
+	var accountID *uuid.UUID
+	if accountStr := query.Get("account_id"); accountStr != "" {
+		parsed, err := uuid.Parse(accountStr)
+		if err != nil {
+			http.Error(w, "Invalid account ID", http.StatusBadRequest)
+			return
+		}
+		accountID = &parsed
+	}
+
+	if query.Has("from") || query.Has("to") || query.Has("cursor") || query.Has("limit") {
+		balanceQuery := logic.BalanceQuery{
+			AccountID: accountID,
+			Cursor:    query.Get("cursor"),
+		}
+		var err error
+		if balanceQuery.From, err = parseTimeParam(query.Get("from"), false); err != nil {
+			http.Error(w, "Invalid from", http.StatusBadRequest)
+			return
+		}
+		if balanceQuery.To, err = parseTimeParam(query.Get("to"), true); err != nil {
+			http.Error(w, "Invalid to", http.StatusBadRequest)
+			return
+		}
+		if limitStr := query.Get("limit"); limitStr != "" {
+			if balanceQuery.Limit, err = strconv.Atoi(limitStr); err != nil {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var page *logic.BalancePage
+		page, errorResp = logic.GetBalancePage(ctx.Db, id, &balanceQuery)
+		if errorResp.Code == http.StatusOK {
+			balances = page.Entries
+			if page.NextCursor != "" {
+				w.Header().Set("X-Next-Cursor", page.NextCursor)
+			}
+		}
+	} else if accountID != nil {
+		balances, errorResp = logic.GetBalancesByAccount(ctx.Db, id, accountID)
+	} else {
+		balances, errorResp = logic.GetAllBalances(ctx.Db, id)
+	}
 	if errorResp.Code != http.StatusOK {
 		http.Error(w, errorResp.Message, errorResp.Code)
 		return
 	}
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		balances, errorResp = logic.ConvertBalances(ctx.Db, balances, currency)
+		if errorResp.Code != http.StatusOK {
+			http.Error(w, errorResp.Message, errorResp.Code)
+			return
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(balances)
 	fmt.Println("Retrieved balances for user ID:", id)
@@ -167,3 +261,24 @@ func (ctx *Context) HandleBalanceInsert(w http.ResponseWriter, r *http.Request,
 	json.NewEncoder(w).Encode(newEntry)
 	fmt.Println("Inserted balance with ID:", entry.ID)
 }
+
+// parseTimeParam parses an RFC 3339 timestamp or a plain date. A plain date
+// used as the exclusive end of a range is moved to the next day, so the whole
+// day is included. Returns nil for an empty value.
+func parseTimeParam(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+func (ctx *Context) RecurringHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleRecurringGet(w, &userID)
+	case http.MethodPost:
+		ctx.HandleRecurringInsert(w, r, &userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) RecurringHandlerByID(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	idStr := strings.TrimPrefix(r.URL.Path, "/recurring/id/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	recurringID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleRecurringGetByID(w, &userID, &recurringID)
+	case http.MethodPut:
+		ctx.HandleRecurringUpdate(w, r, &userID, &recurringID)
+	case http.MethodDelete:
+		ctx.HandleRecurringDelete(w, &userID, &recurringID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) HandleRecurringGet(w http.ResponseWriter, userID *uuid.UUID) {
+	schedules, errorResp := logic.GetRecurring(ctx.Db, userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+	fmt.Println("Retrieved recurring schedules for user ID:", userID)
+}
+
+func (ctx *Context) HandleRecurringGetByID(w http.ResponseWriter, userID *uuid.UUID, recurringID *uuid.UUID) {
+	recurring, errorResp := logic.GetRecurringByID(ctx.Db, userID, recurringID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recurring)
+	fmt.Println("Retrieved recurring schedule with ID:", recurringID)
+}
+
+func (ctx *Context) HandleRecurringInsert(w http.ResponseWriter, r *http.Request, userID *uuid.UUID) {
+	var recurring database.Recurring
+	if err := json.NewDecoder(r.Body).Decode(&recurring); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	newRecurring, errorResp := logic.CreateRecurring(ctx.Db, userID, &recurring)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newRecurring)
+	fmt.Println("Inserted recurring schedule with ID:", newRecurring.ID)
+}
+
+func (ctx *Context) HandleRecurringUpdate(w http.ResponseWriter, r *http.Request, userID *uuid.UUID, recurringID *uuid.UUID) {
+	var recurringForUpdate logic.RecurringForUpdate
+	if err := json.NewDecoder(r.Body).Decode(&recurringForUpdate); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	recurring, errorResp := logic.UpdateRecurring(ctx.Db, userID, recurringID, &recurringForUpdate)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recurring)
+	fmt.Println("Updated recurring schedule with ID:", recurringID)
+}
+
+func (ctx *Context) HandleRecurringDelete(w http.ResponseWriter, userID *uuid.UUID, recurringID *uuid.UUID) {
+	errorResp := logic.DeleteRecurring(ctx.Db, userID, recurringID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Deleted recurring schedule with ID:", recurringID)
+}
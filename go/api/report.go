@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+// ReportHandler aggregates the balances of an account per ?period=monthly or
+// yearly, optionally limited to ?from= and ?to=. The default account is used
+// without ?account_id=.
+func (ctx *Context) ReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+	query := r.URL.Query()
+
+	var accountID uuid.UUID
+	if accountStr := query.Get("account_id"); accountStr != "" {
+		var err error
+		accountID, err = uuid.Parse(accountStr)
+		if err != nil {
+			http.Error(w, "Invalid account ID", http.StatusBadRequest)
+			return
+		}
+	}
+	from, err := parseTimeParam(query.Get("from"), false)
+	if err != nil {
+		http.Error(w, "Invalid from", http.StatusBadRequest)
+		return
+	}
+	to, err := parseTimeParam(query.Get("to"), true)
+	if err != nil {
+		http.Error(w, "Invalid to", http.StatusBadRequest)
+		return
+	}
+
+	report, errorResp := logic.GetReport(ctx.Db, &userID, &accountID, strings.ToLower(query.Get("period")), from, to)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+	fmt.Println("Retrieved", report.Period, "report for user ID:", userID)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"net/netip"
 
 	"github.com/Leander-s/money_manager/db"
 	"github.com/Leander-s/money_manager/logic"
@@ -21,6 +22,12 @@ type Context struct {
 	FronendAddress string
 	// Flag indicating if there are no users in the database
 	NoUsers        bool
+	// Open event streams of the users
+	Events         *logic.BalanceHub
+	// Signer of stateless access tokens, nil if opaque tokens are used
+	AccessTokens   *logic.AccessTokenSigner
+	// Proxies whose X-Forwarded-For header is trusted
+	TrustedProxies []netip.Prefix
 }
 
 func (ctx *Context) RootHandler(w http.ResponseWriter, r *http.Request) {
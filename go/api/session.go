@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+// SessionHandler lists the user's sessions, or logs out everywhere on
+// DELETE. With ?except_current=true the current session stays logged in.
+func (ctx *Context) SessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	var currentID *uuid.UUID
+	if sessionID, ok := r.Context().Value("sessionID").(uuid.UUID); ok {
+		currentID = &sessionID
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleSessionGet(w, &userID, currentID)
+	case http.MethodDelete:
+		keepID := currentID
+		if r.URL.Query().Get("except_current") != "true" {
+			keepID = nil
+		}
+		ctx.HandleSessionDeleteAll(w, &userID, keepID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) SessionHandlerByID(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	idStr := strings.TrimPrefix(r.URL.Path, "/session/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	sessionID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		ctx.HandleSessionDelete(w, &userID, &sessionID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) HandleSessionGet(w http.ResponseWriter, userID *uuid.UUID, currentID *uuid.UUID) {
+	sessions, errorResp := logic.GetSessions(ctx.Db, userID, currentID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+	fmt.Println("Retrieved sessions for user ID:", userID)
+}
+
+func (ctx *Context) HandleSessionDelete(w http.ResponseWriter, userID *uuid.UUID, sessionID *uuid.UUID) {
+	errorResp := logic.RevokeSession(ctx.Db, ctx.AccessTokens, userID, sessionID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Revoked session with ID:", sessionID)
+}
+
+func (ctx *Context) HandleSessionDeleteAll(w http.ResponseWriter, userID *uuid.UUID, keepID *uuid.UUID) {
+	errorResp := logic.RevokeAllSessions(ctx.Db, ctx.AccessTokens, userID, keepID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Revoked all sessions of user ID:", userID)
+}
+
+// clientIP is the address the request came from. X-Forwarded-For is only
+// believed when the request comes from a trusted proxy, then the last hop
+// that is not a trusted proxy is the client. Anyone else could put any
+// address in the header.
+func (ctx *Context) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !ctx.trustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !ctx.trustedProxy(hop) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
+
+func (ctx *Context) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range ctx.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTrustedProxies reads a comma-separated list of proxy addresses and
+// networks in CIDR notation.
+func ParseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, "/") {
+			prefix, err := netip.ParsePrefix(part)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, err
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+// TakeoutHandler sends all data stored about the user as a zip archive.
+func (ctx *Context) TakeoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	takeout, errorResp := logic.NewTakeout(ctx.Db, &userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", takeout.FileName()))
+	if err := takeout.Write(w); err != nil {
+		fmt.Println("Error writing takeout:", err)
+		return
+	}
+	fmt.Println("Sent takeout for user ID:", userID)
+}
+
+// ErasureHandler reports the scheduled erasure of the user's account, schedules
+// it after the password was entered again, or cancels it.
+func (ctx *Context) ErasureHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleErasureGet(w, r)
+	case http.MethodPost:
+		ctx.HandleErasurePost(w, r)
+	case http.MethodDelete:
+		ctx.HandleErasureDelete(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) HandleErasureGet(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	status, errorResp := logic.GetErasureStatus(ctx.Db, &userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+	fmt.Println("Retrieved erasure status for user ID:", userID)
+}
+
+func (ctx *Context) HandleErasurePost(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	var request logic.ErasureRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	status, errorResp := logic.ScheduleErasure(ctx.Db, ctx.MailConfig, &userID, &request)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+	fmt.Println("Scheduled erasure of user ID:", userID, "at", status.ScheduledAt)
+}
+
+func (ctx *Context) HandleErasureDelete(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	errorResp := logic.CancelErasure(ctx.Db, &userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	fmt.Println("Cancelled erasure of user ID:", userID)
+}
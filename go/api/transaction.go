@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+func (ctx *Context) TransactionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleTransactionGet(w, &userID)
+	case http.MethodPost:
+		ctx.HandleTransactionInsert(w, r, &userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) TransactionHandlerByID(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	idStr := strings.TrimPrefix(r.URL.Path, "/transaction/id/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleTransactionGetByID(w, &userID, &transactionID)
+	case http.MethodPut:
+		ctx.HandleTransactionUpdate(w, r, &userID, &transactionID)
+	case http.MethodDelete:
+		ctx.HandleTransactionDelete(w, &userID, &transactionID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) TransactionCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	summaries, errorResp := logic.GetCategorySummary(ctx.Db, &userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+	fmt.Println("Retrieved", len(summaries), "transaction categories for user ID:", userID)
+}
+
+func (ctx *Context) HandleTransactionGet(w http.ResponseWriter, userID *uuid.UUID) {
+	transactions, errorResp := logic.GetAllTransactions(ctx.Db, userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transactions)
+	fmt.Println("Retrieved transactions for user ID:", userID)
+}
+
+func (ctx *Context) HandleTransactionGetByID(w http.ResponseWriter, userID *uuid.UUID, transactionID *uuid.UUID) {
+	transaction, errorResp := logic.GetTransactionByID(ctx.Db, userID, transactionID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+	fmt.Println("Retrieved transaction with ID:", transactionID)
+}
+
+func (ctx *Context) HandleTransactionInsert(w http.ResponseWriter, r *http.Request, userID *uuid.UUID) {
+	var transaction database.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&transaction); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	newTransaction, errorResp := logic.InsertTransaction(ctx.Db, &transaction, userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTransaction)
+	fmt.Println("Inserted transaction with ID:", newTransaction.ID)
+}
+
+func (ctx *Context) HandleTransactionUpdate(w http.ResponseWriter, r *http.Request, userID *uuid.UUID, transactionID *uuid.UUID) {
+	var transactionForUpdate logic.TransactionForUpdate
+	if err := json.NewDecoder(r.Body).Decode(&transactionForUpdate); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	transactionForUpdate.ID = *transactionID
+
+	transaction, errorResp := logic.UpdateTransaction(ctx.Db, userID, &transactionForUpdate)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+	fmt.Println("Updated transaction with ID:", transactionID)
+}
+
+func (ctx *Context) HandleTransactionDelete(w http.ResponseWriter, userID *uuid.UUID, transactionID *uuid.UUID) {
+	errorResp := logic.DeleteTransaction(ctx.Db, userID, transactionID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Deleted transaction with ID:", transactionID)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/logic"
+	"github.com/google/uuid"
+)
+
+func (ctx *Context) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleWebhookGet(w, &userID)
+	case http.MethodPost:
+		ctx.HandleWebhookInsert(w, r, &userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ctx *Context) WebhookHandlerByID(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uuid.UUID)
+	idStr := strings.TrimPrefix(r.URL.Path, "/webhook/id/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	webhookID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ctx.HandleWebhookGetByID(w, &userID, &webhookID)
+	case http.MethodPut:
+		ctx.HandleWebhookUpdate(w, r, &userID, &webhookID)
+	case http.MethodDelete:
+		ctx.HandleWebhookDelete(w, &userID, &webhookID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// WebhookDeliveryHandler returns the latest deliveries of the webhook whose
+// ID follows /webhook/deliveries/, with the outcome of their last attempt.
+func (ctx *Context) WebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := r.Context().Value("userID").(uuid.UUID)
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/webhook/deliveries/")
+	if idStr == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	webhookID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, errorResp := logic.GetWebhookDeliveries(ctx.Db, &userID, &webhookID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+	fmt.Println("Retrieved", len(deliveries), "deliveries of webhook with ID:", webhookID)
+}
+
+func (ctx *Context) HandleWebhookGet(w http.ResponseWriter, userID *uuid.UUID) {
+	webhooks, errorResp := logic.GetWebhooks(ctx.Db, userID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+	fmt.Println("Retrieved webhooks for user ID:", userID)
+}
+
+func (ctx *Context) HandleWebhookGetByID(w http.ResponseWriter, userID *uuid.UUID, webhookID *uuid.UUID) {
+	webhook, errorResp := logic.GetWebhookByID(ctx.Db, userID, webhookID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+	fmt.Println("Retrieved webhook with ID:", webhookID)
+}
+
+func (ctx *Context) HandleWebhookInsert(w http.ResponseWriter, r *http.Request, userID *uuid.UUID) {
+	var webhookForUpdate logic.WebhookForUpdate
+	if err := json.NewDecoder(r.Body).Decode(&webhookForUpdate); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	newWebhook, errorResp := logic.CreateWebhook(ctx.Db, userID, &webhookForUpdate)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newWebhook)
+	fmt.Println("Inserted webhook with ID:", newWebhook.ID)
+}
+
+func (ctx *Context) HandleWebhookUpdate(w http.ResponseWriter, r *http.Request, userID *uuid.UUID, webhookID *uuid.UUID) {
+	var webhookForUpdate logic.WebhookForUpdate
+	if err := json.NewDecoder(r.Body).Decode(&webhookForUpdate); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	webhook, errorResp := logic.UpdateWebhook(ctx.Db, userID, webhookID, &webhookForUpdate)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+	fmt.Println("Updated webhook with ID:", webhookID)
+}
+
+func (ctx *Context) HandleWebhookDelete(w http.ResponseWriter, userID *uuid.UUID, webhookID *uuid.UUID) {
+	errorResp := logic.DeleteWebhook(ctx.Db, userID, webhookID)
+	if errorResp.Code != http.StatusOK {
+		http.Error(w, errorResp.Message, errorResp.Code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Println("Deleted webhook with ID:", webhookID)
+}
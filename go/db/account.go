@@ -0,0 +1,86 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type Account struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Currency    string    `json:"currency"`
+	BankAccount string    `json:"bank_account"`
+	CreatedAt   string    `json:"created_at"`
+	UserID      uuid.UUID `json:"user_id"`
+}
+
+const accountColumns = "id, name, currency, bank_account, created_at, user_id"
+
+func scanAccount(row rowScanner) (*Account, error) {
+	account := &Account{}
+	err := row.Scan(&account.ID, &account.Name, &account.Currency, &account.BankAccount, &account.CreatedAt, &account.UserID)
+	return account, err
+}
+
+func (db *Database) InsertAccountDB(account *Account) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := db.DB.QueryRow(
+		"INSERT INTO accounts (name, currency, bank_account, user_id) VALUES ($1, $2, $3, $4) RETURNING id",
+		account.Name, account.Currency, account.BankAccount, account.UserID,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *Database) SelectAccountByIDDB(id *uuid.UUID) (*Account, error) {
+	if id == nil {
+		return nil, errors.New("id is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+accountColumns+" FROM accounts WHERE id = $1", id)
+
+	account, err := scanAccount(row)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (db *Database) SelectUserAccountsDB(userID *uuid.UUID) ([]*Account, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	rows, err := db.DB.Query("SELECT "+accountColumns+" FROM accounts WHERE user_id = $1 ORDER BY created_at ASC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+func (db *Database) UpdateAccountDB(account *Account) error {
+	_, err := db.DB.Exec(
+		"UPDATE accounts SET name = $1, bank_account = $2 WHERE id = $3",
+		account.Name, account.BankAccount, account.ID,
+	)
+	return err
+}
+
+func (db *Database) DeleteAccountDB(id *uuid.UUID) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"DELETE FROM accounts WHERE id = $1",
+		id,
+	)
+	return err
+}
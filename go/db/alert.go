@@ -0,0 +1,128 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// AlertRule notifies the user when the budget or balance of an account falls
+// below a threshold. Without an account the rule applies to all accounts. The
+// webhook secret signs the payloads like the secret of a webhook and is only
+// returned when it is set.
+type AlertRule struct {
+	ID            uuid.UUID    `json:"id"`
+	Kind          string       `json:"kind"`
+	Threshold     money.Amount `json:"threshold"`
+	NotifyEmail   bool         `json:"notify_email"`
+	WebhookURL    string       `json:"webhook_url"`
+	WebhookSecret string       `json:"webhook_secret,omitempty"`
+	LastFiredAt   *time.Time   `json:"last_fired_at"`
+	CreatedAt     string       `json:"created_at"`
+	UserID        uuid.UUID    `json:"user_id"`
+	AccountID     *uuid.UUID   `json:"account_id"`
+}
+
+const alertRuleColumns = "id, kind, threshold, notify_email, webhook_url, webhook_secret, last_fired_at, created_at, user_id, account_id"
+
+func scanAlertRule(row rowScanner) (*AlertRule, error) {
+	rule := &AlertRule{}
+	err := row.Scan(&rule.ID, &rule.Kind, &rule.Threshold, &rule.NotifyEmail, &rule.WebhookURL, &rule.WebhookSecret,
+		&rule.LastFiredAt, &rule.CreatedAt, &rule.UserID, &rule.AccountID)
+	return rule, err
+}
+
+func (db *Database) InsertAlertRuleDB(rule *AlertRule) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := db.DB.QueryRow(
+		"INSERT INTO alert_rules (kind, threshold, notify_email, webhook_url, webhook_secret, user_id, account_id) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		rule.Kind, rule.Threshold, rule.NotifyEmail, rule.WebhookURL, rule.WebhookSecret, rule.UserID, rule.AccountID,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *Database) SelectAlertRuleByIDDB(id *uuid.UUID) (*AlertRule, error) {
+	if id == nil {
+		return nil, errors.New("id is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+alertRuleColumns+" FROM alert_rules WHERE id = $1", id)
+
+	rule, err := scanAlertRule(row)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (db *Database) SelectUserAlertRulesDB(userID *uuid.UUID) ([]*AlertRule, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	rows, err := db.DB.Query("SELECT "+alertRuleColumns+" FROM alert_rules WHERE user_id = $1 ORDER BY created_at ASC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*AlertRule
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (db *Database) UpdateAlertRuleDB(rule *AlertRule) error {
+	_, err := db.DB.Exec(
+		"UPDATE alert_rules SET kind = $1, threshold = $2, notify_email = $3, webhook_url = $4, webhook_secret = $5, account_id = $6 WHERE id = $7",
+		rule.Kind, rule.Threshold, rule.NotifyEmail, rule.WebhookURL, rule.WebhookSecret, rule.AccountID, rule.ID,
+	)
+	return err
+}
+
+// ClaimAlertRuleDB marks the rule as fired at the given time unless it fired
+// after notBefore already. Returns whether the rule was claimed, so of several
+// concurrent events only one notifies.
+func (db *Database) ClaimAlertRuleDB(id *uuid.UUID, at time.Time, notBefore time.Time) (bool, error) {
+	if id == nil {
+		return false, errors.New("id is nil")
+	}
+	result, err := db.DB.Exec(
+		"UPDATE alert_rules SET last_fired_at = $1 WHERE id = $2 AND (last_fired_at IS NULL OR last_fired_at <= $3)",
+		at, id, notBefore,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ReleaseAlertRuleDB undoes a claim made at the given time when no
+// notification went out, so the next event may notify again.
+func (db *Database) ReleaseAlertRuleDB(id *uuid.UUID, at time.Time, previous *time.Time) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"UPDATE alert_rules SET last_fired_at = $1 WHERE id = $2 AND last_fired_at = $3",
+		previous, id, at,
+	)
+	return err
+}
+
+func (db *Database) DeleteAlertRuleDB(id *uuid.UUID) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"DELETE FROM alert_rules WHERE id = $1",
+		id,
+	)
+	return err
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent records a security relevant action of a user, like a login or a
+// requested erasure.
+type AuditEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Action    string    `json:"action"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+const auditColumns = "id, action, detail, created_at, user_id"
+
+func scanAuditEvent(row rowScanner) (*AuditEvent, error) {
+	event := &AuditEvent{}
+	err := row.Scan(&event.ID, &event.Action, &event.Detail, &event.CreatedAt, &event.UserID)
+	return event, err
+}
+
+func (db *Database) InsertAuditEventDB(event *AuditEvent) error {
+	_, err := db.DB.Exec(
+		"INSERT INTO audit_log (action, detail, user_id) VALUES ($1, $2, $3)",
+		event.Action, event.Detail, event.UserID,
+	)
+	return err
+}
+
+func (db *Database) SelectUserAuditEventsDB(userID *uuid.UUID) ([]*AuditEvent, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	rows, err := db.DB.Query("SELECT "+auditColumns+" FROM audit_log WHERE user_id = $1 ORDER BY created_at ASC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Budget limits the spending in a transaction category per week or month.
+type Budget struct {
+	ID        uuid.UUID    `json:"id"`
+	Category  string       `json:"category"`
+	Period    string       `json:"period"`
+	Limit     money.Amount `json:"limit"`
+	Rollover  string       `json:"rollover"`
+	StartDate time.Time    `json:"start_date"`
+	CreatedAt string       `json:"created_at"`
+	UserID    uuid.UUID    `json:"user_id"`
+	AccountID uuid.UUID    `json:"account_id"`
+}
+
+const budgetColumns = "id, category, period, limit_amount, rollover, start_date, created_at, user_id, account_id"
+
+func scanBudget(row rowScanner) (*Budget, error) {
+	budget := &Budget{}
+	err := row.Scan(&budget.ID, &budget.Category, &budget.Period, &budget.Limit, &budget.Rollover,
+		&budget.StartDate, &budget.CreatedAt, &budget.UserID, &budget.AccountID)
+	return budget, err
+}
+
+func (db *Database) InsertBudgetDB(budget *Budget) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := db.DB.QueryRow(
+		"INSERT INTO budgets (category, period, limit_amount, rollover, start_date, user_id, account_id) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		budget.Category, budget.Period, budget.Limit, budget.Rollover, budget.StartDate, budget.UserID, budget.AccountID,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *Database) SelectBudgetByIDDB(id *uuid.UUID) (*Budget, error) {
+	if id == nil {
+		return nil, errors.New("id is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+budgetColumns+" FROM budgets WHERE id = $1", id)
+
+	budget, err := scanBudget(row)
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (db *Database) SelectUserBudgetsDB(userID *uuid.UUID) ([]*Budget, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	rows, err := db.DB.Query("SELECT "+budgetColumns+" FROM budgets WHERE user_id = $1 ORDER BY category ASC, created_at ASC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []*Budget
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+func (db *Database) UpdateBudgetDB(budget *Budget) error {
+	_, err := db.DB.Exec(
+		"UPDATE budgets SET category = $1, limit_amount = $2, rollover = $3 WHERE id = $4",
+		budget.Category, budget.Limit, budget.Rollover, budget.ID,
+	)
+	return err
+}
+
+func (db *Database) DeleteBudgetDB(id *uuid.UUID) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"DELETE FROM budgets WHERE id = $1",
+		id,
+	)
+	return err
+}
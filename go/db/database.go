@@ -18,6 +18,9 @@ type UserStore interface {
 	SelectUserByIDDB(id *uuid.UUID) (*User, error)
 	UpdateUserDB(user *UserForUpdate) error
 	DeleteUserDB(id *uuid.UUID) error 
+	SetUserErasureDB(userID *uuid.UUID, at *time.Time) error
+	SelectUserErasureDB(userID *uuid.UUID) (*time.Time, error)
+	SelectDueErasuresDB(now time.Time) ([]uuid.UUID, error)
 }
 
 type RoleStore interface {
@@ -31,13 +34,32 @@ type RoleStore interface {
 type TokenStore interface {
 	// Token-related methods
 	InsertToken(Token *Token) error 
-	GetToken(token *uuid.UUID) (*Token, error) 
+	GetToken(token string) (*Token, error) 
 	ListTokens() ([]*Token, error) 
-	DeleteToken(token *uuid.UUID) error 
+	DeleteToken(prefix string) error 
 	DeleteTokensByUserID(userID *uuid.UUID) error 
+	DeleteTokensByUserIDAndPurpose(userID *uuid.UUID, purpose string) error
 	DeleteExpiredTokens() error 
 }
 
+type SessionStore interface {
+	// Session-related methods
+	InsertSessionDB(session *Session, tokens ...*Token) error
+	InsertSessionTokensDB(sessionID *uuid.UUID, tokens ...*Token) error
+	RotateRefreshTokenDB(prefix string, at time.Time, sessionID *uuid.UUID, tokens ...*Token) (bool, error)
+	SelectSessionByIDDB(id *uuid.UUID) (*Session, error)
+	SelectUserSessionsDB(userID *uuid.UUID) ([]*Session, error)
+	TouchSessionDB(id *uuid.UUID, at time.Time, notBefore time.Time) error
+	DeleteSessionDB(id *uuid.UUID) error
+	DeleteUserSessionsDB(userID *uuid.UUID, keep *uuid.UUID) ([]uuid.UUID, error)
+}
+
+type AuditStore interface {
+	// Audit-log-related methods
+	InsertAuditEventDB(event *AuditEvent) error
+	SelectUserAuditEventsDB(userID *uuid.UUID) ([]*AuditEvent, error)
+}
+
 type UserRoleStore interface {
 	UserStore
 	RoleStore
@@ -46,23 +68,206 @@ type UserRoleStore interface {
 type AuthStore interface {
 	// Authentication-related methods would go here
 	TokenStore
+	SessionStore
 	UserRoleStore
+	AuditStore
+}
+
+// SessionTokenStore holds session tokens and the sessions they belong to.
+type SessionTokenStore interface {
+	TokenStore
+	SessionStore
+}
+
+// SessionAuditStore holds sessions and their tokens and records changes to
+// them in the audit log.
+type SessionAuditStore interface {
+	SessionTokenStore
+	AuditStore
 }
 
 type MoneyStore interface {
 	// Money-related methods would go here
 	InsertMoneyDB(entry *MoneyEntry) (uuid.UUID, error) 
+	UpdateChainDB(accountID *uuid.UUID, plan func(entries []*MoneyEntry) (*LedgerChange, error)) error
 	SelectMoneyByIDDB(id *uuid.UUID) (*MoneyEntry, error)
+	SelectMoneyByTransactionIDDB(transactionID *uuid.UUID) (*MoneyEntry, error)
 	SelectUserMoneyDB(userID *uuid.UUID) ([]*MoneyEntry, error) 
 	SelectUserMoneyByCountDB(userID *uuid.UUID, count int64) ([]*MoneyEntry, error) 
+	SelectUserMoneyPageDB(userID *uuid.UUID, query *MoneyQuery) ([]*MoneyEntry, error)
+	SelectAccountMoneyDB(accountID *uuid.UUID) ([]*MoneyEntry, error)
+	SelectAccountMoneyByCountDB(accountID *uuid.UUID, count int64) ([]*MoneyEntry, error)
 	UpdateMoneyBatchDB(entries []*MoneyEntry) error
 	UpdateMoneyDB(entry *MoneyEntry) error 
 	DeleteMoneyDB(id *uuid.UUID) error
 }
 
+type TransactionStore interface {
+	// Transaction-related methods
+	InsertTransactionDB(transaction *Transaction) (uuid.UUID, error)
+	SelectTransactionByIDDB(id *uuid.UUID) (*Transaction, error)
+	SelectUserTransactionsDB(userID *uuid.UUID) ([]*Transaction, error)
+	SelectUserTransactionsPageDB(userID *uuid.UUID, query *TransactionQuery) ([]*Transaction, error)
+	SelectTransactionsByIDsDB(ids []uuid.UUID) ([]*Transaction, error)
+	UpdateTransactionDB(transaction *Transaction) error
+	DeleteTransactionDB(id *uuid.UUID) error
+}
+
+type AccountStore interface {
+	// Account-related methods
+	InsertAccountDB(account *Account) (uuid.UUID, error)
+	SelectAccountByIDDB(id *uuid.UUID) (*Account, error)
+	SelectUserAccountsDB(userID *uuid.UUID) ([]*Account, error)
+	UpdateAccountDB(account *Account) error
+	DeleteAccountDB(id *uuid.UUID) error
+}
+
+type ExchangeRateStore interface {
+	// Exchange-rate-related methods
+	UpsertExchangeRatesDB(rates []*ExchangeRate) error
+	SelectExchangeRatesDB(date time.Time) ([]*ExchangeRate, error)
+}
+
+type LedgerStore interface {
+	AccountStore
+	MoneyStore
+	TransactionStore
+}
+
+type RecurringStore interface {
+	// Recurring-schedule-related methods
+	InsertRecurringDB(recurring *Recurring) (uuid.UUID, error)
+	SelectRecurringByIDDB(id *uuid.UUID) (*Recurring, error)
+	SelectUserRecurringDB(userID *uuid.UUID) ([]*Recurring, error)
+	SelectDueRecurringDB(now time.Time) ([]*Recurring, error)
+	UpdateRecurringDB(recurring *Recurring) error
+	DeleteRecurringDB(id *uuid.UUID) error
+}
+
+type GoalStore interface {
+	// Savings-goal-related methods
+	InsertGoalDB(goal *Goal) (uuid.UUID, error)
+	SelectGoalByIDDB(id *uuid.UUID) (*Goal, error)
+	SelectUserGoalsDB(userID *uuid.UUID) ([]*Goal, error)
+	UpdateGoalDB(goal *Goal) error
+	DeleteGoalDB(id *uuid.UUID) error
+}
+
+type GoalLedgerStore interface {
+	LedgerStore
+	GoalStore
+}
+
+type BudgetStore interface {
+	// Budget-period-related methods
+	InsertBudgetDB(budget *Budget) (uuid.UUID, error)
+	SelectBudgetByIDDB(id *uuid.UUID) (*Budget, error)
+	SelectUserBudgetsDB(userID *uuid.UUID) ([]*Budget, error)
+	UpdateBudgetDB(budget *Budget) error
+	DeleteBudgetDB(id *uuid.UUID) error
+}
+
+type BudgetLedgerStore interface {
+	LedgerStore
+	BudgetStore
+}
+
+type SchedulingStore interface {
+	LedgerStore
+	RecurringStore
+}
+
+type DigestStore interface {
+	// Digest-subscription-related methods
+	UpsertDigestDB(digest *Digest) error
+	SelectDigestDB(userID *uuid.UUID) (*Digest, error)
+	SelectDueDigestsDB(now time.Time) ([]*Digest, error)
+	UpdateDigestSentDB(digest *Digest) error
+	DeleteDigestDB(userID *uuid.UUID) error
+}
+
+// DigestLedgerStore holds what a digest is built from, the user to send it to
+// and the user's accounts, entries and recurring schedules.
+type DigestLedgerStore interface {
+	UserStore
+	SchedulingStore
+	DigestStore
+}
+
+type ReportingStore interface {
+	LedgerStore
+	ExchangeRateStore
+}
+
+type AlertStore interface {
+	// Alert-rule-related methods
+	InsertAlertRuleDB(rule *AlertRule) (uuid.UUID, error)
+	SelectAlertRuleByIDDB(id *uuid.UUID) (*AlertRule, error)
+	SelectUserAlertRulesDB(userID *uuid.UUID) ([]*AlertRule, error)
+	UpdateAlertRuleDB(rule *AlertRule) error
+	ClaimAlertRuleDB(id *uuid.UUID, at time.Time, notBefore time.Time) (bool, error)
+	ReleaseAlertRuleDB(id *uuid.UUID, at time.Time, previous *time.Time) error
+	DeleteAlertRuleDB(id *uuid.UUID) error
+}
+
+type AlertLedgerStore interface {
+	LedgerStore
+	AlertStore
+}
+
+// AlertNotifyStore holds the rules of a user, the user they notify and the
+// queue their webhooks are delivered through.
+type AlertNotifyStore interface {
+	UserStore
+	AlertStore
+	WebhookStore
+}
+
+type WebhookStore interface {
+	// Webhook-related methods
+	InsertWebhookDB(webhook *Webhook) (uuid.UUID, error)
+	SelectWebhookByIDDB(id *uuid.UUID) (*Webhook, error)
+	SelectUserWebhooksDB(userID *uuid.UUID) ([]*Webhook, error)
+	UpdateWebhookDB(webhook *Webhook) error
+	DeleteWebhookDB(id *uuid.UUID) error
+
+	// Webhook-delivery-related methods
+	InsertWebhookDeliveryDB(delivery *WebhookDelivery) (uuid.UUID, error)
+	SelectWebhookDeliveryByIDDB(id *uuid.UUID) (*WebhookDelivery, error)
+	SelectWebhookDeliveriesDB(webhookID *uuid.UUID, limit int) ([]*WebhookDelivery, error)
+	SelectAlertRuleDeliveriesDB(ruleID *uuid.UUID, limit int) ([]*WebhookDelivery, error)
+	SelectDueWebhookDeliveriesDB(now time.Time, limit int) ([]*WebhookDelivery, error)
+	ClaimWebhookDeliveryDB(id *uuid.UUID, now time.Time, leaseUntil time.Time) (bool, error)
+	UpdateWebhookDeliveryDB(delivery *WebhookDelivery) error
+}
+
+// WebhookDeliveryStore holds the delivery queue and the webhooks and alert
+// rules the deliveries go to.
+type WebhookDeliveryStore interface {
+	WebhookStore
+	AlertStore
+}
+
+// TakeoutStore holds everything stored about a user.
+type TakeoutStore interface {
+	AuthStore
+	LedgerStore
+	RecurringStore
+	GoalStore
+	BudgetStore
+	AlertStore
+	WebhookStore
+}
+
 type DatabaseInterface interface {
 	AuthStore
-	MoneyStore
+	ReportingStore
+	RecurringStore
+	GoalStore
+	BudgetStore
+	DigestStore
+	AlertStore
+	WebhookStore
 
 	Close() error
 }
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Digest is a user's subscription to summary emails. LastSentAt is nil until
+// the first digest was sent.
+type Digest struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	Frequency  string     `json:"frequency"`
+	NextSendAt time.Time  `json:"next_send_at"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+	CreatedAt  string     `json:"created_at"`
+}
+
+const digestColumns = "user_id, frequency, next_send_at, last_sent_at, created_at"
+
+func scanDigest(row rowScanner) (*Digest, error) {
+	digest := &Digest{}
+	err := row.Scan(&digest.UserID, &digest.Frequency, &digest.NextSendAt, &digest.LastSentAt, &digest.CreatedAt)
+	return digest, err
+}
+
+// UpsertDigestDB subscribes the user or changes the frequency of an existing
+// subscription.
+func (db *Database) UpsertDigestDB(digest *Digest) error {
+	_, err := db.DB.Exec(
+		`INSERT INTO digests (user_id, frequency, next_send_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET frequency = EXCLUDED.frequency, next_send_at = EXCLUDED.next_send_at`,
+		digest.UserID, digest.Frequency, digest.NextSendAt,
+	)
+	return err
+}
+
+func (db *Database) SelectDigestDB(userID *uuid.UUID) (*Digest, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+digestColumns+" FROM digests WHERE user_id = $1", userID)
+
+	digest, err := scanDigest(row)
+	if err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// SelectDueDigestsDB returns the subscriptions whose next digest is due at or
+// before now.
+func (db *Database) SelectDueDigestsDB(now time.Time) ([]*Digest, error) {
+	rows, err := db.DB.Query("SELECT "+digestColumns+" FROM digests WHERE next_send_at <= $1 ORDER BY next_send_at ASC", now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []*Digest
+	for rows.Next() {
+		digest, err := scanDigest(rows)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+	return digests, rows.Err()
+}
+
+func (db *Database) UpdateDigestSentDB(digest *Digest) error {
+	_, err := db.DB.Exec(
+		"UPDATE digests SET next_send_at = $1, last_sent_at = $2 WHERE user_id = $3",
+		digest.NextSendAt, digest.LastSentAt, digest.UserID,
+	)
+	return err
+}
+
+func (db *Database) DeleteDigestDB(userID *uuid.UUID) error {
+	if userID == nil {
+		return errors.New("userID is nil")
+	}
+	_, err := db.DB.Exec("DELETE FROM digests WHERE user_id = $1", userID)
+	return err
+}
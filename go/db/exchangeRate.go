@@ -0,0 +1,67 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+)
+
+// ExchangeRate states that one unit of Base buys Rate units of Quote.
+type ExchangeRate struct {
+	Base  string     `json:"base"`
+	Quote string     `json:"quote"`
+	Date  time.Time  `json:"date"`
+	Rate  money.Rate `json:"rate"`
+}
+
+func (db *Database) UpsertExchangeRatesDB(rates []*ExchangeRate) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(
+		`INSERT INTO exchange_rates (base, quote, date, rate) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (base, quote, date) DO UPDATE SET rate = EXCLUDED.rate`,
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		_, err := stmt.Exec(rate.Base, rate.Quote, rate.Date, rate.Rate)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SelectExchangeRatesDB returns the most recent rate of every currency pair
+// known on the given date.
+func (db *Database) SelectExchangeRatesDB(date time.Time) ([]*ExchangeRate, error) {
+	rows, err := db.DB.Query(
+		`SELECT DISTINCT ON (base, quote) base, quote, date, rate
+		 FROM exchange_rates
+		 WHERE date <= $1
+		 ORDER BY base, quote, date DESC`,
+		date,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*ExchangeRate
+	for rows.Next() {
+		rate := &ExchangeRate{}
+		if err := rows.Scan(&rate.Base, &rate.Quote, &rate.Date, &rate.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+type Goal struct {
+	ID           uuid.UUID    `json:"id"`
+	Name         string       `json:"name"`
+	TargetAmount money.Amount `json:"target_amount"`
+	Deadline     *time.Time   `json:"deadline"`
+	CreatedAt    string       `json:"created_at"`
+	UserID       uuid.UUID    `json:"user_id"`
+	AccountID    uuid.UUID    `json:"account_id"`
+}
+
+const goalColumns = "id, name, target_amount, deadline, created_at, user_id, account_id"
+
+func scanGoal(row rowScanner) (*Goal, error) {
+	goal := &Goal{}
+	err := row.Scan(&goal.ID, &goal.Name, &goal.TargetAmount, &goal.Deadline, &goal.CreatedAt, &goal.UserID, &goal.AccountID)
+	return goal, err
+}
+
+func (db *Database) InsertGoalDB(goal *Goal) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := db.DB.QueryRow(
+		"INSERT INTO goals (name, target_amount, deadline, user_id, account_id) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		goal.Name, goal.TargetAmount, goal.Deadline, goal.UserID, goal.AccountID,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *Database) SelectGoalByIDDB(id *uuid.UUID) (*Goal, error) {
+	if id == nil {
+		return nil, errors.New("id is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+goalColumns+" FROM goals WHERE id = $1", id)
+
+	goal, err := scanGoal(row)
+	if err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+func (db *Database) SelectUserGoalsDB(userID *uuid.UUID) ([]*Goal, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	rows, err := db.DB.Query("SELECT "+goalColumns+" FROM goals WHERE user_id = $1 ORDER BY created_at ASC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []*Goal
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+func (db *Database) UpdateGoalDB(goal *Goal) error {
+	_, err := db.DB.Exec(
+		"UPDATE goals SET name = $1, target_amount = $2, deadline = $3, account_id = $4 WHERE id = $5",
+		goal.Name, goal.TargetAmount, goal.Deadline, goal.AccountID, goal.ID,
+	)
+	return err
+}
+
+func (db *Database) DeleteGoalDB(id *uuid.UUID) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"DELETE FROM goals WHERE id = $1",
+		id,
+	)
+	return err
+}
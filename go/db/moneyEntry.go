@@ -1,34 +1,187 @@
 package database
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/Leander-s/money_manager/money"
 	"github.com/google/uuid"
 )
 
 type MoneyEntry struct {
-	ID        uuid.UUID `json:"id"`
-	Balance   float64   `json:"balance"`
-	Budget    float64   `json:"budget"`
-	Ratio     float64   `json:"ratio"`
-	CreatedAt string    `json:"created_at"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID            uuid.UUID    `json:"id"`
+	Balance       money.Amount `json:"balance"`
+	Budget        money.Amount `json:"budget"`
+	Ratio         money.Rate   `json:"ratio"`
+	Currency      string       `json:"currency"`
+	EffectiveAt   time.Time    `json:"effective_at"`
+	CreatedAt     string       `json:"created_at"`
+	UserID        uuid.UUID    `json:"user_id"`
+	AccountID     uuid.UUID    `json:"account_id"`
+	TransactionID *uuid.UUID   `json:"transaction_id"`
+	RecurringID   *uuid.UUID   `json:"recurring_id"`
+	Description   string       `json:"description"`
+	ImportRef     *string      `json:"import_ref"`
+}
+
+const moneyColumns = "id, balance, budget, ratio, currency, effective_at, created_at, user_id, account_id, transaction_id, recurring_id, description, import_ref"
+
+const insertMoneyQuery = `INSERT INTO money (balance, budget, ratio, currency, effective_at, user_id, account_id, transaction_id, recurring_id, description, import_ref)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+
+// MoneyQuery selects a page of a user's entries ordered newest first. From is
+// inclusive and To exclusive, After is the position of the last entry of the
+// previous page. Nil fields are not filtered on.
+type MoneyQuery struct {
+	AccountID *uuid.UUID
+	From      *time.Time
+	To        *time.Time
+	After     *MoneyCursor
+	Limit     int
+}
+
+// MoneyCursor is the position of an entry in the newest first order. The ID
+// breaks ties between entries created at the same time.
+type MoneyCursor struct {
+	EffectiveAt time.Time `json:"effective_at"`
+	CreatedAt   string    `json:"created_at"`
+	ID          uuid.UUID `json:"id"`
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMoneyEntry(row rowScanner) (*MoneyEntry, error) {
+	entry := &MoneyEntry{}
+	err := row.Scan(&entry.ID, &entry.Balance, &entry.Budget, &entry.Ratio, &entry.Currency, &entry.EffectiveAt, &entry.CreatedAt, &entry.UserID, &entry.AccountID, &entry.TransactionID, &entry.RecurringID, &entry.Description, &entry.ImportRef)
+	return entry, err
 }
 
 func (db *Database) InsertMoneyDB(entry *MoneyEntry) (uuid.UUID, error) {
 	var id uuid.UUID
 	err := db.DB.QueryRow(
-		"INSERT INTO money (balance, budget, ratio, user_id) VALUES ($1, $2, $3, $4) RETURNING id",
-		entry.Balance, entry.Budget, entry.Ratio, entry.UserID,
+		insertMoneyQuery,
+		entry.Balance, entry.Budget, entry.Ratio, entry.Currency, entry.EffectiveAt, entry.UserID, entry.AccountID, entry.TransactionID, entry.RecurringID, entry.Description, entry.ImportRef,
 	).Scan(&id)
 	return id, err
 }
 
+// LedgerChange is a change of an account's chain. Transactions must have
+// their IDs set already, so new entries can refer to them. The IDs of the
+// inserted entries are set on NewEntries.
+type LedgerChange struct {
+	NewTransactions      []*Transaction
+	UpdatedTransaction   *Transaction
+	DeletedTransactionID *uuid.UUID
+	NewEntries           []*MoneyEntry
+	UpdatedEntries       []*MoneyEntry
+	DeletedEntryID       *uuid.UUID
+}
+
+// UpdateChainDB locks the account, reads its chain newest first and writes the
+// change plan returns for it, all in a single transaction. Writers of the same
+// account wait for each other, so no change is planned on an outdated chain.
+// An error returned by plan rolls the transaction back and is returned as is.
+func (db *Database) UpdateChainDB(accountID *uuid.UUID, plan func(entries []*MoneyEntry) (*LedgerChange, error)) error {
+	if accountID == nil {
+		return errors.New("accountID is nil")
+	}
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	var lockedID uuid.UUID
+	if err := tx.QueryRow("SELECT id FROM accounts WHERE id = $1 FOR UPDATE", accountID).Scan(&lockedID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	entries, err := selectAccountMoney(tx, accountID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	change, err := plan(entries)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := writeLedgerChange(tx, change); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func writeLedgerChange(tx *sql.Tx, change *LedgerChange) error {
+	for _, transaction := range change.NewTransactions {
+		_, err := tx.Exec(
+			"INSERT INTO transactions (id, amount, category, payee, note, date, user_id, account_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			transaction.ID, transaction.Amount, transaction.Category, transaction.Payee, transaction.Note, transaction.Date, transaction.UserID, transaction.AccountID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if transaction := change.UpdatedTransaction; transaction != nil {
+		_, err := tx.Exec(
+			"UPDATE transactions SET amount = $1, category = $2, payee = $3, note = $4, date = $5 WHERE id = $6",
+			transaction.Amount, transaction.Category, transaction.Payee, transaction.Note, transaction.Date, transaction.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if change.DeletedEntryID != nil {
+		if _, err := tx.Exec("DELETE FROM money WHERE id = $1", change.DeletedEntryID); err != nil {
+			return err
+		}
+	}
+	if change.DeletedTransactionID != nil {
+		if _, err := tx.Exec("DELETE FROM transactions WHERE id = $1", change.DeletedTransactionID); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range change.NewEntries {
+		err := tx.QueryRow(
+			insertMoneyQuery,
+			entry.Balance, entry.Budget, entry.Ratio, entry.Currency, entry.EffectiveAt, entry.UserID, entry.AccountID, entry.TransactionID, entry.RecurringID, entry.Description, entry.ImportRef,
+		).Scan(&entry.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return updateLedgerEntries(tx, change.UpdatedEntries)
+}
+
+func updateLedgerEntries(tx *sql.Tx, entries []*MoneyEntry) error {
+	for _, entry := range entries {
+		_, err := tx.Exec(
+			"UPDATE money SET balance = $1, budget = $2, ratio = $3, effective_at = $4 WHERE id = $5",
+			entry.Balance, entry.Budget, entry.Ratio, entry.EffectiveAt, entry.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (db *Database) SelectUserMoneyDB(userID *uuid.UUID) ([]*MoneyEntry, error) {
 	if userID == nil {
 		return nil, errors.New("userID is nil")
 	}
-	rows, err := db.DB.Query("SELECT id, balance, budget, ratio, created_at, user_id FROM money WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	rows, err := db.DB.Query("SELECT "+moneyColumns+" FROM money WHERE user_id = $1 ORDER BY effective_at DESC, created_at DESC", userID)
 	if err != nil {
 		return nil, err
 	}
@@ -36,8 +189,8 @@ func (db *Database) SelectUserMoneyDB(userID *uuid.UUID) ([]*MoneyEntry, error)
 
 	var entries []*MoneyEntry
 	for rows.Next() {
-		entry := &MoneyEntry{}
-		if err := rows.Scan(&entry.ID, &entry.Balance, &entry.Budget, &entry.Ratio, &entry.CreatedAt, &entry.UserID); err != nil {
+		entry, err := scanMoneyEntry(rows)
+		if err != nil {
 			return nil, err
 		}
 		entries = append(entries, entry)
@@ -49,10 +202,23 @@ func (db *Database) SelectMoneyByIDDB(id *uuid.UUID) (*MoneyEntry, error) {
 	if id == nil {
 		return nil, errors.New("id is nil")
 	}
-	row := db.DB.QueryRow("SELECT id, balance, budget, ratio, created_at, user_id FROM money WHERE id = $1 ORDER BY created_at DESC", id)
+	row := db.DB.QueryRow("SELECT "+moneyColumns+" FROM money WHERE id = $1 ORDER BY effective_at DESC, created_at DESC", id)
 
-	entry := &MoneyEntry{}
-	if err := row.Scan(&entry.ID, &entry.Balance, &entry.Budget, &entry.Ratio, &entry.CreatedAt, &entry.UserID); err != nil {
+	entry, err := scanMoneyEntry(row)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (db *Database) SelectMoneyByTransactionIDDB(transactionID *uuid.UUID) (*MoneyEntry, error) {
+	if transactionID == nil {
+		return nil, errors.New("transactionID is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+moneyColumns+" FROM money WHERE transaction_id = $1", transactionID)
+
+	entry, err := scanMoneyEntry(row)
+	if err != nil {
 		return nil, err
 	}
 	return entry, nil
@@ -62,7 +228,53 @@ func (db *Database) SelectUserMoneyByCountDB(userID *uuid.UUID, count int64) ([]
 	if userID == nil {
 		return nil, errors.New("userID is nil")
 	}
-	rows, err := db.DB.Query("SELECT id, balance, budget, ratio, created_at, user_id FROM money WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2", userID, count)
+	rows, err := db.DB.Query("SELECT "+moneyColumns+" FROM money WHERE user_id = $1 ORDER BY effective_at DESC, created_at DESC LIMIT $2", userID, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*MoneyEntry
+	for rows.Next() {
+		entry, err := scanMoneyEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (db *Database) SelectUserMoneyPageDB(userID *uuid.UUID, query *MoneyQuery) ([]*MoneyEntry, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+
+	conditions := []string{"user_id = $1"}
+	args := []any{userID}
+	if query.AccountID != nil {
+		args = append(args, query.AccountID)
+		conditions = append(conditions, fmt.Sprintf("account_id = $%d", len(args)))
+	}
+	if query.From != nil {
+		args = append(args, *query.From)
+		conditions = append(conditions, fmt.Sprintf("effective_at >= $%d", len(args)))
+	}
+	if query.To != nil {
+		args = append(args, *query.To)
+		conditions = append(conditions, fmt.Sprintf("effective_at < $%d", len(args)))
+	}
+	if query.After != nil {
+		args = append(args, query.After.EffectiveAt, query.After.CreatedAt, query.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(effective_at, created_at, id) < ($%d, $%d::timestamptz, $%d)", len(args)-2, len(args)-1, len(args)))
+	}
+	args = append(args, query.Limit)
+
+	rows, err := db.DB.Query(
+		"SELECT "+moneyColumns+" FROM money WHERE "+strings.Join(conditions, " AND ")+
+			fmt.Sprintf(" ORDER BY effective_at DESC, created_at DESC, id DESC LIMIT $%d", len(args)),
+		args...,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -70,8 +282,58 @@ func (db *Database) SelectUserMoneyByCountDB(userID *uuid.UUID, count int64) ([]
 
 	var entries []*MoneyEntry
 	for rows.Next() {
-		entry := &MoneyEntry{}
-		if err := rows.Scan(&entry.ID, &entry.Balance, &entry.Budget, &entry.Ratio, &entry.CreatedAt, &entry.UserID); err != nil {
+		entry, err := scanMoneyEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (db *Database) SelectAccountMoneyDB(accountID *uuid.UUID) ([]*MoneyEntry, error) {
+	if accountID == nil {
+		return nil, errors.New("accountID is nil")
+	}
+	return selectAccountMoney(db.DB, accountID)
+}
+
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func selectAccountMoney(q querier, accountID *uuid.UUID) ([]*MoneyEntry, error) {
+	rows, err := q.Query("SELECT "+moneyColumns+" FROM money WHERE account_id = $1 ORDER BY effective_at DESC, created_at DESC", accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*MoneyEntry
+	for rows.Next() {
+		entry, err := scanMoneyEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (db *Database) SelectAccountMoneyByCountDB(accountID *uuid.UUID, count int64) ([]*MoneyEntry, error) {
+	if accountID == nil {
+		return nil, errors.New("accountID is nil")
+	}
+	rows, err := db.DB.Query("SELECT "+moneyColumns+" FROM money WHERE account_id = $1 ORDER BY effective_at DESC, created_at DESC LIMIT $2", accountID, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*MoneyEntry
+	for rows.Next() {
+		entry, err := scanMoneyEntry(rows)
+		if err != nil {
 			return nil, err
 		}
 		entries = append(entries, entry)
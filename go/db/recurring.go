@@ -0,0 +1,116 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Recurring is a schedule for an income or expense that is booked on the
+// account automatically, similar to an iCalendar RRULE with FREQ, INTERVAL,
+// BYMONTHDAY and UNTIL.
+type Recurring struct {
+	ID          uuid.UUID    `json:"id"`
+	Description string       `json:"description"`
+	Amount      money.Amount `json:"amount"`
+	Frequency   string       `json:"frequency"`
+	Interval    int          `json:"interval"`
+	DayOfMonth  *int         `json:"day_of_month"`
+	StartDate   time.Time    `json:"start_date"`
+	EndDate     *time.Time   `json:"end_date"`
+	Occurrence  int          `json:"-"`
+	NextRun     time.Time    `json:"next_run"`
+	CreatedAt   string       `json:"created_at"`
+	UserID      uuid.UUID    `json:"user_id"`
+	AccountID   uuid.UUID    `json:"account_id"`
+}
+
+const recurringColumns = "id, description, amount, frequency, interval, day_of_month, start_date, end_date, occurrence, next_run, created_at, user_id, account_id"
+
+func scanRecurring(row rowScanner) (*Recurring, error) {
+	recurring := &Recurring{}
+	err := row.Scan(&recurring.ID, &recurring.Description, &recurring.Amount, &recurring.Frequency,
+		&recurring.Interval, &recurring.DayOfMonth, &recurring.StartDate, &recurring.EndDate,
+		&recurring.Occurrence, &recurring.NextRun, &recurring.CreatedAt, &recurring.UserID, &recurring.AccountID)
+	return recurring, err
+}
+
+func (db *Database) InsertRecurringDB(recurring *Recurring) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := db.DB.QueryRow(
+		`INSERT INTO recurring (description, amount, frequency, interval, day_of_month, start_date, end_date, occurrence, next_run, user_id, account_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+		recurring.Description, recurring.Amount, recurring.Frequency, recurring.Interval, recurring.DayOfMonth,
+		recurring.StartDate, recurring.EndDate, recurring.Occurrence, recurring.NextRun, recurring.UserID, recurring.AccountID,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *Database) SelectRecurringByIDDB(id *uuid.UUID) (*Recurring, error) {
+	if id == nil {
+		return nil, errors.New("id is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+recurringColumns+" FROM recurring WHERE id = $1", id)
+
+	recurring, err := scanRecurring(row)
+	if err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
+
+func (db *Database) SelectUserRecurringDB(userID *uuid.UUID) ([]*Recurring, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	return db.selectRecurring("SELECT "+recurringColumns+" FROM recurring WHERE user_id = $1 ORDER BY next_run ASC", userID)
+}
+
+// SelectDueRecurringDB returns all schedules with an occurrence at or before
+// the given time that has not been materialized yet.
+func (db *Database) SelectDueRecurringDB(now time.Time) ([]*Recurring, error) {
+	return db.selectRecurring(
+		"SELECT "+recurringColumns+" FROM recurring WHERE next_run <= $1 AND (end_date IS NULL OR next_run <= end_date) ORDER BY next_run ASC",
+		now,
+	)
+}
+
+func (db *Database) selectRecurring(query string, args ...any) ([]*Recurring, error) {
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*Recurring
+	for rows.Next() {
+		recurring, err := scanRecurring(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, recurring)
+	}
+	return schedules, rows.Err()
+}
+
+func (db *Database) UpdateRecurringDB(recurring *Recurring) error {
+	_, err := db.DB.Exec(
+		`UPDATE recurring SET description = $1, amount = $2, end_date = $3, occurrence = $4, next_run = $5
+		 WHERE id = $6`,
+		recurring.Description, recurring.Amount, recurring.EndDate, recurring.Occurrence, recurring.NextRun, recurring.ID,
+	)
+	return err
+}
+
+func (db *Database) DeleteRecurringDB(id *uuid.UUID) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"DELETE FROM recurring WHERE id = $1",
+		id,
+	)
+	return err
+}
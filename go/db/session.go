@@ -0,0 +1,216 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login on one device. Current is set for the session of the
+// request that lists it.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  string    `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserID     uuid.UUID `json:"user_id"`
+	Current    bool      `json:"current"`
+}
+
+const sessionColumns = "id, device_name, ip_address, user_agent, created_at, last_used_at, user_id"
+
+func scanSession(row rowScanner) (*Session, error) {
+	session := &Session{}
+	err := row.Scan(&session.ID, &session.DeviceName, &session.IPAddress, &session.UserAgent,
+		&session.CreatedAt, &session.LastUsedAt, &session.UserID)
+	return session, err
+}
+
+// InsertSessionDB inserts the session and its tokens in a single
+// transaction, so there is never a session without a token. The IDs of the
+// session and the tokens' session are set.
+func (db *Database) InsertSessionDB(session *Session, tokens ...*Token) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(
+		"INSERT INTO sessions (device_name, ip_address, user_agent, user_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at, last_used_at",
+		session.DeviceName, session.IPAddress, session.UserAgent, session.UserID,
+	).Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := insertSessionTokens(tx, &session.ID, tokens); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// InsertSessionTokensDB adds tokens to an existing session in a single
+// transaction.
+func (db *Database) InsertSessionTokensDB(sessionID *uuid.UUID, tokens ...*Token) error {
+	if sessionID == nil {
+		return errors.New("sessionID is nil")
+	}
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := insertSessionTokens(tx, sessionID, tokens); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RotateRefreshTokenDB marks the refresh token as used and adds the tokens
+// replacing it to the session in a single transaction. Returns false without
+// adding the tokens if the refresh token was used before, so of two
+// concurrent uses only one succeeds. If adding the tokens fails, the refresh
+// token stays unused and can be retried.
+func (db *Database) RotateRefreshTokenDB(prefix string, at time.Time, sessionID *uuid.UUID, tokens ...*Token) (bool, error) {
+	if sessionID == nil {
+		return false, errors.New("sessionID is nil")
+	}
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec(
+		"UPDATE tokens SET used_at = $1 WHERE prefix = $2 AND used_at IS NULL",
+		at, prefix,
+	)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := insertSessionTokens(tx, sessionID, tokens); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func insertSessionTokens(tx *sql.Tx, sessionID *uuid.UUID, tokens []*Token) error {
+	for _, token := range tokens {
+		if err := hashTokenForInsert(token); err != nil {
+			return err
+		}
+		token.SessionID = sessionID
+		_, err := tx.Exec(
+			insertTokenQuery,
+			token.Prefix, token.Hash, token.UserID, token.Expiry, token.Purpose, token.SessionID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *Database) SelectSessionByIDDB(id *uuid.UUID) (*Session, error) {
+	if id == nil {
+		return nil, errors.New("id is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+sessionColumns+" FROM sessions WHERE id = $1", id)
+
+	session, err := scanSession(row)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SelectUserSessionsDB returns the sessions of the user, most recently used
+// first.
+func (db *Database) SelectUserSessionsDB(userID *uuid.UUID) ([]*Session, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	rows, err := db.DB.Query("SELECT "+sessionColumns+" FROM sessions WHERE user_id = $1 ORDER BY last_used_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchSessionDB sets the time the session was last used, unless it was
+// already used after notBefore, to save writes on every request.
+func (db *Database) TouchSessionDB(id *uuid.UUID, at time.Time, notBefore time.Time) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"UPDATE sessions SET last_used_at = $1 WHERE id = $2 AND last_used_at < $3",
+		at, id, notBefore,
+	)
+	return err
+}
+
+// DeleteSessionDB deletes the session and with it its tokens.
+func (db *Database) DeleteSessionDB(id *uuid.UUID) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"DELETE FROM sessions WHERE id = $1",
+		id,
+	)
+	return err
+}
+
+// DeleteUserSessionsDB deletes all sessions of the user except the one to
+// keep, if any, and returns the IDs of the deleted sessions.
+func (db *Database) DeleteUserSessionsDB(userID *uuid.UUID, keep *uuid.UUID) ([]uuid.UUID, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	rows, err := db.DB.Query(
+		"DELETE FROM sessions WHERE user_id = $1 AND ($2::uuid IS NULL OR id <> $2::uuid) RETURNING id",
+		userID, keep,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -1,22 +1,95 @@
 package database
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Token is an opaque secret of the form mm_<prefix>_<secret>. Only the prefix
+// and a hash of the whole token are stored, so Token is empty for tokens read
+// from the database and only known to whoever issued it.
 type Token struct {
-	Token  uuid.UUID `json:"token"`
-	UserID uuid.UUID `json:"userID"`
-	Expiry time.Time `json:"expiry"`
+	Token     string     `json:"token"`
+	Prefix    string     `json:"-"`
+	Hash      string     `json:"-"`
+	UserID    uuid.UUID  `json:"userID"`
+	Expiry    time.Time  `json:"expiry"`
+	Purpose   string     `json:"purpose"`
+	SessionID *uuid.UUID `json:"sessionID,omitempty"`
+	UsedAt    *time.Time `json:"-"`
 }
 
+const tokenColumns = "prefix, token_hash, user_id, expires_at, purpose, session_id, used_at"
+
+func scanToken(row rowScanner) (*Token, error) {
+	token := &Token{}
+	err := row.Scan(&token.Prefix, &token.Hash, &token.UserID, &token.Expiry, &token.Purpose, &token.SessionID, &token.UsedAt)
+	return token, err
+}
+
+const (
+	tokenScheme        = "mm"
+	tokenPrefixBytes   = 6
+	tokenSecretBytes   = 32
+	tokenPartSeparator = "_"
+)
+
+// NewTokenSecret generates a token and its prefix.
+func NewTokenSecret() (string, string) {
+	prefix := make([]byte, tokenPrefixBytes)
+	secret := make([]byte, tokenSecretBytes)
+	rand.Read(prefix)
+	rand.Read(secret)
+	prefixStr := hex.EncodeToString(prefix)
+	return strings.Join([]string{tokenScheme, prefixStr, hex.EncodeToString(secret)}, tokenPartSeparator), prefixStr
+}
+
+// HashToken returns the hex encoded SHA-256 hash of the token as stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenPrefix returns the prefix of a well-formed token.
+func TokenPrefix(token string) (string, error) {
+	parts := strings.Split(token, tokenPartSeparator)
+	if len(parts) != 3 || parts[0] != tokenScheme ||
+		len(parts[1]) != 2*tokenPrefixBytes || len(parts[2]) != 2*tokenSecretBytes {
+		return "", errors.New("malformed token")
+	}
+	return parts[1], nil
+}
+
+const insertTokenQuery = "INSERT INTO tokens (prefix, token_hash, user_id, expires_at, purpose, session_id) VALUES ($1, $2, $3, $4, $5, $6)"
+
+// hashTokenForInsert sets the prefix and hash the token is stored as.
+func hashTokenForInsert(Token *Token) error {
+	prefix, err := TokenPrefix(Token.Token)
+	if err != nil {
+		return err
+	}
+	Token.Prefix = prefix
+	Token.Hash = HashToken(Token.Token)
+	return nil
+}
+
+// InsertToken stores the prefix and hash of the token, never the token
+// itself.
 func (db *Database) InsertToken(Token *Token) error {
+	if err := hashTokenForInsert(Token); err != nil {
+		return err
+	}
+
 	err := db.DB.QueryRow(
-		"INSERT INTO tokens (token, user_id, expires_at) VALUES ($1, $2, $3)",
-		Token.Token, Token.UserID, Token.Expiry,
+		insertTokenQuery,
+		Token.Prefix, Token.Hash, Token.UserID, Token.Expiry, Token.Purpose, Token.SessionID,
 	).Err()
 	if err != nil {
 		return err
@@ -24,21 +97,25 @@ func (db *Database) InsertToken(Token *Token) error {
 	return nil
 }
 
-func (db *Database) GetToken(token *uuid.UUID) (*Token, error) {
-	if token == nil {
-		return nil, errors.New("token is nil")
+// GetToken looks the token up by its prefix and returns it if the stored
+// hash matches. The comparison takes constant time.
+func (db *Database) GetToken(token string) (*Token, error) {
+	prefix, err := TokenPrefix(token)
+	if err != nil {
+		return nil, err
 	}
-	Token := &Token{}
-	err := db.DB.QueryRow(
-		"SELECT user_id, expires_at FROM tokens WHERE token = $1",
-		token,
-	).Scan(&Token.UserID, &Token.Expiry)
-	Token.Token = *token
-	return Token, err
+	Token, err := scanToken(db.DB.QueryRow("SELECT "+tokenColumns+" FROM tokens WHERE prefix = $1", prefix))
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(Token.Hash), []byte(HashToken(token))) != 1 {
+		return nil, errors.New("token hash mismatch")
+	}
+	return Token, nil
 }
 
 func (db *Database) ListTokens() ([]*Token, error) {
-	rows, err := db.DB.Query("SELECT token, user_id, expires_at FROM tokens")
+	rows, err := db.DB.Query("SELECT " + tokenColumns + " FROM tokens")
 	if err != nil {
 		return nil, err
 	}
@@ -46,8 +123,8 @@ func (db *Database) ListTokens() ([]*Token, error) {
 
 	var tokens []*Token
 	for rows.Next() {
-		token := &Token{}
-		if err := rows.Scan(&token.Token, &token.UserID, &token.Expiry); err != nil {
+		token, err := scanToken(rows)
+		if err != nil {
 			return nil, err
 		}
 		tokens = append(tokens, token)
@@ -55,13 +132,10 @@ func (db *Database) ListTokens() ([]*Token, error) {
 	return tokens, rows.Err()
 }
 
-func (db *Database) DeleteToken(token *uuid.UUID) error {
-	if token == nil {
-		return errors.New("token is nil")
-	}
+func (db *Database) DeleteToken(prefix string) error {
 	_, err := db.DB.Exec(
-		"DELETE FROM tokens WHERE token = $1",
-		token,
+		"DELETE FROM tokens WHERE prefix = $1",
+		prefix,
 	)
 	return err
 }
@@ -77,10 +151,35 @@ func (db *Database) DeleteTokensByUserID(userID *uuid.UUID) error {
 	return err
 }
 
+// DeleteTokensByUserIDAndPurpose deletes the user's tokens issued for one
+// purpose, leaving the others valid.
+func (db *Database) DeleteTokensByUserIDAndPurpose(userID *uuid.UUID, purpose string) error {
+	if userID == nil {
+		return errors.New("userID is nil")
+	}
+	_, err := db.DB.Exec(
+		"DELETE FROM tokens WHERE user_id = $1 AND purpose = $2",
+		userID, purpose,
+	)
+	return err
+}
+
+// DeleteExpiredTokens deletes expired tokens and the sessions left without a
+// token that is still valid.
 func (db *Database) DeleteExpiredTokens() error {
+	now := time.Now()
 	_, err := db.DB.Exec(
+		`DELETE FROM sessions WHERE NOT EXISTS (
+			SELECT 1 FROM tokens WHERE tokens.session_id = sessions.id AND tokens.expires_at >= $1
+		)`,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = db.DB.Exec(
 		"DELETE FROM tokens WHERE expires_at < $1",
-		time.Now(),
+		now,
 	)
 	return err
 }
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+type Transaction struct {
+	ID        uuid.UUID    `json:"id"`
+	Amount    money.Amount `json:"amount"`
+	Category  string       `json:"category"`
+	Payee     string       `json:"payee"`
+	Note      string       `json:"note"`
+	Date      time.Time    `json:"date"`
+	CreatedAt string       `json:"created_at"`
+	UserID    uuid.UUID    `json:"user_id"`
+	AccountID uuid.UUID    `json:"account_id"`
+}
+
+// TransactionQuery filters a page of a user's transactions, newest first.
+// After is the position of the last transaction of the previous page. Nil
+// fields are not filtered on.
+type TransactionQuery struct {
+	AccountID *uuid.UUID
+	After     *TransactionCursor
+	Limit     int
+}
+
+// TransactionCursor is the position of a transaction in the newest first
+// order. The ID breaks ties between transactions created at the same time.
+type TransactionCursor struct {
+	Date      time.Time
+	CreatedAt string
+	ID        uuid.UUID
+}
+
+const transactionColumns = "id, amount, category, payee, note, date, created_at, user_id, account_id"
+
+func scanTransaction(row rowScanner) (*Transaction, error) {
+	transaction := &Transaction{}
+	err := row.Scan(&transaction.ID, &transaction.Amount, &transaction.Category, &transaction.Payee,
+		&transaction.Note, &transaction.Date, &transaction.CreatedAt, &transaction.UserID, &transaction.AccountID)
+	return transaction, err
+}
+
+func (db *Database) InsertTransactionDB(transaction *Transaction) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := db.DB.QueryRow(
+		"INSERT INTO transactions (amount, category, payee, note, date, user_id, account_id) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		transaction.Amount, transaction.Category, transaction.Payee, transaction.Note, transaction.Date, transaction.UserID, transaction.AccountID,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *Database) SelectTransactionByIDDB(id *uuid.UUID) (*Transaction, error) {
+	if id == nil {
+		return nil, errors.New("id is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+transactionColumns+" FROM transactions WHERE id = $1", id)
+
+	transaction, err := scanTransaction(row)
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+func (db *Database) SelectUserTransactionsDB(userID *uuid.UUID) ([]*Transaction, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	rows, err := db.DB.Query("SELECT "+transactionColumns+" FROM transactions WHERE user_id = $1 ORDER BY date DESC, created_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+func (db *Database) SelectUserTransactionsPageDB(userID *uuid.UUID, query *TransactionQuery) ([]*Transaction, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+
+	conditions := []string{"user_id = $1"}
+	args := []any{userID}
+	if query.AccountID != nil {
+		args = append(args, query.AccountID)
+		conditions = append(conditions, fmt.Sprintf("account_id = $%d", len(args)))
+	}
+	if query.After != nil {
+		args = append(args, query.After.Date, query.After.CreatedAt, query.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(date, created_at, id) < ($%d, $%d::timestamptz, $%d)", len(args)-2, len(args)-1, len(args)))
+	}
+	args = append(args, query.Limit)
+
+	rows, err := db.DB.Query(
+		"SELECT "+transactionColumns+" FROM transactions WHERE "+strings.Join(conditions, " AND ")+
+			fmt.Sprintf(" ORDER BY date DESC, created_at DESC, id DESC LIMIT $%d", len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+// SelectTransactionsByIDsDB returns the transactions with the given IDs, in no
+// particular order.
+func (db *Database) SelectTransactionsByIDsDB(ids []uuid.UUID) ([]*Transaction, error) {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+	rows, err := db.DB.Query("SELECT "+transactionColumns+" FROM transactions WHERE id = ANY($1::uuid[])", values)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+func (db *Database) UpdateTransactionDB(transaction *Transaction) error {
+	_, err := db.DB.Exec(
+		"UPDATE transactions SET amount = $1, category = $2, payee = $3, note = $4, date = $5 WHERE id = $6",
+		transaction.Amount, transaction.Category, transaction.Payee, transaction.Note, transaction.Date, transaction.ID,
+	)
+	return err
+}
+
+func (db *Database) DeleteTransactionDB(id *uuid.UUID) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"DELETE FROM transactions WHERE id = $1",
+		id,
+	)
+	return err
+}
@@ -1,6 +1,10 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -146,3 +150,61 @@ func (db *Database) CheckUserRoleDB(userID *uuid.UUID, role string) (bool, error
 	).Scan(&count)
 	return count > 0, err
 }
+
+// SetUserErasureDB schedules the erasure of the user at the given time, or
+// cancels it if at is nil.
+func (db *Database) SetUserErasureDB(userID *uuid.UUID, at *time.Time) error {
+	if userID == nil {
+		return errors.New("userID is nil")
+	}
+	result, err := db.DB.Exec(
+		"UPDATE users SET erasure_scheduled_at = $1 WHERE id = $2",
+		at, userID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (db *Database) SelectUserErasureDB(userID *uuid.UUID) (*time.Time, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	var at *time.Time
+	err := db.DB.QueryRow(
+		"SELECT erasure_scheduled_at FROM users WHERE id = $1",
+		userID,
+	).Scan(&at)
+	return at, err
+}
+
+// SelectDueErasuresDB returns the IDs of the users whose erasure is scheduled
+// at or before now.
+func (db *Database) SelectDueErasuresDB(now time.Time) ([]uuid.UUID, error) {
+	rows, err := db.DB.Query(
+		"SELECT id FROM users WHERE erasure_scheduled_at IS NOT NULL AND erasure_scheduled_at <= $1",
+		now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
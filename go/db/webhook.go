@@ -0,0 +1,235 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook is an endpoint of a user that receives the events it is subscribed
+// to. The secret signs the payloads and is only returned when the webhook is
+// created.
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	CreatedAt string    `json:"created_at"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+// WebhookDelivery is one event queued for a webhook or the webhook of an alert
+// rule, and the outcome of the last attempt to deliver it.
+type WebhookDelivery struct {
+	ID             uuid.UUID  `json:"id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus *int       `json:"response_status"`
+	LastError      string     `json:"last_error"`
+	CreatedAt      string     `json:"created_at"`
+	WebhookID      *uuid.UUID `json:"webhook_id"`
+	AlertRuleID    *uuid.UUID `json:"alert_rule_id"`
+}
+
+const webhookColumns = "id, url, secret, events, created_at, user_id"
+
+const webhookDeliveryColumns = "id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, last_error, created_at, webhook_id, alert_rule_id"
+
+func scanWebhook(row rowScanner) (*Webhook, error) {
+	webhook := &Webhook{}
+	var events string
+	err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &events, &webhook.CreatedAt, &webhook.UserID)
+	webhook.Events = strings.Split(events, ",")
+	return webhook, err
+}
+
+func scanWebhookDelivery(row rowScanner) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	err := row.Scan(&delivery.ID, &delivery.EventType, &delivery.Payload, &delivery.Status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.LastAttemptAt, &delivery.ResponseStatus, &delivery.LastError,
+		&delivery.CreatedAt, &delivery.WebhookID, &delivery.AlertRuleID)
+	return delivery, err
+}
+
+func (db *Database) InsertWebhookDB(webhook *Webhook) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := db.DB.QueryRow(
+		"INSERT INTO webhooks (url, secret, events, user_id) VALUES ($1, $2, $3, $4) RETURNING id",
+		webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.UserID,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *Database) SelectWebhookByIDDB(id *uuid.UUID) (*Webhook, error) {
+	if id == nil {
+		return nil, errors.New("id is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id)
+
+	webhook, err := scanWebhook(row)
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (db *Database) SelectUserWebhooksDB(userID *uuid.UUID) ([]*Webhook, error) {
+	if userID == nil {
+		return nil, errors.New("userID is nil")
+	}
+	rows, err := db.DB.Query("SELECT "+webhookColumns+" FROM webhooks WHERE user_id = $1 ORDER BY created_at ASC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (db *Database) UpdateWebhookDB(webhook *Webhook) error {
+	_, err := db.DB.Exec(
+		"UPDATE webhooks SET url = $1, events = $2 WHERE id = $3",
+		webhook.URL, strings.Join(webhook.Events, ","), webhook.ID,
+	)
+	return err
+}
+
+func (db *Database) DeleteWebhookDB(id *uuid.UUID) error {
+	if id == nil {
+		return errors.New("id is nil")
+	}
+	_, err := db.DB.Exec(
+		"DELETE FROM webhooks WHERE id = $1",
+		id,
+	)
+	return err
+}
+
+func (db *Database) InsertWebhookDeliveryDB(delivery *WebhookDelivery) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := db.DB.QueryRow(
+		"INSERT INTO webhook_deliveries (event_type, payload, next_attempt_at, webhook_id, alert_rule_id) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		delivery.EventType, delivery.Payload, delivery.NextAttemptAt, delivery.WebhookID, delivery.AlertRuleID,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *Database) SelectWebhookDeliveryByIDDB(id *uuid.UUID) (*WebhookDelivery, error) {
+	if id == nil {
+		return nil, errors.New("id is nil")
+	}
+	row := db.DB.QueryRow("SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE id = $1", id)
+
+	delivery, err := scanWebhookDelivery(row)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// SelectWebhookDeliveriesDB returns the latest deliveries of the webhook,
+// newest first.
+func (db *Database) SelectWebhookDeliveriesDB(webhookID *uuid.UUID, limit int) ([]*WebhookDelivery, error) {
+	if webhookID == nil {
+		return nil, errors.New("webhookID is nil")
+	}
+	rows, err := db.DB.Query(
+		"SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC LIMIT $2",
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// SelectAlertRuleDeliveriesDB returns the latest deliveries of the alert
+// rule's webhook, newest first.
+func (db *Database) SelectAlertRuleDeliveriesDB(ruleID *uuid.UUID, limit int) ([]*WebhookDelivery, error) {
+	if ruleID == nil {
+		return nil, errors.New("ruleID is nil")
+	}
+	rows, err := db.DB.Query(
+		"SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE alert_rule_id = $1 ORDER BY created_at DESC LIMIT $2",
+		ruleID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// SelectDueWebhookDeliveriesDB returns pending deliveries whose next attempt
+// is due at or before now, oldest first.
+func (db *Database) SelectDueWebhookDeliveriesDB(now time.Time, limit int) ([]*WebhookDelivery, error) {
+	rows, err := db.DB.Query(
+		"SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= $1 ORDER BY next_attempt_at ASC LIMIT $2",
+		now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+func scanWebhookDeliveries(rows *sql.Rows) ([]*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimWebhookDeliveryDB moves the next attempt of a due delivery to
+// leaseUntil, so no one else attempts it meanwhile. Returns whether the
+// delivery was claimed.
+func (db *Database) ClaimWebhookDeliveryDB(id *uuid.UUID, now time.Time, leaseUntil time.Time) (bool, error) {
+	if id == nil {
+		return false, errors.New("id is nil")
+	}
+	result, err := db.DB.Exec(
+		"UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id = $2 AND status = 'pending' AND next_attempt_at <= $3",
+		leaseUntil, id, now,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// UpdateWebhookDeliveryDB stores the outcome of an attempt.
+func (db *Database) UpdateWebhookDeliveryDB(delivery *WebhookDelivery) error {
+	_, err := db.DB.Exec(
+		`UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, last_attempt_at = $4,
+		response_status = $5, last_error = $6 WHERE id = $7`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastAttemptAt,
+		delivery.ResponseStatus, delivery.LastError, delivery.ID,
+	)
+	return err
+}
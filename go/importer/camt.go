@@ -0,0 +1,227 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+)
+
+// Elements of an ISO 20022 camt.053 bank to customer statement. Names are
+// matched without namespace, so all versions of the schema are read.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	IBAN     string        `xml:"Acct>Id>IBAN"`
+	Other    string        `xml:"Acct>Id>Othr>Id"`
+	Currency string        `xml:"Acct>Ccy"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Type   string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camtAmount `xml:"Amt"`
+	Credit string     `xml:"CdtDbtInd"`
+	Date   camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Reference string     `xml:"NtryRef"`
+	BankRef   string     `xml:"AcctSvcrRef"`
+	Amount    camtAmount `xml:"Amt"`
+	Credit    string     `xml:"CdtDbtInd"`
+	Status    camtStatus `xml:"Sts"`
+	BookedAt  camtDate   `xml:"BookgDt"`
+	Info      string     `xml:"AddtlNtryInf"`
+	Details   []camtTx   `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTx struct {
+	Remittance  []string `xml:"RmtInf>Ustrd"`
+	Creditor    string   `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPty string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Debtor      string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPty   string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	BankRef     string   `xml:"Refs>AcctSvcrRef"`
+}
+
+// camtStatus is written as text up to version 2019 and in a Cd element in
+// later versions.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// ParseCamt053 reads an ISO 20022 camt.053 statement file. Only booked entries
+// are read. A file with several statements of the same account, e.g. one per
+// day, is returned as a single statement from the first opening to the last
+// closing balance.
+func ParseCamt053(reader io.Reader) (*Statement, error) {
+	var document camtDocument
+	if err := xml.NewDecoder(reader).Decode(&document); err != nil {
+		return nil, err
+	}
+	if len(document.Statements) == 0 {
+		return nil, ErrNoStatement
+	}
+
+	statement := &Statement{Lines: []Line{}}
+	for i, camt := range document.Statements {
+		account := strings.TrimSpace(camt.IBAN)
+		if account == "" {
+			account = strings.TrimSpace(camt.Other)
+		}
+		if i == 0 {
+			statement.Account = account
+			statement.Currency = strings.ToUpper(strings.TrimSpace(camt.Currency))
+		} else if account != statement.Account {
+			return nil, errors.New("statements of several accounts")
+		}
+
+		for _, camtBalance := range camt.Balances {
+			balance, err := camtBalance.parse()
+			if err != nil {
+				return nil, err
+			}
+			switch strings.TrimSpace(camtBalance.Type) {
+			case "OPBD", "PRCD":
+				if statement.Opening == nil {
+					statement.Opening = balance
+				}
+			case "CLBD":
+				statement.Closing = balance
+			}
+			if statement.Currency == "" {
+				statement.Currency = strings.ToUpper(camtBalance.Amount.Currency)
+			}
+		}
+
+		for _, entry := range camt.Entries {
+			if !entry.booked() {
+				continue
+			}
+			line, err := entry.parse()
+			if err != nil {
+				return nil, err
+			}
+			statement.Lines = append(statement.Lines, line)
+		}
+	}
+
+	return statement, nil
+}
+
+func (balance *camtBalance) parse() (*Balance, error) {
+	amount, err := camtSigned(balance.Amount.Value, balance.Credit)
+	if err != nil {
+		return nil, err
+	}
+	date, err := balance.Date.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Balance{Date: date, Amount: amount}, nil
+}
+
+// booked reports whether the entry is final rather than pending.
+func (entry *camtEntry) booked() bool {
+	status := strings.TrimSpace(entry.Status.Code)
+	if status == "" {
+		status = strings.TrimSpace(entry.Status.Text)
+	}
+	return status == "" || status == "BOOK"
+}
+
+func (entry *camtEntry) parse() (Line, error) {
+	amount, err := camtSigned(entry.Amount.Value, entry.Credit)
+	if err != nil {
+		return Line{}, err
+	}
+	date, err := entry.BookedAt.parse()
+	if err != nil {
+		return Line{}, err
+	}
+
+	line := Line{
+		ID:     strings.TrimSpace(entry.BankRef),
+		Date:   date,
+		Amount: amount,
+		Memo:   strings.TrimSpace(entry.Info),
+	}
+	if len(entry.Details) > 0 {
+		tx := entry.Details[0]
+		// The other party is the creditor of payments and the debtor of
+		// incoming money
+		if amount < 0 {
+			line.Payee = strings.TrimSpace(tx.Creditor + tx.CreditorPty)
+		} else {
+			line.Payee = strings.TrimSpace(tx.Debtor + tx.DebtorPty)
+		}
+		if remittance := strings.TrimSpace(strings.Join(tx.Remittance, " ")); remittance != "" {
+			line.Memo = remittance
+		}
+		if line.ID == "" {
+			line.ID = strings.TrimSpace(tx.BankRef)
+		}
+	}
+	if line.ID == "" {
+		line.ID = strings.TrimSpace(entry.Reference)
+	}
+	return line, nil
+}
+
+func (date *camtDate) parse() (time.Time, error) {
+	if value := strings.TrimSpace(date.Date); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		return parsed, nil
+	}
+	if value := strings.TrimSpace(date.DateTime); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			// Offsets are optional in ISO 20022 date times
+			parsed, err = time.Parse("2006-01-02T15:04:05", value)
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		return parsed, nil
+	}
+	return time.Time{}, errors.New("missing date")
+}
+
+// camtSigned applies the credit or debit indicator to an amount, which camt
+// always writes as a positive number.
+func camtSigned(value string, indicator string) (money.Amount, error) {
+	amount, err := parseAmount(value)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.TrimSpace(indicator) {
+	case "CRDT":
+		return amount, nil
+	case "DBIT":
+		return -amount, nil
+	default:
+		return 0, fmt.Errorf("invalid credit or debit indicator %q", indicator)
+	}
+}
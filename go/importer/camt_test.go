@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+)
+
+func TestParseCamt053(t *testing.T) {
+	file, err := os.Open("testdata/statement.camt053.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	statement, err := ParseCamt053(file)
+	if err != nil {
+		t.Fatalf("Expected statement to parse, but got %s", err)
+	}
+
+	if statement.Account != "DE89370400440532013000" || statement.Currency != "EUR" {
+		t.Errorf("Expected IBAN account in EUR, but got %s in %s", statement.Account, statement.Currency)
+	}
+	if statement.Opening == nil || statement.Opening.Amount != money.FromInt(1000) || !statement.Opening.Date.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected opening balance of the first day, but got %+v", statement.Opening)
+	}
+	if statement.Closing == nil || statement.Closing.Amount != money.FromInt(3400) || !statement.Closing.Date.Equal(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected closing balance of the last day, but got %+v", statement.Closing)
+	}
+
+	// The pending entry is skipped
+	if len(statement.Lines) != 3 {
+		t.Fatalf("Expected 3 lines, but got %d", len(statement.Lines))
+	}
+	salary := statement.Lines[0]
+	if salary.ID != "2024010200001" || salary.Amount != money.FromInt(2500) || salary.Payee != "ACME GmbH" || salary.Memo != "Gehalt Januar" {
+		t.Errorf("Unexpected salary line %+v", salary)
+	}
+	if statement.Lines[1].Amount != money.FromInt(-50) || statement.Lines[1].Payee != "Stadtwerke" {
+		t.Errorf("Expected debit to Stadtwerke, but got %+v", statement.Lines[1])
+	}
+	card := statement.Lines[2]
+	if card.Memo != "Kartenzahlung Supermarkt" || !card.Date.Equal(time.Date(2024, 1, 3, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected card line %+v", card)
+	}
+}
@@ -0,0 +1,219 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Start of a field like :61: or :60F: at the beginning of a line
+var mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+
+// Value date, optional entry date, credit or debit mark with an optional
+// funds code, amount and transaction type of a :61: statement line
+var mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[CD])[A-Z]?([\d,]+)[NFS][A-Z0-9]{3}([^/\n]*)(?://([^\n]*))?`)
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// ParseMT940 reads a SWIFT MT940 customer statement. A file with several
+// statements of the same account, e.g. one per day, is returned as a single
+// statement from the first opening to the last closing balance.
+func ParseMT940(reader io.Reader) (*Statement, error) {
+	fields, err := mt940Fields(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var statement *Statement
+	var line *Line
+	for _, field := range fields {
+		switch field.tag {
+		case "25":
+			account := strings.TrimSpace(field.value)
+			if statement == nil {
+				statement = &Statement{Account: account, Lines: []Line{}}
+			} else if account != statement.Account {
+				return nil, errors.New("statements of several accounts")
+			}
+		case "60F", "60M":
+			if statement == nil {
+				return nil, errors.New("opening balance before account")
+			}
+			balance, currency, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, err
+			}
+			if statement.Opening == nil {
+				statement.Opening = balance
+				statement.Currency = currency
+			}
+		case "62F", "62M":
+			if statement == nil {
+				return nil, errors.New("closing balance before account")
+			}
+			balance, _, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, err
+			}
+			statement.Closing = balance
+		case "61":
+			if statement == nil {
+				return nil, errors.New("statement line before account")
+			}
+			parsed, err := parseMT940Line(field.value)
+			if err != nil {
+				return nil, err
+			}
+			statement.Lines = append(statement.Lines, parsed)
+			line = &statement.Lines[len(statement.Lines)-1]
+		case "86":
+			// Information to the account owner belongs to the line before
+			if line != nil {
+				parseMT940Details(line, field.value)
+				line = nil
+			}
+		default:
+			line = nil
+		}
+	}
+
+	if statement == nil {
+		return nil, ErrNoStatement
+	}
+	return statement, nil
+}
+
+// mt940Fields splits the text block of the message into fields. Lines that
+// don't start with a tag continue the field before, SWIFT block headers and
+// the trailing - are skipped.
+func mt940Fields(reader io.Reader) ([]mt940Field, error) {
+	fields := []mt940Field{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(text, "{") {
+			// {1:...}{2:...}{4: may be followed by the first field
+			if index := strings.Index(text, "{4:"); index >= 0 {
+				text = text[index+3:]
+			} else {
+				continue
+			}
+		}
+		if text == "-" || text == "-}" || strings.HasPrefix(text, "-}") || strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if match := mt940Tag.FindStringSubmatch(text); match != nil {
+			fields = append(fields, mt940Field{tag: match[1], value: text[len(match[0]):]})
+		} else if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + text
+		}
+	}
+	return fields, scanner.Err()
+}
+
+// parseMT940Balance reads balances like C240131EUR1234,56.
+func parseMT940Balance(value string) (*Balance, string, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 11 {
+		return nil, "", fmt.Errorf("invalid balance %q", value)
+	}
+
+	date, err := time.Parse("060102", value[1:7])
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid balance date %q", value)
+	}
+	amount, err := parseAmount(value[10:])
+	if err != nil {
+		return nil, "", err
+	}
+	switch value[0] {
+	case 'C':
+	case 'D':
+		amount = -amount
+	default:
+		return nil, "", fmt.Errorf("invalid balance %q", value)
+	}
+
+	return &Balance{Date: date, Amount: amount}, value[7:10], nil
+}
+
+// parseMT940Line reads a :61: statement line. The entry date is used if
+// present, since that is when the bank booked the line. Reversals (RC, RD)
+// count in the opposite direction. The bank's reference after // identifies
+// the line.
+func parseMT940Line(value string) (Line, error) {
+	match := mt940Line.FindStringSubmatch(value)
+	if match == nil {
+		return Line{}, fmt.Errorf("invalid statement line %q", value)
+	}
+
+	date, err := time.Parse("060102", match[1])
+	if err != nil {
+		return Line{}, fmt.Errorf("invalid statement line date %q", value)
+	}
+	if match[2] != "" {
+		entryDate, err := time.Parse("0102", match[2])
+		if err != nil {
+			return Line{}, fmt.Errorf("invalid statement line date %q", value)
+		}
+		// The entry date has no year and may fall into the year before or
+		// after the value date
+		booked := time.Date(date.Year(), entryDate.Month(), entryDate.Day(), 0, 0, 0, 0, time.UTC)
+		if booked.Sub(date) > 180*24*time.Hour {
+			booked = booked.AddDate(-1, 0, 0)
+		} else if date.Sub(booked) > 180*24*time.Hour {
+			booked = booked.AddDate(1, 0, 0)
+		}
+		date = booked
+	}
+
+	amount, err := parseAmount(match[4])
+	if err != nil {
+		return Line{}, err
+	}
+	if match[3] == "D" || match[3] == "RC" {
+		amount = -amount
+	}
+
+	line := Line{Date: date, Amount: amount}
+	if reference := strings.TrimSpace(match[6]); reference != "" && reference != "NONREF" {
+		line.ID = reference
+	}
+	return line, nil
+}
+
+// parseMT940Details reads the :86: field. The structured form German banks
+// use separates subfields with ?, e.g. ?20 to ?29 for the remittance
+// information and ?32, ?33 for the name of the other party. Anything else is
+// taken as memo.
+func parseMT940Details(line *Line, value string) {
+	value = strings.ReplaceAll(value, "\n", "")
+	if len(value) < 4 || !strings.Contains(value[:4], "?") {
+		line.Memo = strings.TrimSpace(value)
+		return
+	}
+
+	var memo, payee strings.Builder
+	for _, subfield := range strings.Split(value, "?")[1:] {
+		if len(subfield) < 2 {
+			continue
+		}
+		code, text := subfield[:2], subfield[2:]
+		switch {
+		case code >= "20" && code <= "29", code >= "60" && code <= "63":
+			memo.WriteString(text)
+		case code == "32", code == "33":
+			payee.WriteString(text)
+		}
+	}
+	line.Memo = strings.TrimSpace(memo.String())
+	line.Payee = strings.TrimSpace(payee.String())
+}
@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+)
+
+func TestParseMT940(t *testing.T) {
+	file, err := os.Open("testdata/statement.mt940")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	statement, err := ParseMT940(file)
+	if err != nil {
+		t.Fatalf("Expected statement to parse, but got %s", err)
+	}
+
+	if statement.Account != "37040044/0532013000" || statement.Currency != "EUR" {
+		t.Errorf("Expected account 37040044/0532013000 in EUR, but got %s in %s", statement.Account, statement.Currency)
+	}
+	if statement.Opening == nil || statement.Opening.Amount != money.FromInt(1000) {
+		t.Errorf("Expected opening balance 1000, but got %+v", statement.Opening)
+	}
+	if statement.Closing == nil || statement.Closing.Amount != money.FromInt(3460) || !statement.Closing.Date.Equal(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected closing balance 3460 on 2024-01-03, but got %+v", statement.Closing)
+	}
+
+	expected := []Line{
+		{ID: "2024010200001", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: money.FromInt(2500), Payee: "ACME GmbH", Memo: "Gehalt Januar"},
+		{ID: "2024010200002", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: money.FromInt(-50), Payee: "Stadtwerke", Memo: "Abschlag Strom"},
+		// A reversed debit, booked in the year before its value date
+		{Date: time.Date(2023, 12, 29, 0, 0, 0, 0, time.UTC), Amount: money.FromInt(10), Memo: "Storno Gebuehr"},
+	}
+	if len(statement.Lines) != len(expected) {
+		t.Fatalf("Expected %d lines, but got %d", len(expected), len(statement.Lines))
+	}
+	for i, e := range expected {
+		line := statement.Lines[i]
+		if line.ID != e.ID || !line.Date.Equal(e.Date) || line.Amount != e.Amount || line.Payee != e.Payee || line.Memo != e.Memo {
+			t.Errorf("Expected line %d %+v, but got %+v", i, e, line)
+		}
+	}
+}
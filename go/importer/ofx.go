@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ofxToken struct {
+	tag     string
+	closing bool
+	text    string
+}
+
+// ParseOFX reads an OFX 1.x file, the SGML dialect QFX files use as well, or
+// an OFX 2.x XML file and returns the first bank or credit card statement in
+// it. Files with several accounts have to be imported once per account.
+func ParseOFX(reader io.Reader) (*Statement, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	content := string(data)
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("missing OFX element")
+	}
+
+	var statement *Statement
+	var line *Line
+	inAccount := false
+	for _, token := range tokenizeOFX(content[start:]) {
+		if token.closing {
+			switch token.tag {
+			case "STMTRS", "CCSTMTRS":
+				if statement != nil {
+					return statement, nil
+				}
+			case "BANKACCTFROM", "CCACCTFROM":
+				inAccount = false
+			case "STMTTRN":
+				if line == nil || statement == nil {
+					continue
+				}
+				if line.Date.IsZero() {
+					return nil, fmt.Errorf("transaction %q: missing date", line.ID)
+				}
+				statement.Lines = append(statement.Lines, *line)
+				line = nil
+			}
+			continue
+		}
+
+		switch token.tag {
+		case "STMTRS", "CCSTMTRS":
+			statement = &Statement{Lines: []Line{}}
+			continue
+		case "BANKACCTFROM", "CCACCTFROM":
+			inAccount = true
+			continue
+		case "STMTTRN":
+			line = &Line{}
+			continue
+		}
+		if statement == nil || token.text == "" {
+			continue
+		}
+
+		switch {
+		case token.tag == "CURDEF":
+			statement.Currency = strings.ToUpper(token.text)
+		case token.tag == "ACCTID" && inAccount:
+			statement.Account = token.text
+		case line == nil:
+		case token.tag == "FITID":
+			line.ID = token.text
+		case token.tag == "DTPOSTED":
+			if line.Date, err = parseOFXDate(token.text); err != nil {
+				return nil, err
+			}
+		case token.tag == "TRNAMT":
+			if line.Amount, err = parseAmount(token.text); err != nil {
+				return nil, err
+			}
+		case token.tag == "NAME":
+			line.Payee = token.text
+		case token.tag == "MEMO":
+			line.Memo = token.text
+		}
+	}
+
+	if statement == nil {
+		return nil, ErrNoStatement
+	}
+	// A truncated SGML file may lack the closing tag of its statement
+	return statement, nil
+}
+
+// tokenizeOFX splits OFX content into tags and the text following them. In
+// SGML files leaf elements are not closed, so their value is simply the text
+// up to the next tag.
+func tokenizeOFX(content string) []ofxToken {
+	tokens := []ofxToken{}
+	for {
+		open := strings.IndexByte(content, '<')
+		if open < 0 {
+			return tokens
+		}
+		end := strings.IndexByte(content[open:], '>')
+		if end < 0 {
+			return tokens
+		}
+		end += open
+
+		token := ofxToken{tag: strings.ToUpper(strings.TrimSpace(content[open+1 : end]))}
+		if tag, ok := strings.CutPrefix(token.tag, "/"); ok {
+			token.tag = tag
+			token.closing = true
+		}
+
+		content = content[end+1:]
+		next := strings.IndexByte(content, '<')
+		if next < 0 {
+			next = len(content)
+		}
+		token.text = html.UnescapeString(strings.TrimSpace(content[:next]))
+		tokens = append(tokens, token)
+	}
+}
+
+// parseOFXDate reads dates like 20240105, 20240105120000.000 or
+// 20240105120000.000[-5:EST]. Dates without an offset are taken as UTC.
+func parseOFXDate(value string) (time.Time, error) {
+	location := time.UTC
+	datetime, zone, hasZone := strings.Cut(value, "[")
+	if hasZone {
+		offsetStr, name, _ := strings.Cut(strings.TrimSuffix(zone, "]"), ":")
+		offset, err := strconv.ParseFloat(offsetStr, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		if name == "" {
+			name = "UTC" + offsetStr
+		}
+		location = time.FixedZone(name, int(offset*3600))
+	}
+	datetime, _, _ = strings.Cut(strings.TrimSpace(datetime), ".")
+
+	var layout string
+	switch len(datetime) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	date, err := time.ParseInLocation(layout, datetime, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
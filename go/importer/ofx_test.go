@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+)
+
+func TestParseOFX_SGML(t *testing.T) {
+	file, err := os.Open("testdata/checking.qfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	statement, err := ParseOFX(file)
+	if err != nil {
+		t.Fatalf("Expected statement to parse, but got %s", err)
+	}
+
+	if statement.Account != "000123456789" || statement.Currency != "USD" {
+		t.Errorf("Expected account 000123456789 in USD, but got %s in %s", statement.Account, statement.Currency)
+	}
+	if len(statement.Lines) != 3 {
+		t.Fatalf("Expected 3 lines, but got %d", len(statement.Lines))
+	}
+
+	first := statement.Lines[0]
+	expectedDate := time.Date(2024, 1, 2, 17, 0, 0, 0, time.UTC)
+	if first.ID != "202401020001" || !first.Date.Equal(expectedDate) || first.Amount != money.FromInt(2500) {
+		t.Errorf("Unexpected first line %+v", first)
+	}
+	if first.Payee != "ACME PAYROLL" || first.Memo != "Salary January" {
+		t.Errorf("Expected payee and memo of the salary, but got %q and %q", first.Payee, first.Memo)
+	}
+	if statement.Lines[1].Amount != money.MustParseAmount("-1234.56") || statement.Lines[1].Payee != "Landlord & Sons" {
+		t.Errorf("Unexpected second line %+v", statement.Lines[1])
+	}
+}
+
+func TestParseOFX_XML(t *testing.T) {
+	file, err := os.Open("testdata/creditcard.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	statement, err := ParseOFX(file)
+	if err != nil {
+		t.Fatalf("Expected statement to parse, but got %s", err)
+	}
+
+	if statement.Account != "4111 1111 1111 1111" || statement.Currency != "EUR" {
+		t.Errorf("Expected credit card account in EUR, but got %s in %s", statement.Account, statement.Currency)
+	}
+	if len(statement.Lines) != 2 {
+		t.Fatalf("Expected 2 lines, but got %d", len(statement.Lines))
+	}
+	if statement.Lines[0].Amount != money.MustParseAmount("-42.90") || statement.Lines[0].Payee != "Buchhandlung" {
+		t.Errorf("Unexpected first line %+v", statement.Lines[0])
+	}
+	if expected := time.Date(2024, 2, 3, 9, 15, 0, 0, time.UTC); !statement.Lines[0].Date.Equal(expected) {
+		t.Errorf("Expected date %s, but got %s", expected, statement.Lines[0].Date)
+	}
+}
+
+func TestParseOFX_Invalid(t *testing.T) {
+	if _, err := ParseOFX(strings.NewReader("not an ofx file")); err == nil {
+		t.Errorf("Expected file without OFX element to be rejected")
+	}
+	if _, err := ParseOFX(strings.NewReader("<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>")); err != ErrNoStatement {
+		t.Errorf("Expected %s, but got %v", ErrNoStatement, err)
+	}
+}
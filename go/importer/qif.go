@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseQIF reads the transactions of a bank, cash or credit card account from
+// a QIF file. QIF has no standard date order, dayFirst reads dates as D/M/Y
+// instead of the M/D/Y Quicken writes in the US. The account name of an
+// !Account block is used as the statement's account.
+func ParseQIF(reader io.Reader, dayFirst bool) (*Statement, error) {
+	statement := &Statement{Lines: []Line{}}
+	scanner := bufio.NewScanner(reader)
+
+	var line Line
+	hasAmount := false
+	inAccount := false
+	inTransactions := false
+	found := false
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if lineNumber == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		code, value := text[0], strings.TrimSpace(text[1:])
+
+		if code == '!' {
+			header := strings.ToLower(value)
+			switch {
+			case header == "account":
+				inAccount = true
+			case strings.HasPrefix(header, "type:bank"), strings.HasPrefix(header, "type:cash"),
+				strings.HasPrefix(header, "type:ccard"), strings.HasPrefix(header, "type:oth"):
+				inAccount = false
+				inTransactions = true
+				found = true
+			case strings.HasPrefix(header, "type:cat"), strings.HasPrefix(header, "type:class"),
+				strings.HasPrefix(header, "type:memorized"):
+				// Lists of categories and the like are not needed
+				inAccount = false
+				inTransactions = false
+			case strings.HasPrefix(header, "type:"):
+				return nil, fmt.Errorf("line %d: unsupported account type %q", lineNumber, value)
+			}
+			continue
+		}
+
+		if inAccount {
+			if code == 'N' {
+				statement.Account = value
+			}
+			continue
+		}
+		if !inTransactions {
+			continue
+		}
+
+		var err error
+		switch code {
+		case 'D':
+			if line.Date, err = parseQIFDate(value, dayFirst); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+		case 'T', 'U':
+			// U repeats T with more precision in newer Quicken versions
+			if line.Amount, err = parseAmount(value); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			hasAmount = true
+		case 'P':
+			line.Payee = value
+		case 'M':
+			line.Memo = value
+		case 'L':
+			line.Category = value
+		case '^':
+			if line.Date.IsZero() || !hasAmount {
+				return nil, fmt.Errorf("line %d: transaction without date or amount", lineNumber)
+			}
+			statement.Lines = append(statement.Lines, line)
+			line = Line{}
+			hasAmount = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, ErrNoStatement
+	}
+	return statement, nil
+}
+
+// parseQIFDate reads dates like 01/05/2024, 1/ 5'24 or 2024-01-05. Two digit
+// years before 70 are in this century.
+func parseQIFDate(value string, dayFirst bool) (time.Time, error) {
+	normalized := strings.NewReplacer("'", "/", " ", "", "-", "/", ".", "/").Replace(value)
+	parts := strings.Split(normalized, "/")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	numbers := [3]int{}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		numbers[i] = number
+	}
+
+	year, month, day := numbers[2], numbers[0], numbers[1]
+	if len(parts[0]) == 4 {
+		year, month, day = numbers[0], numbers[1], numbers[2]
+	} else if dayFirst {
+		month, day = day, month
+	}
+	if len(parts[2]) <= 2 && len(parts[0]) != 4 {
+		if year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(month) || date.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+)
+
+func TestParseQIF(t *testing.T) {
+	file, err := os.Open("testdata/checking.qif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	statement, err := ParseQIF(file, false)
+	if err != nil {
+		t.Fatalf("Expected statement to parse, but got %s", err)
+	}
+
+	if statement.Account != "Everyday Checking" {
+		t.Errorf("Expected account Everyday Checking, but got %s", statement.Account)
+	}
+	if len(statement.Lines) != 3 {
+		t.Fatalf("Expected 3 lines, but got %d", len(statement.Lines))
+	}
+
+	expected := []struct {
+		date     time.Time
+		amount   money.Amount
+		category string
+	}{
+		{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), money.FromInt(2500), "Income:Salary"},
+		{time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), money.MustParseAmount("-1234.56"), "Rent"},
+		{time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), money.FromInt(-100), "[Savings]"},
+	}
+	for i, e := range expected {
+		line := statement.Lines[i]
+		if !line.Date.Equal(e.date) || line.Amount != e.amount || line.Category != e.category {
+			t.Errorf("Expected line %d on %s with %s in %s, but got %+v", i, e.date, e.amount, e.category, line)
+		}
+	}
+	if statement.Lines[0].Payee != "ACME Payroll" || statement.Lines[0].Memo != "Salary January" {
+		t.Errorf("Unexpected payee or memo %+v", statement.Lines[0])
+	}
+}
+
+func TestParseQIFDate_DayFirst(t *testing.T) {
+	date, err := parseQIFDate("05.01.2024", true)
+	if err != nil || !date.Equal(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2024-01-05, but got %s (%v)", date, err)
+	}
+	if _, err := parseQIFDate("31/02/2024", true); err == nil {
+		t.Errorf("Expected invalid date to be rejected")
+	}
+}
+
+func TestParseQIF_Investment(t *testing.T) {
+	if _, err := ParseQIF(strings.NewReader("!Type:Invst\nD01/02/2024\n^\n"), false); err == nil {
+		t.Errorf("Expected investment accounts to be rejected")
+	}
+}
@@ -0,0 +1,62 @@
+// Package importer reads bank statements in the formats banks and personal
+// finance tools export, so they can be booked as balance entries and
+// transactions.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+)
+
+// ErrNoStatement is returned for files that parse but hold no statement.
+var ErrNoStatement = errors.New("no statement found")
+
+// Statement is a bank statement read from any of the supported formats.
+// Account identifies the account at the bank, e.g. its number or IBAN, and is
+// empty if the format has none. Opening and Closing are the booked balances
+// before and after the lines, for formats that report them.
+type Statement struct {
+	Account  string
+	Currency string
+	Opening  *Balance
+	Closing  *Balance
+	Lines    []Line
+}
+
+// Balance is the booked balance of the account on a date.
+type Balance struct {
+	Date   time.Time
+	Amount money.Amount
+}
+
+// Line is a single booking on a statement. ID is the bank's identifier of the
+// booking, like the OFX FITID, and is empty if the format has none.
+type Line struct {
+	ID       string
+	Date     time.Time
+	Amount   money.Amount
+	Payee    string
+	Memo     string
+	Category string
+}
+
+// parseAmount reads an amount with a decimal point, or a decimal comma if
+// there is no point, and optional thousands separators.
+func parseAmount(value string) (money.Amount, error) {
+	normalized := strings.TrimSpace(value)
+	if strings.Contains(normalized, ".") {
+		normalized = strings.ReplaceAll(normalized, ",", "")
+	} else {
+		normalized = strings.ReplaceAll(normalized, ",", ".")
+	}
+
+	amount, err := money.ParseAmount(normalized)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return amount, nil
+}
@@ -0,0 +1,346 @@
+package logic
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+// Signed access tokens are HS256 JSON Web Tokens. They are validated without
+// a database lookup, so sessions revoked on this instance are remembered in
+// memory until their access tokens have expired. The revocation list file
+// covers other instances in emergencies.
+const (
+	accessTokenAlgorithm = "HS256"
+	accessTokenType      = "JWT"
+	// Least length of a signing key, the size of the SHA-256 output
+	minAccessTokenKeyBytes = 32
+)
+
+// AccessTokenKeyset is the keyset file. Keys maps key IDs to base64 encoded
+// secrets. New tokens are signed with the active key, all keys are accepted.
+// To rotate, add a key and make it active, then remove the old key once the
+// tokens signed with it have expired.
+type AccessTokenKeyset struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+// AccessTokenRevocations is the revocation list file for emergencies. Tokens
+// are revoked by ID, whole sessions by session ID, and all tokens of a user
+// that were issued up to the given time.
+type AccessTokenRevocations struct {
+	Tokens   []string                `json:"tokens"`
+	Sessions []uuid.UUID             `json:"sessions"`
+	Users    map[uuid.UUID]time.Time `json:"users"`
+}
+
+type accessTokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+type accessTokenClaims struct {
+	Subject   uuid.UUID `json:"sub"`
+	SessionID uuid.UUID `json:"sid"`
+	ID        string    `json:"jti"`
+	IssuedAt  int64     `json:"iat"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+// AccessTokenSigner signs and validates access tokens with the keys of the
+// keyset file. Both files are read again by Reload when they have changed.
+type AccessTokenSigner struct {
+	keysetFile     string
+	revocationFile string
+
+	mu          sync.RWMutex
+	activeKey   string
+	keys        map[string][]byte
+	tokens      map[string]struct{}
+	sessions    map[uuid.UUID]struct{}
+	users       map[uuid.UUID]time.Time
+	modifiedAts map[string]time.Time
+	// Sessions revoked on this instance, until their access tokens expire
+	revokedSessions map[uuid.UUID]time.Time
+}
+
+// NewAccessTokenSigner loads the keyset file and, if given, the revocation
+// list file.
+func NewAccessTokenSigner(keysetFile string, revocationFile string) (*AccessTokenSigner, error) {
+	signer := &AccessTokenSigner{
+		keysetFile:      keysetFile,
+		revocationFile:  revocationFile,
+		modifiedAts:     map[string]time.Time{},
+		revokedSessions: map[uuid.UUID]time.Time{},
+	}
+	if _, err := signer.Reload(); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// Reload reads the files that changed since they were last read and reports
+// whether anything was reloaded. On error the previous keys and revocations
+// stay in use.
+func (signer *AccessTokenSigner) Reload() (bool, error) {
+	reloaded := false
+
+	if modifiedAt, changed, err := signer.fileChanged(signer.keysetFile); err != nil {
+		return reloaded, err
+	} else if changed {
+		var keyset AccessTokenKeyset
+		if err := readJSONFile(signer.keysetFile, &keyset); err != nil {
+			return reloaded, fmt.Errorf("reading keyset: %w", err)
+		}
+		keys, err := parseAccessTokenKeyset(&keyset)
+		if err != nil {
+			return reloaded, fmt.Errorf("reading keyset: %w", err)
+		}
+
+		signer.mu.Lock()
+		signer.activeKey = keyset.Active
+		signer.keys = keys
+		signer.modifiedAts[signer.keysetFile] = modifiedAt
+		signer.mu.Unlock()
+		reloaded = true
+	}
+
+	if signer.revocationFile == "" {
+		return reloaded, nil
+	}
+	if modifiedAt, changed, err := signer.fileChanged(signer.revocationFile); err != nil {
+		return reloaded, err
+	} else if changed {
+		var revocations AccessTokenRevocations
+		if err := readJSONFile(signer.revocationFile, &revocations); err != nil {
+			return reloaded, fmt.Errorf("reading revocation list: %w", err)
+		}
+
+		tokens := map[string]struct{}{}
+		for _, id := range revocations.Tokens {
+			tokens[id] = struct{}{}
+		}
+		sessions := map[uuid.UUID]struct{}{}
+		for _, id := range revocations.Sessions {
+			sessions[id] = struct{}{}
+		}
+
+		signer.mu.Lock()
+		signer.tokens = tokens
+		signer.sessions = sessions
+		signer.users = revocations.Users
+		signer.modifiedAts[signer.revocationFile] = modifiedAt
+		signer.mu.Unlock()
+		reloaded = true
+	}
+
+	return reloaded, nil
+}
+
+func (signer *AccessTokenSigner) fileChanged(path string) (time.Time, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	signer.mu.RLock()
+	defer signer.mu.RUnlock()
+	modifiedAt, ok := signer.modifiedAts[path]
+	return info.ModTime(), !ok || !info.ModTime().Equal(modifiedAt), nil
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func parseAccessTokenKeyset(keyset *AccessTokenKeyset) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for id, encoded := range keyset.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not base64: %w", id, err)
+		}
+		if len(key) < minAccessTokenKeyBytes {
+			return nil, fmt.Errorf("key %q is shorter than %d bytes", id, minAccessTokenKeyBytes)
+		}
+		keys[id] = key
+	}
+	if _, ok := keys[keyset.Active]; !ok {
+		return nil, fmt.Errorf("active key %q not found", keyset.Active)
+	}
+	return keys, nil
+}
+
+// Sign issues an access token of the session, valid for the lifetime of
+// session tokens.
+func (signer *AccessTokenSigner) Sign(userID uuid.UUID, sessionID uuid.UUID, now time.Time) (database.Token, error) {
+	signer.mu.RLock()
+	keyID := signer.activeKey
+	key := signer.keys[keyID]
+	signer.mu.RUnlock()
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	expiry := now.Add(tokenLifetimes[TokenPurposeSession])
+
+	header, err := json.Marshal(accessTokenHeader{Algorithm: accessTokenAlgorithm, Type: accessTokenType, KeyID: keyID})
+	if err != nil {
+		return database.Token{}, err
+	}
+	claims, err := json.Marshal(accessTokenClaims{
+		Subject:   userID,
+		SessionID: sessionID,
+		ID:        hex.EncodeToString(id),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiry.Unix(),
+	})
+	if err != nil {
+		return database.Token{}, err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	signature := base64.RawURLEncoding.EncodeToString(accessTokenSignature(key, signingInput))
+
+	return database.Token{
+		Token:     signingInput + "." + signature,
+		UserID:    userID,
+		Expiry:    time.Unix(expiry.Unix(), 0),
+		Purpose:   TokenPurposeSession,
+		SessionID: &sessionID,
+	}, nil
+}
+
+// Validate checks the signature, expiry and revocation of an access token
+// without touching the database.
+func (signer *AccessTokenSigner) Validate(tokenStr string, now time.Time) (*database.Token, error) {
+	parts := strings.Split(tokenStr, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenFormat
+	}
+
+	var header accessTokenHeader
+	if err := decodeAccessTokenPart(parts[0], &header); err != nil {
+		return nil, ErrTokenFormat
+	}
+	// Only the algorithm the tokens are issued with is accepted
+	if header.Algorithm != accessTokenAlgorithm {
+		fmt.Println("Access token with algorithm", header.Algorithm)
+		return nil, ErrTokenInvalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenFormat
+	}
+
+	signer.mu.RLock()
+	defer signer.mu.RUnlock()
+
+	key, ok := signer.keys[header.KeyID]
+	if !ok {
+		fmt.Println("Access token signed with unknown key", header.KeyID)
+		return nil, ErrTokenInvalid
+	}
+	if !hmac.Equal(signature, accessTokenSignature(key, parts[0]+"."+parts[1])) {
+		return nil, ErrTokenInvalid
+	}
+
+	var claims accessTokenClaims
+	if err := decodeAccessTokenPart(parts[1], &claims); err != nil {
+		return nil, ErrTokenFormat
+	}
+
+	expiry := time.Unix(claims.ExpiresAt, 0)
+	if !now.Before(expiry) {
+		return nil, ErrTokenExpired
+	}
+
+	if _, ok := signer.tokens[claims.ID]; ok {
+		return nil, ErrTokenRevoked
+	}
+	if _, ok := signer.sessions[claims.SessionID]; ok {
+		return nil, ErrTokenRevoked
+	}
+	if until, ok := signer.revokedSessions[claims.SessionID]; ok && now.Before(until) {
+		return nil, ErrTokenRevoked
+	}
+	if revokedAt, ok := signer.users[claims.Subject]; ok && claims.IssuedAt <= revokedAt.Unix() {
+		return nil, ErrTokenRevoked
+	}
+
+	return &database.Token{
+		Token:     tokenStr,
+		UserID:    claims.Subject,
+		Expiry:    expiry,
+		Purpose:   TokenPurposeSession,
+		SessionID: &claims.SessionID,
+	}, nil
+}
+
+// RevokeSessions rejects the access tokens of the sessions until every token
+// issued before now has expired. It does nothing on a nil signer, so callers
+// don't have to check whether access tokens are signed.
+func (signer *AccessTokenSigner) RevokeSessions(sessionIDs []uuid.UUID, now time.Time) {
+	if signer == nil || len(sessionIDs) == 0 {
+		return
+	}
+	until := now.Add(tokenLifetimes[TokenPurposeSession])
+
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+	// Forget sessions whose tokens have all expired
+	for id, revokedUntil := range signer.revokedSessions {
+		if !now.Before(revokedUntil) {
+			delete(signer.revokedSessions, id)
+		}
+	}
+	for _, id := range sessionIDs {
+		signer.revokedSessions[id] = until
+	}
+}
+
+func accessTokenSignature(key []byte, signingInput string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+func decodeAccessTokenPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// IsSignedAccessToken reports whether the token looks like a signed access
+// token rather than an opaque one.
+func IsSignedAccessToken(tokenStr string) bool {
+	return strings.Count(tokenStr, ".") == 2
+}
+
+// ValidateAccessToken validates the access token of a request. Signed tokens
+// are checked in memory when a signer is configured, opaque tokens are looked
+// up in the store, so sessions from before signing was enabled keep working.
+func ValidateAccessToken(store database.SessionTokenStore, signer *AccessTokenSigner, tokenStr string) (*database.Token, error) {
+	if signer != nil && IsSignedAccessToken(tokenStr) {
+		return signer.Validate(tokenStr, time.Now())
+	}
+	return ValidateSession(store, tokenStr)
+}
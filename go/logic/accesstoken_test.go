@@ -0,0 +1,275 @@
+package logic
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+func writeAccessTokenFile(t *testing.T, path string, v any, modifiedAt time.Time) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Set the time explicitly, writes within the same clock tick would not
+	// be noticed by Reload
+	if err := os.Chtimes(path, modifiedAt, modifiedAt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func accessTokenKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), minAccessTokenKeyBytes)))
+}
+
+func newTestAccessTokenSigner(t *testing.T) (*AccessTokenSigner, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	keysetFile := filepath.Join(dir, "keyset.json")
+	revocationFile := filepath.Join(dir, "revocations.json")
+	modifiedAt := time.Now().Add(-time.Hour)
+	writeAccessTokenFile(t, keysetFile, AccessTokenKeyset{Active: "a", Keys: map[string]string{"a": accessTokenKey('a')}}, modifiedAt)
+	writeAccessTokenFile(t, revocationFile, AccessTokenRevocations{}, modifiedAt)
+
+	signer, err := NewAccessTokenSigner(keysetFile, revocationFile)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	return signer, keysetFile, revocationFile
+}
+
+func mustDecodeHeader(t *testing.T, token string) []byte {
+	t.Helper()
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header
+}
+
+func TestAccessTokenSigner_SignAndValidate(t *testing.T) {
+	signer, _, _ := newTestAccessTokenSigner(t)
+	userID, sessionID := uuid.New(), uuid.New()
+	now := time.Now()
+
+	token, err := signer.Sign(userID, sessionID, now)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if !IsSignedAccessToken(token.Token) {
+		t.Fatalf("Expected a signed token, but got %s", token.Token)
+	}
+
+	validated, err := signer.Validate(token.Token, now)
+	if err != nil {
+		t.Fatalf("Expected token to be valid, but got %v", err)
+	}
+	if validated.UserID != userID || validated.SessionID == nil || *validated.SessionID != sessionID {
+		t.Errorf("Expected user %s and session %s, but got %s and %v", userID, sessionID, validated.UserID, validated.SessionID)
+	}
+	if !validated.Expiry.Equal(token.Expiry) {
+		t.Errorf("Expected expiry %s, but got %s", token.Expiry, validated.Expiry)
+	}
+
+	if _, err := signer.Validate(token.Token, token.Expiry); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected error %v, but got %v", ErrTokenExpired, err)
+	}
+
+	parts := strings.Split(token.Token, ".")
+	claims, _ := json.Marshal(accessTokenClaims{Subject: uuid.New(), SessionID: sessionID, ExpiresAt: token.Expiry.Unix()})
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString(claims) + "." + parts[2]
+	if _, err := signer.Validate(forged, now); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("Expected error %v for changed claims, but got %v", ErrTokenInvalid, err)
+	}
+
+	header, _ := json.Marshal(accessTokenHeader{Algorithm: "none", KeyID: "a"})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + parts[1] + "."
+	if _, err := signer.Validate(unsigned, now); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("Expected error %v for an unsigned token, but got %v", ErrTokenInvalid, err)
+	}
+
+	if _, err := signer.Validate("mm_not_signed", now); !errors.Is(err, ErrTokenFormat) {
+		t.Errorf("Expected error %v, but got %v", ErrTokenFormat, err)
+	}
+}
+
+func TestAccessTokenSigner_KeyRotation(t *testing.T) {
+	signer, keysetFile, _ := newTestAccessTokenSigner(t)
+	now := time.Now()
+	old, _ := signer.Sign(uuid.New(), uuid.New(), now)
+
+	writeAccessTokenFile(t, keysetFile, AccessTokenKeyset{
+		Active: "b",
+		Keys:   map[string]string{"a": accessTokenKey('a'), "b": accessTokenKey('b')},
+	}, now)
+	if reloaded, err := signer.Reload(); err != nil || !reloaded {
+		t.Fatalf("Expected keyset to be reloaded, but got %v, %v", reloaded, err)
+	}
+
+	rotated, _ := signer.Sign(uuid.New(), uuid.New(), now)
+	if !strings.Contains(string(mustDecodeHeader(t, rotated.Token)), `"kid":"b"`) {
+		t.Errorf("Expected tokens to be signed with the active key")
+	}
+	for _, token := range []string{old.Token, rotated.Token} {
+		if _, err := signer.Validate(token, now); err != nil {
+			t.Errorf("Expected token to be valid during rotation, but got %v", err)
+		}
+	}
+
+	writeAccessTokenFile(t, keysetFile, AccessTokenKeyset{Active: "b", Keys: map[string]string{"b": accessTokenKey('b')}}, now.Add(time.Minute))
+	signer.Reload()
+	if _, err := signer.Validate(old.Token, now); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("Expected error %v for a removed key, but got %v", ErrTokenInvalid, err)
+	}
+
+	// A broken keyset keeps the previous keys in use
+	writeAccessTokenFile(t, keysetFile, AccessTokenKeyset{Active: "c", Keys: map[string]string{"c": "c2hvcnQ="}}, now.Add(2*time.Minute))
+	if _, err := signer.Reload(); err == nil {
+		t.Errorf("Expected an error for a short key")
+	}
+	if _, err := signer.Validate(rotated.Token, now); err != nil {
+		t.Errorf("Expected previous keys to stay in use, but got %v", err)
+	}
+}
+
+func TestAccessTokenSigner_Revocations(t *testing.T) {
+	signer, _, revocationFile := newTestAccessTokenSigner(t)
+	now := time.Now()
+	userID := uuid.New()
+
+	byID, _ := signer.Sign(uuid.New(), uuid.New(), now)
+	bySession, _ := signer.Sign(uuid.New(), uuid.New(), now)
+	byUser, _ := signer.Sign(userID, uuid.New(), now)
+	other, _ := signer.Sign(uuid.New(), uuid.New(), now)
+
+	var claims accessTokenClaims
+	decodeAccessTokenPart(strings.Split(byID.Token, ".")[1], &claims)
+	writeAccessTokenFile(t, revocationFile, AccessTokenRevocations{
+		Tokens:   []string{claims.ID},
+		Sessions: []uuid.UUID{*bySession.SessionID},
+		Users:    map[uuid.UUID]time.Time{userID: now},
+	}, now)
+	if _, err := signer.Reload(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	for _, token := range []string{byID.Token, bySession.Token, byUser.Token} {
+		if _, err := signer.Validate(token, now); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("Expected error %v, but got %v", ErrTokenRevoked, err)
+		}
+	}
+	if _, err := signer.Validate(other.Token, now); err != nil {
+		t.Errorf("Expected other tokens to stay valid, but got %v", err)
+	}
+
+	// Tokens issued after the user's revocation are accepted again
+	later, _ := signer.Sign(userID, uuid.New(), now.Add(time.Second))
+	if _, err := signer.Validate(later.Token, now.Add(time.Second)); err != nil {
+		t.Errorf("Expected a new token of the user to be valid, but got %v", err)
+	}
+}
+
+func TestRefreshSession_SignedAccessToken(t *testing.T) {
+	signer, _, _ := newTestAccessTokenSigner(t)
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+	refresh := GenerateRefreshToken(&userID)
+	session := database.Session{UserID: userID}
+	store.InsertSessionDB(&session, &refresh)
+
+	tokens, errResp := RefreshSession(store, signer, &RefreshRequest{RefreshToken: refresh.Token})
+	if errResp.Message != "" {
+		t.Fatalf("Expected no error, but got %s", errResp.Message)
+	}
+	if !IsSignedAccessToken(tokens.Token.Token) {
+		t.Fatalf("Expected a signed access token, but got %s", tokens.Token.Token)
+	}
+	for _, token := range store.tokens {
+		if token.Purpose == TokenPurposeSession {
+			t.Errorf("Expected signed access tokens not to be stored")
+		}
+	}
+
+	validated, err := ValidateAccessToken(store, signer, tokens.Token.Token)
+	if err != nil {
+		t.Fatalf("Expected token to be valid, but got %v", err)
+	}
+	if *validated.SessionID != session.ID {
+		t.Errorf("Expected session %s, but got %s", session.ID, validated.SessionID)
+	}
+
+	// Opaque tokens issued before signing was enabled stay valid
+	opaque := GenerateSessionToken(&userID)
+	store.InsertSessionTokensDB(&session.ID, &opaque)
+	if _, err := ValidateAccessToken(store, signer, opaque.Token); err != nil {
+		t.Errorf("Expected opaque token to be valid, but got %v", err)
+	}
+	if _, err := ValidateAccessToken(store, nil, tokens.Token.Token); err == nil {
+		t.Errorf("Expected signed token to be rejected without a signer")
+	}
+}
+
+func TestAccessTokenSigner_RevokeSessions(t *testing.T) {
+	signer, _, _ := newTestAccessTokenSigner(t)
+	now := time.Now()
+	revoked, _ := signer.Sign(uuid.New(), uuid.New(), now)
+	other, _ := signer.Sign(uuid.New(), uuid.New(), now)
+
+	signer.RevokeSessions([]uuid.UUID{*revoked.SessionID}, now)
+	if _, err := signer.Validate(revoked.Token, now); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected error %v, but got %v", ErrTokenRevoked, err)
+	}
+	if _, err := signer.Validate(other.Token, now); err != nil {
+		t.Errorf("Expected other sessions to stay valid, but got %v", err)
+	}
+
+	// Reloading the revocation list keeps sessions revoked on this instance
+	signer.modifiedAts = map[string]time.Time{}
+	signer.Reload()
+	if _, err := signer.Validate(revoked.Token, now); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected session to stay revoked after a reload, but got %v", err)
+	}
+
+	// Revocations are forgotten once every token of the session has expired
+	later := now.Add(tokenLifetimes[TokenPurposeSession])
+	signer.RevokeSessions([]uuid.UUID{*other.SessionID}, later)
+	if _, ok := signer.revokedSessions[*revoked.SessionID]; ok {
+		t.Errorf("Expected expired revocations to be forgotten")
+	}
+
+	var nilSigner *AccessTokenSigner
+	nilSigner.RevokeSessions([]uuid.UUID{uuid.New()}, now)
+}
+
+func TestRefreshSession_ReuseRevokesSignedAccessToken(t *testing.T) {
+	signer, _, _ := newTestAccessTokenSigner(t)
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+	refresh := GenerateRefreshToken(&userID)
+	session := database.Session{UserID: userID}
+	store.InsertSessionDB(&session, &refresh)
+
+	tokens, errResp := RefreshSession(store, signer, &RefreshRequest{RefreshToken: refresh.Token})
+	if errResp.Message != "" {
+		t.Fatalf("Expected no error, but got %s", errResp.Message)
+	}
+	if _, errResp := RefreshSession(store, signer, &RefreshRequest{RefreshToken: refresh.Token}); errResp.Code != 401 {
+		t.Fatalf("Expected status 401 on reuse, but got %d", errResp.Code)
+	}
+
+	if _, err := ValidateAccessToken(store, signer, tokens.Token.Token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected the signed access token to be revoked with its session, but got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package logic
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Name of the account created for users that enter a balance before creating
+// an account themselves.
+const defaultAccountName = "Default"
+
+type AccountForUpdate struct {
+	Name        string `json:"name"`
+	BankAccount string `json:"bank_account"`
+}
+
+// AccountBalance holds the latest balance and budget of an account in the
+// account's own currency.
+type AccountBalance struct {
+	Account *database.Account `json:"account"`
+	Balance money.Amount      `json:"balance"`
+	Budget  money.Amount      `json:"budget"`
+}
+
+// NetWorth sums up all accounts in a single reporting currency.
+type NetWorth struct {
+	Currency string           `json:"currency"`
+	Balance  money.Amount     `json:"balance"`
+	Budget   money.Amount     `json:"budget"`
+	Accounts []AccountBalance `json:"accounts"`
+}
+
+func CreateAccount(store database.AccountStore, userID *uuid.UUID, account *database.Account) (*database.Account, ErrorResponse) {
+	account.Name = strings.TrimSpace(account.Name)
+	if account.Name == "" {
+		return nil, ErrorResponse{
+			Message: "Account name is required",
+			Code:    http.StatusBadRequest,
+		}
+	}
+	currency, err := normalizeCurrency(account.Currency)
+	if err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+	account.Currency = currency
+	account.BankAccount = normalizeBankAccount(account.BankAccount)
+	account.UserID = *userID
+
+	accountID, err := store.InsertAccountDB(account)
+	if err != nil {
+		fmt.Println("Error inserting account:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to insert account",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return GetAccountByID(store, userID, &accountID)
+}
+
+func GetAccounts(store database.AccountStore, userID *uuid.UUID) ([]*database.Account, ErrorResponse) {
+	accounts, err := store.SelectUserAccountsDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving accounts:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve accounts",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return accounts, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func GetAccountByID(store database.AccountStore, actorID *uuid.UUID, accountID *uuid.UUID) (*database.Account, ErrorResponse) {
+	account, err := store.SelectAccountByIDDB(accountID)
+	if err != nil {
+		fmt.Println("Error retrieving account:", err)
+		return nil, ErrorResponse{
+			Message: "Account not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if account.UserID != *actorID {
+		return nil, ErrorResponse{
+			Message: "Forbidden: cannot access another user's account",
+			Code:    http.StatusForbidden,
+		}
+	}
+
+	return account, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func UpdateAccount(store database.AccountStore, actorID *uuid.UUID, accountID *uuid.UUID, accountForUpdate *AccountForUpdate) (*database.Account, ErrorResponse) {
+	account, errResp := GetAccountByID(store, actorID, accountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	account.Name = strings.TrimSpace(accountForUpdate.Name)
+	account.BankAccount = normalizeBankAccount(accountForUpdate.BankAccount)
+	if account.Name == "" {
+		return nil, ErrorResponse{
+			Message: "Account name is required",
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	if err := store.UpdateAccountDB(account); err != nil {
+		fmt.Println("Error updating account:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to update account",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return account, errResp
+}
+
+// DeleteAccount removes the account. Its balance entries and transactions are
+// removed with it by the database.
+func DeleteAccount(store database.AccountStore, actorID *uuid.UUID, accountID *uuid.UUID) ErrorResponse {
+	_, errResp := GetAccountByID(store, actorID, accountID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+
+	if err := store.DeleteAccountDB(accountID); err != nil {
+		fmt.Println("Error deleting account:", err)
+		return ErrorResponse{
+			Message: "Failed to delete account",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return errResp
+}
+
+// resolveAccount returns the account an entry should be booked on. Without an
+// explicit account the user's oldest account is used, and created if the user
+// has none yet.
+func resolveAccount(store database.AccountStore, userID *uuid.UUID, accountID *uuid.UUID) (*database.Account, ErrorResponse) {
+	if accountID != nil && *accountID != uuid.Nil {
+		return GetAccountByID(store, userID, accountID)
+	}
+
+	accounts, errResp := GetAccounts(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	if len(accounts) > 0 {
+		return accounts[0], errResp
+	}
+
+	return CreateAccount(store, userID, &database.Account{Name: defaultAccountName})
+}
+
+// matchAccount returns the account of the user with the given bank account
+// number, or nil if there is none.
+func matchAccount(store database.AccountStore, userID *uuid.UUID, bankAccount string) (*database.Account, ErrorResponse) {
+	accounts, errResp := GetAccounts(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	bankAccount = normalizeBankAccount(bankAccount)
+	for _, account := range accounts {
+		if bankAccount != "" && account.BankAccount == bankAccount {
+			return account, errResp
+		}
+	}
+	return nil, errResp
+}
+
+// normalizeBankAccount drops the spaces IBANs are usually written with, so
+// numbers from statements and user input compare equal.
+func normalizeBankAccount(bankAccount string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(bankAccount), ""))
+}
+
+func GetNetWorth(store database.ReportingStore, userID *uuid.UUID, currency string) (*NetWorth, ErrorResponse) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	converter, errResp := loadCurrencyConverter(store)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	accounts, errResp := GetAccounts(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	lastEntries := map[uuid.UUID]*database.MoneyEntry{}
+	for _, account := range accounts {
+		lastEntry, errResp := GetLastBalance(store, &account.ID)
+		if errResp.Code == http.StatusInternalServerError {
+			return nil, errResp
+		}
+		if lastEntry != nil {
+			lastEntries[account.ID] = lastEntry
+		}
+	}
+
+	netWorth, err := calculateNetWorth(accounts, lastEntries, converter, currency)
+	if err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusUnprocessableEntity,
+		}
+	}
+	return &netWorth, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// calculateNetWorth sums up the latest balance and budget of every account,
+// converted into the reporting currency. Accounts without entries count as
+// zero.
+func calculateNetWorth(accounts []*database.Account, lastEntries map[uuid.UUID]*database.MoneyEntry, converter *currencyConverter, currency string) (NetWorth, error) {
+	netWorth := NetWorth{Currency: currency, Accounts: []AccountBalance{}}
+	for _, account := range accounts {
+		accountBalance := AccountBalance{Account: account}
+		if lastEntry, ok := lastEntries[account.ID]; ok {
+			accountBalance.Balance = lastEntry.Balance
+			accountBalance.Budget = lastEntry.Budget
+		}
+
+		balance, err := converter.convert(accountBalance.Balance, account.Currency, currency)
+		if err != nil {
+			return netWorth, err
+		}
+		budget, err := converter.convert(accountBalance.Budget, account.Currency, currency)
+		if err != nil {
+			return netWorth, err
+		}
+
+		netWorth.Balance += balance
+		netWorth.Budget += budget
+		netWorth.Accounts = append(netWorth.Accounts, accountBalance)
+	}
+	return netWorth, nil
+}
@@ -0,0 +1,55 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+func TestCalculateNetWorth(t *testing.T) {
+	checking := &database.Account{ID: uuid.New(), Name: "Checking", Currency: "EUR"}
+	savings := &database.Account{ID: uuid.New(), Name: "Savings", Currency: "USD"}
+	cash := &database.Account{ID: uuid.New(), Name: "Cash", Currency: "EUR"}
+
+	lastEntries := map[uuid.UUID]*database.MoneyEntry{
+		checking.ID: {Balance: money.FromInt(1200), Budget: money.FromInt(300)},
+		savings.ID:  {Balance: money.FromInt(5000), Budget: money.FromInt(-50)},
+	}
+
+	converter := newCurrencyConverter([]*database.ExchangeRate{
+		{Base: "EUR", Quote: "USD", Date: time.Now(), Rate: money.MustParseRate("1.25")},
+	})
+
+	netWorth, err := calculateNetWorth([]*database.Account{checking, savings, cash}, lastEntries, converter, "EUR")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if netWorth.Balance != money.FromInt(5200) {
+		t.Errorf("Expected balance %s, but got %s", money.FromInt(5200), netWorth.Balance)
+	}
+	if netWorth.Budget != money.FromInt(260) {
+		t.Errorf("Expected budget %s, but got %s", money.FromInt(260), netWorth.Budget)
+	}
+	if len(netWorth.Accounts) != 3 {
+		t.Fatalf("Expected 3 accounts, but got %d", len(netWorth.Accounts))
+	}
+	if netWorth.Accounts[1].Balance != money.FromInt(5000) {
+		t.Errorf("Expected account balance in its own currency, but got %s", netWorth.Accounts[1].Balance)
+	}
+	if netWorth.Accounts[2].Balance != 0 || netWorth.Accounts[2].Budget != 0 {
+		t.Errorf("Expected account without entries to be empty, but got %+v", netWorth.Accounts[2])
+	}
+}
+
+func TestCalculateNetWorthMissingRate(t *testing.T) {
+	savings := &database.Account{ID: uuid.New(), Name: "Savings", Currency: "CHF"}
+
+	_, err := calculateNetWorth([]*database.Account{savings}, nil, newCurrencyConverter(nil), "EUR")
+	if err == nil {
+		t.Errorf("Expected error for missing exchange rate")
+	}
+}
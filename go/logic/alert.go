@@ -0,0 +1,407 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Kinds of alert rules. AlertBudgetNegative is AlertBudgetBelow with a
+// threshold of zero.
+const (
+	AlertBudgetNegative = "budget_negative"
+	AlertBudgetBelow    = "budget_below"
+	AlertBalanceBelow   = "balance_below"
+)
+
+// Least time between two notifications of the same rule
+const alertCooldown = 24 * time.Hour
+
+// Event type of the deliveries queued for an alert rule's webhook
+const AlertFired = "alert.fired"
+
+type AlertRuleForUpdate struct {
+	Kind        string       `json:"kind"`
+	Threshold   money.Amount `json:"threshold"`
+	NotifyEmail bool         `json:"notify_email"`
+	WebhookURL  string       `json:"webhook_url"`
+	AccountID   *uuid.UUID   `json:"account_id"`
+}
+
+// AlertPayload is the JSON body posted to an alert rule's webhook. It is
+// signed and retried like the payload of a webhook.
+type AlertPayload struct {
+	RuleID    uuid.UUID    `json:"rule_id"`
+	Kind      string       `json:"kind"`
+	Threshold money.Amount `json:"threshold"`
+	AccountID uuid.UUID    `json:"account_id"`
+	Currency  string       `json:"currency"`
+	Balance   money.Amount `json:"balance"`
+	Budget    money.Amount `json:"budget"`
+	FiredAt   time.Time    `json:"fired_at"`
+}
+
+// CreateAlertRule creates the rule with a new webhook secret. The secret is
+// only returned here and when an update sets it.
+func CreateAlertRule(store database.AlertLedgerStore, userID *uuid.UUID, rule *database.AlertRule) (*database.AlertRule, ErrorResponse) {
+	rule.UserID = *userID
+	if errResp := prepareAlertRule(store, userID, rule); errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		fmt.Println("Error generating alert webhook secret:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to create alert rule",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	rule.WebhookSecret = secret
+
+	ruleID, err := store.InsertAlertRuleDB(rule)
+	if err != nil {
+		fmt.Println("Error inserting alert rule:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to insert alert rule",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	created, errResp := GetAlertRuleByID(store, userID, &ruleID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	created.WebhookSecret = secret
+	return created, errResp
+}
+
+func GetAlertRules(store database.AlertStore, userID *uuid.UUID) ([]*database.AlertRule, ErrorResponse) {
+	rules, err := store.SelectUserAlertRulesDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving alert rules:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve alert rules",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	for _, rule := range rules {
+		rule.WebhookSecret = ""
+	}
+	return rules, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func GetAlertRuleByID(store database.AlertStore, actorID *uuid.UUID, ruleID *uuid.UUID) (*database.AlertRule, ErrorResponse) {
+	rule, errResp := getAlertRule(store, actorID, ruleID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	rule.WebhookSecret = ""
+	return rule, errResp
+}
+
+// getAlertRule returns the user's rule including its webhook secret.
+func getAlertRule(store database.AlertStore, actorID *uuid.UUID, ruleID *uuid.UUID) (*database.AlertRule, ErrorResponse) {
+	rule, err := store.SelectAlertRuleByIDDB(ruleID)
+	if err != nil {
+		fmt.Println("Error retrieving alert rule:", err)
+		return nil, ErrorResponse{
+			Message: "Alert rule not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if rule.UserID != *actorID {
+		return nil, ErrorResponse{
+			Message: "Forbidden: cannot access another user's alert rule",
+			Code:    http.StatusForbidden,
+		}
+	}
+
+	return rule, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// UpdateAlertRule replaces the settings of the rule. Rules created before
+// alert webhooks were signed get a secret here, which is returned once.
+func UpdateAlertRule(store database.AlertLedgerStore, actorID *uuid.UUID, ruleID *uuid.UUID, ruleForUpdate *AlertRuleForUpdate) (*database.AlertRule, ErrorResponse) {
+	rule, errResp := getAlertRule(store, actorID, ruleID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	rule.Kind = ruleForUpdate.Kind
+	rule.Threshold = ruleForUpdate.Threshold
+	rule.NotifyEmail = ruleForUpdate.NotifyEmail
+	rule.WebhookURL = ruleForUpdate.WebhookURL
+	rule.AccountID = ruleForUpdate.AccountID
+	if errResp := prepareAlertRule(store, actorID, rule); errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	secret := rule.WebhookSecret
+	if secret == "" {
+		var err error
+		if rule.WebhookSecret, err = generateWebhookSecret(); err != nil {
+			fmt.Println("Error generating alert webhook secret:", err)
+			return nil, ErrorResponse{
+				Message: "Failed to update alert rule",
+				Code:    http.StatusInternalServerError,
+			}
+		}
+	}
+
+	if err := store.UpdateAlertRuleDB(rule); err != nil {
+		fmt.Println("Error updating alert rule:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to update alert rule",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	if secret != "" {
+		rule.WebhookSecret = ""
+	}
+	return rule, errResp
+}
+
+func DeleteAlertRule(store database.AlertStore, actorID *uuid.UUID, ruleID *uuid.UUID) ErrorResponse {
+	_, errResp := GetAlertRuleByID(store, actorID, ruleID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+
+	if err := store.DeleteAlertRuleDB(ruleID); err != nil {
+		fmt.Println("Error deleting alert rule:", err)
+		return ErrorResponse{
+			Message: "Failed to delete alert rule",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return errResp
+}
+
+// GetAlertRuleDeliveries returns the latest deliveries of the rule's webhook,
+// newest first.
+func GetAlertRuleDeliveries(store database.WebhookDeliveryStore, actorID *uuid.UUID, ruleID *uuid.UUID) ([]*database.WebhookDelivery, ErrorResponse) {
+	_, errResp := GetAlertRuleByID(store, actorID, ruleID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	deliveries, err := store.SelectAlertRuleDeliveriesDB(ruleID, webhookLogLimit)
+	if err != nil {
+		fmt.Println("Error retrieving alert rule deliveries:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve alert rule deliveries",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return deliveries, errResp
+}
+
+// prepareAlertRule normalizes and validates the rule and checks that its
+// account belongs to the user.
+func prepareAlertRule(store database.AccountStore, userID *uuid.UUID, rule *database.AlertRule) ErrorResponse {
+	rule.Kind = strings.ToLower(strings.TrimSpace(rule.Kind))
+	rule.WebhookURL = strings.TrimSpace(rule.WebhookURL)
+	if rule.Kind == AlertBudgetNegative {
+		rule.Threshold = 0
+	}
+	if rule.AccountID != nil && *rule.AccountID == uuid.Nil {
+		rule.AccountID = nil
+	}
+
+	if err := validateAlertRule(rule); err != nil {
+		return ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	if rule.AccountID != nil {
+		_, errResp := GetAccountByID(store, userID, rule.AccountID)
+		if errResp.Code != http.StatusOK {
+			return errResp
+		}
+	}
+
+	return ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func validateAlertRule(rule *database.AlertRule) error {
+	switch rule.Kind {
+	case AlertBudgetNegative, AlertBudgetBelow, AlertBalanceBelow:
+	default:
+		return fmt.Errorf("invalid kind %q", rule.Kind)
+	}
+	if !rule.NotifyEmail && rule.WebhookURL == "" {
+		return errors.New("email or webhook_url is required")
+	}
+	if rule.WebhookURL != "" {
+		if err := validateWebhookURL(rule.WebhookURL); err != nil {
+			return fmt.Errorf("invalid webhook_url: %w", err)
+		}
+	}
+	return nil
+}
+
+// alertTriggered reports whether the change from previous to latest makes the
+// rule's value fall below its threshold. A value that already was below does
+// not trigger again.
+func alertTriggered(rule *database.AlertRule, previous *database.MoneyEntry, latest *database.MoneyEntry) bool {
+	if latest == nil {
+		return false
+	}
+	value := func(entry *database.MoneyEntry) money.Amount {
+		if rule.Kind == AlertBalanceBelow {
+			return entry.Balance
+		}
+		return entry.Budget
+	}
+
+	if value(latest) >= rule.Threshold {
+		return false
+	}
+	return previous == nil || value(previous) >= rule.Threshold
+}
+
+// NewAlertNotifier returns a listener that evaluates the user's alert rules
+// on every balance event and notifies by email and webhook. Rules are
+// evaluated in the background and each rule notifies at most once per
+// cooldown. Webhooks go through the delivery queue and are retried by
+// DeliverDueWebhooks.
+func NewAlertNotifier(store database.AlertNotifyStore, sender EmailSender) BalanceListener {
+	return func(event *BalanceEvent) {
+		go evaluateAlerts(store, sender, webhookClient, event)
+	}
+}
+
+// evaluateAlerts notifies for every rule the event triggers. The cooldown is
+// claimed before notifying so concurrent events notify once, and released
+// again when neither the email nor the first webhook attempt went out.
+func evaluateAlerts(store database.AlertNotifyStore, sender EmailSender, client *http.Client, event *BalanceEvent) {
+	rules, err := store.SelectUserAlertRulesDB(&event.UserID)
+	if err != nil {
+		fmt.Println("Error retrieving alert rules:", err)
+		return
+	}
+
+	for _, rule := range rules {
+		if rule.AccountID != nil && *rule.AccountID != event.AccountID {
+			continue
+		}
+		if !alertTriggered(rule, event.Previous, event.Latest) {
+			continue
+		}
+
+		claimed, err := store.ClaimAlertRuleDB(&rule.ID, event.At, event.At.Add(-alertCooldown))
+		if err != nil {
+			fmt.Println("Error claiming alert rule:", err)
+			continue
+		}
+		if !claimed {
+			fmt.Println("Alert rule", rule.ID, "fired within cooldown, skipping")
+			continue
+		}
+
+		payload := AlertPayload{
+			RuleID:    rule.ID,
+			Kind:      rule.Kind,
+			Threshold: rule.Threshold,
+			AccountID: event.AccountID,
+			Currency:  event.Latest.Currency,
+			Balance:   event.Latest.Balance,
+			Budget:    event.Latest.Budget,
+			FiredAt:   event.At,
+		}
+		sent := false
+		if rule.NotifyEmail {
+			sent = sendAlertEmail(store, sender, &event.UserID, &payload)
+		}
+		if rule.WebhookURL != "" {
+			if delivery := enqueueAlertDelivery(store, rule, &payload); delivery != nil {
+				sent = attemptWebhookDelivery(store, client, delivery, time.Now()) || sent
+			}
+		}
+		if sent {
+			continue
+		}
+
+		if err := store.ReleaseAlertRuleDB(&rule.ID, event.At, rule.LastFiredAt); err != nil {
+			fmt.Println("Error releasing alert rule:", err)
+		}
+	}
+}
+
+// sendAlertEmail emails the alert to the user. Returns whether it was sent.
+func sendAlertEmail(store database.UserStore, sender EmailSender, userID *uuid.UUID, payload *AlertPayload) bool {
+	user, err := store.SelectUserByIDDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving user for alert:", err)
+		return false
+	}
+
+	subject, body := alertMessage(payload)
+	if err := sender.SendEmail(user.Email, subject, body, ""); err != nil {
+		fmt.Println("Failed to send alert email:", err.Error())
+		return false
+	}
+	return true
+}
+
+// enqueueAlertDelivery queues the alert for the rule's webhook. Returns nil if
+// it could not be queued.
+func enqueueAlertDelivery(store database.WebhookStore, rule *database.AlertRule, payload *AlertPayload) *database.WebhookDelivery {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Println("Error encoding alert:", err)
+		return nil
+	}
+
+	delivery := &database.WebhookDelivery{
+		EventType:     AlertFired,
+		Payload:       string(body),
+		Status:        WebhookDeliveryPending,
+		NextAttemptAt: payload.FiredAt,
+		AlertRuleID:   &rule.ID,
+	}
+	if delivery.ID, err = store.InsertWebhookDeliveryDB(delivery); err != nil {
+		fmt.Println("Error queueing alert delivery:", err)
+		return nil
+	}
+	return delivery
+}
+
+func alertMessage(payload *AlertPayload) (string, string) {
+	switch payload.Kind {
+	case AlertBalanceBelow:
+		return "Balance below " + payload.Threshold.String(),
+			fmt.Sprintf("The balance of your account dropped to %s %s, below your threshold of %s.", payload.Balance, payload.Currency, payload.Threshold)
+	case AlertBudgetBelow:
+		return "Budget below " + payload.Threshold.String(),
+			fmt.Sprintf("The budget of your account dropped to %s %s, below your threshold of %s.", payload.Budget, payload.Currency, payload.Threshold)
+	default:
+		return "Budget is negative",
+			fmt.Sprintf("The budget of your account dropped to %s %s. You are spending more than your budget.", payload.Budget, payload.Currency)
+	}
+}
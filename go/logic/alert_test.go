@@ -0,0 +1,65 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+)
+
+func TestAlertTriggered(t *testing.T) {
+	entry := func(balance int64, budget int64) *database.MoneyEntry {
+		return &database.MoneyEntry{Balance: money.FromInt(balance), Budget: money.FromInt(budget)}
+	}
+
+	tests := []struct {
+		name     string
+		rule     database.AlertRule
+		previous *database.MoneyEntry
+		latest   *database.MoneyEntry
+		expected bool
+	}{
+		{"budget turns negative", database.AlertRule{Kind: AlertBudgetNegative}, entry(1000, 50), entry(900, -50), true},
+		{"budget stays negative", database.AlertRule{Kind: AlertBudgetNegative}, entry(1000, -10), entry(900, -50), false},
+		{"budget stays positive", database.AlertRule{Kind: AlertBudgetNegative}, entry(1000, 50), entry(990, 40), false},
+		{"budget recovers", database.AlertRule{Kind: AlertBudgetNegative}, entry(900, -50), entry(1100, 50), false},
+		{"budget at zero", database.AlertRule{Kind: AlertBudgetNegative}, entry(1000, 50), entry(950, 0), false},
+		{"budget below threshold", database.AlertRule{Kind: AlertBudgetBelow, Threshold: money.FromInt(100)}, entry(1000, 150), entry(900, 50), true},
+		{"balance below threshold", database.AlertRule{Kind: AlertBalanceBelow, Threshold: money.FromInt(500)}, entry(600, 300), entry(400, 300), true},
+		{"budget ignored by balance rule", database.AlertRule{Kind: AlertBalanceBelow, Threshold: money.FromInt(500)}, entry(600, 300), entry(600, -100), false},
+		{"first entry below threshold", database.AlertRule{Kind: AlertBalanceBelow, Threshold: money.FromInt(500)}, nil, entry(400, 0), true},
+		{"chain emptied", database.AlertRule{Kind: AlertBalanceBelow, Threshold: money.FromInt(500)}, entry(600, 0), nil, false},
+	}
+
+	for _, test := range tests {
+		if got := alertTriggered(&test.rule, test.previous, test.latest); got != test.expected {
+			t.Errorf("Expected %s to be %t, but got %t", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestValidateAlertRule(t *testing.T) {
+	valid := []database.AlertRule{
+		{Kind: AlertBudgetNegative, NotifyEmail: true},
+		{Kind: AlertBalanceBelow, WebhookURL: "https://example.com/hook"},
+	}
+	for _, rule := range valid {
+		if err := validateAlertRule(&rule); err != nil {
+			t.Errorf("Expected rule %+v to be valid, but got %v", rule, err)
+		}
+	}
+
+	invalid := []database.AlertRule{
+		{Kind: "budget_above", NotifyEmail: true},
+		{Kind: AlertBudgetBelow},
+		{Kind: AlertBudgetBelow, WebhookURL: "ftp://example.com/hook"},
+		{Kind: AlertBudgetBelow, WebhookURL: "example.com/hook"},
+		{Kind: AlertBudgetBelow, WebhookURL: "http://127.0.0.1:8080/hook"},
+		{Kind: AlertBudgetBelow, WebhookURL: "http://169.254.169.254/latest/meta-data"},
+	}
+	for _, rule := range invalid {
+		if err := validateAlertRule(&rule); err == nil {
+			t.Errorf("Expected rule %+v to be invalid, but got no error", rule)
+		}
+	}
+}
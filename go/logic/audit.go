@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditLogin            = "login"
+	AuditPasswordReset    = "password_reset"
+	AuditEmailVerified    = "email_verified"
+	AuditTakeout          = "takeout"
+	AuditErasureScheduled = "erasure_scheduled"
+	AuditErasureCancelled = "erasure_cancelled"
+	AuditSessionRevoked   = "session_revoked"
+	AuditSessionsRevoked  = "sessions_revoked"
+	AuditRefreshReused    = "refresh_token_reused"
+)
+
+// recordAudit adds an event to the user's audit log. A failure is only
+// logged, it never fails the action that is audited.
+func recordAudit(store database.AuditStore, userID *uuid.UUID, action string, detail string) {
+	event := &database.AuditEvent{
+		Action: action,
+		Detail: detail,
+		UserID: *userID,
+	}
+	if err := store.InsertAuditEventDB(event); err != nil {
+		fmt.Println("Error recording audit event:", err)
+	}
+}
+
+func GetAuditLog(store database.AuditStore, userID *uuid.UUID) ([]*database.AuditEvent, ErrorResponse) {
+	events, err := store.SelectUserAuditEventsDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving audit log:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve audit log",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return events, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
@@ -11,9 +11,14 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// LoginRequest logs a device in. The IP address and user agent are taken
+// from the request, not the body.
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
+	IPAddress  string `json:"-"`
+	UserAgent  string `json:"-"`
 }
 
 type ErrorResponse struct {
@@ -26,45 +31,157 @@ type ResetPasswordRequest struct {
 }
 
 type ResetPasswordExecutionRequest struct {
-	Token       uuid.UUID `json:"token"`
-	NewPassword string    `json:"password"`
+	Token       string `json:"token"`
+	NewPassword string `json:"password"`
 }
 
-func GenerateToken(userID *uuid.UUID) database.Token {
+// Purposes a token can be issued for. A token is only accepted by the flow
+// it was issued for.
+// Session tokens are short-lived access tokens that are renewed with the
+// session's refresh token.
+const (
+	TokenPurposeSession           = "session"
+	TokenPurposeRefresh           = "refresh"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+)
+
+// Time a token is valid after it was issued, per purpose
+var tokenLifetimes = map[string]time.Duration{
+	TokenPurposeSession:           15 * time.Minute,
+	TokenPurposeRefresh:           30 * 24 * time.Hour,
+	TokenPurposeEmailVerification: 72 * time.Hour,
+	TokenPurposePasswordReset:     time.Hour,
+}
+
+// AuthTokens is the access token of a session with the refresh token to
+// renew it. The access token's fields are at the top level, as before
+// refresh tokens existed.
+type AuthTokens struct {
+	database.Token
+	RefreshToken  string    `json:"refresh_token"`
+	RefreshExpiry time.Time `json:"refresh_expiry"`
+}
+
+// Least time between two updates of a session's last use
+const sessionTouchInterval = time.Minute
+
+// Longest device name and IP address stored for a session
+const (
+	maxDeviceNameLength = 100
+	maxIPAddressLength  = 45
+)
+
+var (
+	ErrTokenFormat  = errors.New("WrongFormat")
+	ErrTokenInvalid = errors.New("InvalidToken")
+	ErrTokenExpired = errors.New("TokenExpired")
+	ErrTokenRevoked = errors.New("TokenRevoked")
+)
+
+// GenerateToken issues a token for the purpose, valid for the purpose's
+// lifetime.
+func GenerateToken(userID *uuid.UUID, purpose string) database.Token {
 	if userID == nil {
 		return database.Token{}
 	}
-	expirationTime := time.Now().Add(24 * time.Hour)
+	expirationTime := time.Now().Add(tokenLifetimes[purpose])
+	token, prefix := database.NewTokenSecret()
 	return database.Token{
-		UserID: *userID,
-		Token:  uuid.New(),
-		Expiry: expirationTime,
+		UserID:  *userID,
+		Token:   token,
+		Prefix:  prefix,
+		Expiry:  expirationTime,
+		Purpose: purpose,
 	}
 }
 
-func ValidateToken(store database.TokenStore, tokenStr string) (uuid.UUID, error) {
-	tokenID, err := uuid.Parse(tokenStr)
+func GenerateSessionToken(userID *uuid.UUID) database.Token {
+	return GenerateToken(userID, TokenPurposeSession)
+}
+
+func GenerateRefreshToken(userID *uuid.UUID) database.Token {
+	return GenerateToken(userID, TokenPurposeRefresh)
+}
+
+func GenerateEmailVerificationToken(userID *uuid.UUID) database.Token {
+	return GenerateToken(userID, TokenPurposeEmailVerification)
+}
+
+func GeneratePasswordResetToken(userID *uuid.UUID) database.Token {
+	return GenerateToken(userID, TokenPurposePasswordReset)
+}
+
+// ValidateToken returns the user of a token issued for the purpose. A token
+// issued for another purpose is as invalid as an unknown one.
+func ValidateToken(store database.TokenStore, tokenStr string, purpose string) (uuid.UUID, error) {
+	token, err := validateToken(store, tokenStr, purpose)
 	if err != nil {
-		return uuid.Nil, errors.New("WrongFormat")
+		return uuid.Nil, err
 	}
+	return token.UserID, nil
+}
 
-	token, err := store.GetToken(&tokenID)
+// DeleteExpiredTokens deletes expired tokens, which also ends the sessions
+// left without a valid token. Run by the scheduler rather than on every
+// request.
+func DeleteExpiredTokens(store database.TokenStore) {
+	if err := store.DeleteExpiredTokens(); err != nil {
+		fmt.Println("Error deleting expired tokens:", err)
+	}
+}
+
+func validateToken(store database.TokenStore, tokenStr string, purpose string) (*database.Token, error) {
+	if _, err := database.TokenPrefix(tokenStr); err != nil {
+		return nil, ErrTokenFormat
+	}
+
+	token, err := store.GetToken(tokenStr)
 	if err != nil {
 		fmt.Println("Token error:", err.Error())
-		return uuid.Nil, errors.New("InvalidToken")
+		return nil, ErrTokenInvalid
+	}
+
+	if token.Purpose != purpose {
+		fmt.Println("Token issued for", token.Purpose, "used for", purpose)
+		return nil, ErrTokenInvalid
 	}
 
 	expirationTime := token.Expiry
 	if time.Now().After(expirationTime) {
-		err := store.DeleteToken(&token.Token)
-		if err != nil {
-			fmt.Println("Failed to delete token:", err)
+		return nil, ErrTokenExpired
+	}
+
+	return token, nil
+}
+
+// ValidateSession validates a session token and records the use of its
+// session. The returned token holds the user and the session.
+func ValidateSession(store database.SessionTokenStore, tokenStr string) (*database.Token, error) {
+	token, err := validateToken(store, tokenStr, TokenPurposeSession)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.SessionID != nil {
+		now := time.Now()
+		if err := store.TouchSessionDB(token.SessionID, now, now.Add(-sessionTouchInterval)); err != nil {
+			fmt.Println("Failed to update session:", err)
 		}
-		return uuid.Nil, errors.New("TokenExpired")
 	}
+	return token, nil
+}
 
-	store.DeleteExpiredTokens()
-	return token.UserID, nil
+func ValidateSessionToken(store database.TokenStore, tokenStr string) (uuid.UUID, error) {
+	return ValidateToken(store, tokenStr, TokenPurposeSession)
+}
+
+func ValidateEmailVerificationToken(store database.TokenStore, tokenStr string) (uuid.UUID, error) {
+	return ValidateToken(store, tokenStr, TokenPurposeEmailVerification)
+}
+
+func ValidatePasswordResetToken(store database.TokenStore, tokenStr string) (uuid.UUID, error) {
+	return ValidateToken(store, tokenStr, TokenPurposePasswordReset)
 }
 
 func PrintAvailableTokens(store database.TokenStore) {
@@ -74,7 +191,7 @@ func PrintAvailableTokens(store database.TokenStore) {
 	} else {
 		fmt.Println("Tokens are:")
 		for _, t := range tokens {
-			fmt.Println(t.Token.String(), " for user ", t.UserID)
+			fmt.Println(t.Prefix, " for user ", t.UserID, " purpose ", t.Purpose)
 		}
 	}
 }
@@ -99,8 +216,10 @@ func ValidateUser(store database.UserStore, email string, password string) (uuid
 	return user.ID, nil
 }
 
-func Login(store database.AuthStore, loginReq *LoginRequest) (database.Token, ErrorResponse) {
-	var token database.Token
+// Login starts a new session. The access token is signed when a signer is
+// given and stored as an opaque token otherwise.
+func Login(store database.AuthStore, signer *AccessTokenSigner, loginReq *LoginRequest) (AuthTokens, ErrorResponse) {
+	var tokens AuthTokens
 	var errorResp ErrorResponse = ErrorResponse{
 		Message: "",
 		Code:    http.StatusOK,
@@ -112,30 +231,46 @@ func Login(store database.AuthStore, loginReq *LoginRequest) (database.Token, Er
 			Message: "Login failed: " + err.Error(),
 			Code:    http.StatusUnauthorized,
 		}
-		return token, errorResp
+		return tokens, errorResp
 	}
 
-	token = GenerateToken(&id)
-	err = store.DeleteTokensByUserID(&id)
+	// Every login is a new session, so other devices stay logged in
+	var token database.Token
+	refreshToken := GenerateRefreshToken(&id)
+	sessionTokens := []*database.Token{&refreshToken}
+	if signer == nil {
+		token = GenerateSessionToken(&id)
+		sessionTokens = append(sessionTokens, &token)
+	}
+	session := database.Session{
+		DeviceName: deviceName(loginReq),
+		IPAddress:  truncate(loginReq.IPAddress, maxIPAddressLength),
+		UserAgent:  loginReq.UserAgent,
+		UserID:     id,
+	}
+	err = store.InsertSessionDB(&session, sessionTokens...)
 	if err != nil {
-		fmt.Println("Failed to delete existing tokens for user:", err.Error())
+		fmt.Println("Failed to insert session:", err.Error())
 		errorResp = ErrorResponse{
 			Message: "Internal server error",
 			Code:    http.StatusInternalServerError,
 		}
-		return token, errorResp
+		return tokens, errorResp
 	}
-	err = store.InsertToken(&token)
-	if err != nil {
-		fmt.Println("Failed to insert token:", err.Error())
-		errorResp = ErrorResponse{
-			Message: "Internal server error",
-			Code:    http.StatusInternalServerError,
+	if signer != nil {
+		token, err = signer.Sign(id, session.ID, time.Now())
+		if err != nil {
+			fmt.Println("Failed to sign access token:", err.Error())
+			errorResp = ErrorResponse{
+				Message: "Internal server error",
+				Code:    http.StatusInternalServerError,
+			}
+			return tokens, errorResp
 		}
-		return token, errorResp
 	}
+	recordAudit(store, &id, AuditLogin, session.DeviceName)
 
-	return token, errorResp
+	return newAuthTokens(token, refreshToken), errorResp
 }
 
 func Register(store database.AuthStore, mailConfig EmailSender, hostAddress string, registerReq *UserForCreate) ErrorResponse {
@@ -197,7 +332,7 @@ func SendPasswordResetEmail(store database.AuthStore, mailConfig EmailSender, fr
 		}
 	}
 
-	resetToken := GenerateToken(&user.ID)
+	resetToken := GeneratePasswordResetToken(&user.ID)
 	err = store.InsertToken(&resetToken)
 	if err != nil {
 		fmt.Println("Failed to insert password reset token:", err.Error())
@@ -208,7 +343,7 @@ func SendPasswordResetEmail(store database.AuthStore, mailConfig EmailSender, fr
 	}
 
 	err = mailConfig.SendEmail(user.Email, "Password Reset",
-		fmt.Sprintf("Please reset your password using this link: %s", frontendAddress+"/reset-password/"+resetToken.Token.String()),
+		fmt.Sprintf("Please reset your password using this link: %s", frontendAddress+"/reset-password/"+resetToken.Token),
 		"")
 	if err != nil {
 		fmt.Println("Failed to send password reset email:", err.Error())
@@ -220,9 +355,9 @@ func SendPasswordResetEmail(store database.AuthStore, mailConfig EmailSender, fr
 	return ErrorResponse{Message: "If the email is registered, a password reset link has been sent.", Code: http.StatusOK}
 }
 
-func ResetPassword(store database.AuthStore, token *uuid.UUID, newPassword string) ErrorResponse {
+func ResetPassword(store database.AuthStore, signer *AccessTokenSigner, token string, newPassword string) ErrorResponse {
 	hashedPassword := hashPassword(newPassword)
-	userID, err := ValidateToken(store, token.String())
+	userID, err := ValidatePasswordResetToken(store, token)
 	if err != nil {
 		fmt.Println("Password reset token validation failed:", err.Error())
 		return ErrorResponse{
@@ -255,16 +390,23 @@ func ResetPassword(store database.AuthStore, token *uuid.UUID, newPassword strin
 			Code:    http.StatusInternalServerError,
 		}
 	}
-	err = store.DeleteToken(token)
+	err = store.DeleteTokensByUserIDAndPurpose(&userID, TokenPurposePasswordReset)
+	if err != nil {
+		fmt.Println("Failed to delete used password reset tokens:", err.Error())
+	}
+	// Whoever knew the old password is logged out
+	sessionIDs, err := store.DeleteUserSessionsDB(&userID, nil)
 	if err != nil {
-		fmt.Println("Failed to delete used password reset token:", err.Error())
+		fmt.Println("Failed to delete sessions after password reset:", err.Error())
 	}
+	signer.RevokeSessions(sessionIDs, time.Now())
+	recordAudit(store, &userID, AuditPasswordReset, "")
 	return ErrorResponse{Message: "", Code: http.StatusOK}
 }
 
 func SendEmailVerification(store database.TokenStore, mailConfig EmailSender, hostAddress string, user *database.User) ErrorResponse {
 	store.DeleteExpiredTokens()
-	verificationToken := GenerateToken(&user.ID)
+	verificationToken := GenerateEmailVerificationToken(&user.ID)
 	err := store.InsertToken(&verificationToken)
 	if err != nil {
 		fmt.Println("Failed to insert email verification token:", err.Error())
@@ -276,7 +418,7 @@ func SendEmailVerification(store database.TokenStore, mailConfig EmailSender, ho
 
 	// Placeholder for email sending logic
 	err = mailConfig.SendEmail(user.Email, "Email Verification",
-		fmt.Sprintf("Please verify your email using this link: %s", hostAddress+"/verify-email/"+verificationToken.Token.String()),
+		fmt.Sprintf("Please verify your email using this link: %s", hostAddress+"/verify-email/"+verificationToken.Token),
 		"")
 	if err != nil {
 		fmt.Println("Failed to send verification email:", err.Error())
@@ -289,32 +431,27 @@ func SendEmailVerification(store database.TokenStore, mailConfig EmailSender, ho
 }
 
 func VerifyEmail(store database.AuthStore, tokenStr string) ErrorResponse {
-	tokenID, err := uuid.Parse(tokenStr)
-	if err != nil {
+	userID, err := ValidateEmailVerificationToken(store, tokenStr)
+	if errors.Is(err, ErrTokenFormat) {
 		return ErrorResponse{
 			Message: "Invalid token format",
 			Code:    http.StatusBadRequest,
 		}
 	}
-
-	token, err := store.GetToken(&tokenID)
-	if err != nil {
+	if errors.Is(err, ErrTokenExpired) {
 		return ErrorResponse{
-			Message: "Invalid token",
+			Message: "Token expired",
 			Code:    http.StatusUnauthorized,
 		}
 	}
-
-	expirationTime := token.Expiry
-	if time.Now().After(expirationTime) {
-		store.DeleteToken(&token.Token)
+	if err != nil {
 		return ErrorResponse{
-			Message: "Token expired",
+			Message: "Invalid token",
 			Code:    http.StatusUnauthorized,
 		}
 	}
 
-	user, err := store.SelectUserByIDDB(&token.UserID)
+	user, err := store.SelectUserByIDDB(&userID)
 	if err != nil {
 		return ErrorResponse{
 			Message: "User not found",
@@ -339,7 +476,8 @@ func VerifyEmail(store database.AuthStore, tokenStr string) ErrorResponse {
 		}
 	}
 
-	store.DeleteToken(&token.Token)
+	store.DeleteTokensByUserIDAndPurpose(&user.ID, TokenPurposeEmailVerification)
+	recordAudit(store, &user.ID, AuditEmailVerified, "")
 	return ErrorResponse{Message: "", Code: http.StatusOK}
 }
 
@@ -1,11 +1,111 @@
 package logic
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// memoryTokenStore keeps tokens in a map by prefix for testing token
+// validation. Like the database it only keeps the hash of a token.
+type memoryTokenStore struct {
+	tokens   map[string]database.Token
+	sessions map[uuid.UUID]*database.Session
+	audit    []*database.AuditEvent
+	// Makes rotating refresh tokens fail like a database error
+	failInserts bool
+}
+
+func newMemoryTokenStore() *memoryTokenStore {
+	return &memoryTokenStore{
+		tokens:   map[string]database.Token{},
+		sessions: map[uuid.UUID]*database.Session{},
+	}
+}
+
+func (store *memoryTokenStore) InsertToken(token *database.Token) error {
+	prefix, err := database.TokenPrefix(token.Token)
+	if err != nil {
+		return err
+	}
+	store.tokens[prefix] = database.Token{
+		Prefix:    prefix,
+		Hash:      database.HashToken(token.Token),
+		UserID:    token.UserID,
+		Expiry:    token.Expiry,
+		Purpose:   token.Purpose,
+		SessionID: token.SessionID,
+		UsedAt:    token.UsedAt,
+	}
+	return nil
+}
+
+func (store *memoryTokenStore) GetToken(token string) (*database.Token, error) {
+	prefix, err := database.TokenPrefix(token)
+	if err != nil {
+		return nil, err
+	}
+	found, ok := store.tokens[prefix]
+	if !ok || found.Hash != database.HashToken(token) {
+		return nil, errors.New("no rows in result set")
+	}
+	return &found, nil
+}
+
+func (store *memoryTokenStore) ListTokens() ([]*database.Token, error) {
+	tokens := []*database.Token{}
+	for _, token := range store.tokens {
+		tokens = append(tokens, &token)
+	}
+	return tokens, nil
+}
+
+func (store *memoryTokenStore) DeleteToken(prefix string) error {
+	delete(store.tokens, prefix)
+	return nil
+}
+
+func (store *memoryTokenStore) DeleteTokensByUserID(userID *uuid.UUID) error {
+	for id, token := range store.tokens {
+		if token.UserID == *userID {
+			delete(store.tokens, id)
+		}
+	}
+	return nil
+}
+
+func (store *memoryTokenStore) DeleteTokensByUserIDAndPurpose(userID *uuid.UUID, purpose string) error {
+	for id, token := range store.tokens {
+		if token.UserID == *userID && token.Purpose == purpose {
+			delete(store.tokens, id)
+		}
+	}
+	return nil
+}
+
+func (store *memoryTokenStore) InsertAuditEventDB(event *database.AuditEvent) error {
+	store.audit = append(store.audit, event)
+	return nil
+}
+
+func (store *memoryTokenStore) SelectUserAuditEventsDB(userID *uuid.UUID) ([]*database.AuditEvent, error) {
+	return store.audit, nil
+}
+
+func (store *memoryTokenStore) DeleteExpiredTokens() error {
+	for id, token := range store.tokens {
+		if token.Expiry.Before(time.Now()) {
+			delete(store.tokens, id)
+		}
+	}
+	return nil
+}
+
 func TestPasswordGeneration(t *testing.T) {
 	password := "password"
 	hashedPassword := hashPassword(password)
@@ -15,3 +115,107 @@ func TestPasswordGeneration(t *testing.T) {
 		t.Errorf("Hashed password did not match original password: %v", bcryptCompareErr)
 	}
 }
+
+func TestGenerateToken_Lifetimes(t *testing.T) {
+	userID := uuid.New()
+	for purpose, lifetime := range tokenLifetimes {
+		token := GenerateToken(&userID, purpose)
+		if token.Purpose != purpose {
+			t.Errorf("Expected purpose %s, but got %s", purpose, token.Purpose)
+		}
+		remaining := time.Until(token.Expiry)
+		if remaining > lifetime || remaining < lifetime-time.Minute {
+			t.Errorf("Expected %s token to be valid for %s, but got %s", purpose, lifetime, remaining)
+		}
+	}
+
+	if time.Until(GeneratePasswordResetToken(&userID).Expiry) >= time.Until(GenerateRefreshToken(&userID).Expiry) {
+		t.Errorf("Expected password reset tokens to expire before refresh tokens")
+	}
+	if time.Until(GenerateSessionToken(&userID).Expiry) >= time.Until(GenerateRefreshToken(&userID).Expiry) {
+		t.Errorf("Expected access tokens to expire before refresh tokens")
+	}
+}
+
+func TestValidateToken_Purpose(t *testing.T) {
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+
+	session := GenerateSessionToken(&userID)
+	verification := GenerateEmailVerificationToken(&userID)
+	reset := GeneratePasswordResetToken(&userID)
+	for _, token := range []database.Token{session, verification, reset} {
+		store.InsertToken(&token)
+	}
+
+	validators := map[string]func(database.TokenStore, string) (uuid.UUID, error){
+		TokenPurposeSession:           ValidateSessionToken,
+		TokenPurposeEmailVerification: ValidateEmailVerificationToken,
+		TokenPurposePasswordReset:     ValidatePasswordResetToken,
+	}
+	for purpose, validate := range validators {
+		for _, token := range []database.Token{session, verification, reset} {
+			got, err := validate(store, token.Token)
+			if token.Purpose == purpose {
+				if err != nil || got != userID {
+					t.Errorf("Expected %s token to be valid for %s, but got %v", token.Purpose, purpose, err)
+				}
+				continue
+			}
+			if !errors.Is(err, ErrTokenInvalid) {
+				t.Errorf("Expected %s token to be invalid for %s, but got %v", token.Purpose, purpose, err)
+			}
+		}
+	}
+
+	if _, err := ValidateSessionToken(store, "not-a-token"); !errors.Is(err, ErrTokenFormat) {
+		t.Errorf("Expected error %v, but got %v", ErrTokenFormat, err)
+	}
+
+	expired := GenerateSessionToken(&userID)
+	expired.Expiry = time.Now().Add(-time.Minute)
+	store.InsertToken(&expired)
+	if _, err := ValidateSessionToken(store, expired.Token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected error %v, but got %v", ErrTokenExpired, err)
+	}
+	if _, ok := store.tokens[expired.Prefix]; !ok {
+		t.Errorf("Expected expired token to be kept until the scheduler runs")
+	}
+	DeleteExpiredTokens(store)
+	if _, ok := store.tokens[expired.Prefix]; ok {
+		t.Errorf("Expected expired token to be deleted")
+	}
+}
+
+func TestValidateToken_Hashed(t *testing.T) {
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+	token := GenerateSessionToken(&userID)
+	store.InsertToken(&token)
+
+	if !strings.HasPrefix(token.Token, "mm_"+token.Prefix+"_") {
+		t.Errorf("Expected token to start with its prefix %s, but got %s", token.Prefix, token.Token)
+	}
+	stored := store.tokens[token.Prefix]
+	if stored.Token != "" || stored.Hash == token.Token || len(stored.Hash) != 64 {
+		t.Errorf("Expected only the hash of the token to be stored, but got %+v", stored)
+	}
+
+	// Same prefix, different secret
+	forged := token.Token[:len(token.Token)-4] + "0000"
+	if forged == token.Token {
+		forged = token.Token[:len(token.Token)-4] + "1111"
+	}
+	if _, err := ValidateSessionToken(store, forged); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("Expected error %v for a forged secret, but got %v", ErrTokenInvalid, err)
+	}
+
+	if _, err := ValidateSessionToken(store, uuid.New().String()); !errors.Is(err, ErrTokenFormat) {
+		t.Errorf("Expected error %v for a plaintext UUID token, but got %v", ErrTokenFormat, err)
+	}
+
+	other := GenerateSessionToken(&userID)
+	if other.Token == token.Token || other.Prefix == token.Prefix {
+		t.Errorf("Expected tokens to be unique, but got %s twice", token.Token)
+	}
+}
@@ -0,0 +1,315 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Supported budget periods. Weeks start on Monday, months on the first.
+const (
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodMonthly = "monthly"
+)
+
+// Rollover rules for what is left of a period's budget. With RolloverUnused
+// only money that was not spent carries over, with RolloverFull overspending
+// is taken from the next period as well.
+const (
+	RolloverNone   = "none"
+	RolloverUnused = "unused"
+	RolloverFull   = "full"
+)
+
+// BudgetForUpdate holds the fields of a budget that can be changed. To change
+// its period, delete it and create a new one.
+type BudgetForUpdate struct {
+	Category string       `json:"category"`
+	Limit    money.Amount `json:"limit"`
+	Rollover string       `json:"rollover"`
+}
+
+// BudgetPeriod is the spending of a budget's category from Start up to but
+// not including End. Available is the limit plus what was carried over from
+// the period before.
+type BudgetPeriod struct {
+	Start     time.Time    `json:"start"`
+	End       time.Time    `json:"end"`
+	Limit     money.Amount `json:"limit"`
+	Carried   money.Amount `json:"carried"`
+	Available money.Amount `json:"available"`
+	Spent     money.Amount `json:"spent"`
+	Remaining money.Amount `json:"remaining"`
+}
+
+// BudgetStatus reports the current period of a budget and, if requested, all
+// periods since the budget started, newest first.
+type BudgetStatus struct {
+	Budget  *database.Budget `json:"budget"`
+	Current BudgetPeriod     `json:"current"`
+	Periods []BudgetPeriod   `json:"periods,omitempty"`
+}
+
+func CreateBudget(store database.BudgetLedgerStore, userID *uuid.UUID, budget *database.Budget) (*database.Budget, ErrorResponse) {
+	account, errResp := resolveAccount(store, userID, &budget.AccountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	budget.AccountID = account.ID
+	budget.UserID = *userID
+	budget.Category = strings.TrimSpace(budget.Category)
+	budget.Period = strings.ToLower(strings.TrimSpace(budget.Period))
+	budget.Rollover = strings.ToLower(strings.TrimSpace(budget.Rollover))
+	if budget.Rollover == "" {
+		budget.Rollover = RolloverNone
+	}
+	if budget.StartDate.IsZero() {
+		budget.StartDate = time.Now()
+	}
+
+	if err := validateBudget(budget); err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+	budget.StartDate = periodStart(budget.Period, budget.StartDate)
+
+	budgetID, err := store.InsertBudgetDB(budget)
+	if err != nil {
+		fmt.Println("Error inserting budget:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to insert budget",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return GetBudgetByID(store, userID, &budgetID)
+}
+
+func GetBudgets(store database.BudgetStore, userID *uuid.UUID) ([]*database.Budget, ErrorResponse) {
+	budgets, err := store.SelectUserBudgetsDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving budgets:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve budgets",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return budgets, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func GetBudgetByID(store database.BudgetStore, actorID *uuid.UUID, budgetID *uuid.UUID) (*database.Budget, ErrorResponse) {
+	budget, err := store.SelectBudgetByIDDB(budgetID)
+	if err != nil {
+		fmt.Println("Error retrieving budget:", err)
+		return nil, ErrorResponse{
+			Message: "Budget not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if budget.UserID != *actorID {
+		return nil, ErrorResponse{
+			Message: "Forbidden: cannot access another user's budget",
+			Code:    http.StatusForbidden,
+		}
+	}
+
+	return budget, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func UpdateBudget(store database.BudgetStore, actorID *uuid.UUID, budgetID *uuid.UUID, budgetForUpdate *BudgetForUpdate) (*database.Budget, ErrorResponse) {
+	budget, errResp := GetBudgetByID(store, actorID, budgetID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	budget.Category = strings.TrimSpace(budgetForUpdate.Category)
+	budget.Limit = budgetForUpdate.Limit
+	budget.Rollover = strings.ToLower(strings.TrimSpace(budgetForUpdate.Rollover))
+	if budget.Rollover == "" {
+		budget.Rollover = RolloverNone
+	}
+
+	if err := validateBudget(budget); err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	if err := store.UpdateBudgetDB(budget); err != nil {
+		fmt.Println("Error updating budget:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to update budget",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return budget, errResp
+}
+
+func DeleteBudget(store database.BudgetStore, actorID *uuid.UUID, budgetID *uuid.UUID) ErrorResponse {
+	_, errResp := GetBudgetByID(store, actorID, budgetID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+
+	if err := store.DeleteBudgetDB(budgetID); err != nil {
+		fmt.Println("Error deleting budget:", err)
+		return ErrorResponse{
+			Message: "Failed to delete budget",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return errResp
+}
+
+// GetBudgetStatus returns the current period of the budget together with all
+// periods before it.
+func GetBudgetStatus(store database.BudgetLedgerStore, actorID *uuid.UUID, budgetID *uuid.UUID) (*BudgetStatus, ErrorResponse) {
+	budget, errResp := GetBudgetByID(store, actorID, budgetID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	transactions, errResp := GetAllTransactions(store, actorID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	periods := calculateBudgetPeriods(budget, transactions, time.Now())
+	return &BudgetStatus{
+		Budget:  budget,
+		Current: periods[0],
+		Periods: periods,
+	}, errResp
+}
+
+// GetAllBudgetStatus returns the current period of every budget of the user.
+func GetAllBudgetStatus(store database.BudgetLedgerStore, userID *uuid.UUID) ([]BudgetStatus, ErrorResponse) {
+	budgets, errResp := GetBudgets(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	transactions, errResp := GetAllTransactions(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	now := time.Now()
+	statuses := []BudgetStatus{}
+	for _, budget := range budgets {
+		periods := calculateBudgetPeriods(budget, transactions, now)
+		statuses = append(statuses, BudgetStatus{
+			Budget:  budget,
+			Current: periods[0],
+		})
+	}
+
+	return statuses, errResp
+}
+
+func validateBudget(budget *database.Budget) error {
+	if budget.Category == "" {
+		return errors.New("category is required")
+	}
+	switch budget.Period {
+	case BudgetPeriodWeekly, BudgetPeriodMonthly:
+	default:
+		return fmt.Errorf("invalid period %q", budget.Period)
+	}
+	switch budget.Rollover {
+	case RolloverNone, RolloverUnused, RolloverFull:
+	default:
+		return fmt.Errorf("invalid rollover %q", budget.Rollover)
+	}
+	if budget.Limit <= 0 {
+		return errors.New("limit must be positive")
+	}
+	return nil
+}
+
+// calculateBudgetPeriods walks the periods from the budget's start up to the
+// one containing now and carries over what is left of each period according
+// to the rollover rule. Spending is the net outflow of the transactions in the
+// budget's category and account, so refunds are taken off again. Returns the
+// periods newest first and always at least the current one.
+func calculateBudgetPeriods(budget *database.Budget, transactions []*database.Transaction, now time.Time) []BudgetPeriod {
+	periods := []BudgetPeriod{}
+	var carried money.Amount
+	for start := periodStart(budget.Period, budget.StartDate); ; {
+		end := nextPeriod(budget.Period, start)
+		period := BudgetPeriod{
+			Start:     start,
+			End:       end,
+			Limit:     budget.Limit,
+			Carried:   carried,
+			Available: budget.Limit + carried,
+		}
+		for _, transaction := range transactions {
+			if transaction.AccountID != budget.AccountID || !strings.EqualFold(transaction.Category, budget.Category) {
+				continue
+			}
+			if transaction.Date.Before(start) || !transaction.Date.Before(end) {
+				continue
+			}
+			period.Spent -= transaction.Amount
+		}
+		period.Remaining = period.Available - period.Spent
+		periods = append(periods, period)
+
+		if end.After(now) {
+			slices.Reverse(periods)
+			return periods
+		}
+
+		switch budget.Rollover {
+		case RolloverUnused:
+			carried = max(period.Remaining, 0)
+		case RolloverFull:
+			carried = period.Remaining
+		}
+		start = end
+	}
+}
+
+// periodStart returns the start of the week, month or year containing t, in
+// t's location.
+func periodStart(period string, t time.Time) time.Time {
+	switch period {
+	case BudgetPeriodWeekly:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case ReportPeriodYearly:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+func nextPeriod(period string, start time.Time) time.Time {
+	switch period {
+	case BudgetPeriodWeekly:
+		return start.AddDate(0, 0, 7)
+	case ReportPeriodYearly:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
@@ -0,0 +1,65 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+func TestCalculateBudgetPeriods_Rollover(t *testing.T) {
+	accountID := uuid.New()
+	budget := &database.Budget{
+		Category:  "Groceries",
+		Period:    BudgetPeriodMonthly,
+		Limit:     money.FromInt(300),
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		AccountID: accountID,
+	}
+	transactions := []*database.Transaction{
+		{Amount: money.FromInt(-200), Category: "Groceries", Date: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), AccountID: accountID},
+		{Amount: money.FromInt(-450), Category: "groceries", Date: time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC), AccountID: accountID},
+		// Refunds reduce the spending
+		{Amount: money.FromInt(20), Category: "Groceries", Date: time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC), AccountID: accountID},
+		{Amount: money.FromInt(-50), Category: "Groceries", Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), AccountID: accountID},
+		// Other categories and accounts are ignored
+		{Amount: money.FromInt(-80), Category: "Rent", Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), AccountID: accountID},
+		{Amount: money.FromInt(-80), Category: "Groceries", Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), AccountID: uuid.New()},
+	}
+	now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	expected := map[string][]money.Amount{
+		// Remaining of March, February and January
+		RolloverNone:   {money.FromInt(250), money.FromInt(-130), money.FromInt(100)},
+		RolloverUnused: {money.FromInt(250), money.FromInt(-30), money.FromInt(100)},
+		RolloverFull:   {money.FromInt(220), money.FromInt(-30), money.FromInt(100)},
+	}
+
+	for rollover, remaining := range expected {
+		budget.Rollover = rollover
+		periods := calculateBudgetPeriods(budget, transactions, now)
+		if len(periods) != len(remaining) {
+			t.Fatalf("Expected %d periods with rollover %s, but got %d", len(remaining), rollover, len(periods))
+		}
+		for i, period := range periods {
+			if period.Remaining != remaining[i] {
+				t.Errorf("Expected remaining %s in period %d with rollover %s, but got %s", remaining[i], i, rollover, period.Remaining)
+			}
+		}
+	}
+
+	if start := calculateBudgetPeriods(budget, transactions, now)[0].Start; !start.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected current period to start on 2024-03-01, but got %s", start)
+	}
+}
+
+func TestPeriodStart_Weekly(t *testing.T) {
+	// 2024-03-03 is a Sunday
+	start := periodStart(BudgetPeriodWeekly, time.Date(2024, 3, 3, 18, 30, 0, 0, time.UTC))
+	expected := time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC)
+	if !start.Equal(expected) {
+		t.Errorf("Expected week to start on %s, but got %s", expected, start)
+	}
+}
@@ -0,0 +1,278 @@
+package logic
+
+import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Supported digest frequencies. Digests are sent at the start of each week or
+// month, like budget periods.
+const (
+	DigestFrequencyWeekly  = BudgetPeriodWeekly
+	DigestFrequencyMonthly = BudgetPeriodMonthly
+)
+
+// Time after which a digest that could not be sent is tried again
+const digestRetryInterval = time.Hour
+
+//go:embed templates/digest.html templates/digest.txt
+var digestTemplates embed.FS
+
+var digestTemplateFuncs = map[string]any{
+	"date": func(t time.Time) string {
+		return t.Format(time.DateOnly)
+	},
+	// signed writes increases with a plus sign
+	"signed": func(amount money.Amount) string {
+		if amount > 0 {
+			return "+" + amount.String()
+		}
+		return amount.String()
+	},
+}
+
+var digestHTMLTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Funcs(digestTemplateFuncs).ParseFS(digestTemplates, "templates/digest.html"))
+
+var digestTextTemplate = texttemplate.Must(texttemplate.New("digest.txt").Funcs(digestTemplateFuncs).ParseFS(digestTemplates, "templates/digest.txt"))
+
+type DigestForUpdate struct {
+	Frequency string `json:"frequency"`
+}
+
+// DigestAccount is the balance and budget of an account and their change
+// since the last digest.
+type DigestAccount struct {
+	Name          string
+	Currency      string
+	Balance       money.Amount
+	Budget        money.Amount
+	BalanceChange money.Amount
+	BudgetChange  money.Amount
+}
+
+// DigestItem is an upcoming occurrence of a recurring schedule.
+type DigestItem struct {
+	Date        time.Time
+	Description string
+	Amount      money.Amount
+	Currency    string
+}
+
+// DigestData is what a digest email is rendered from. Since is the time of
+// the last digest and Upcoming holds the recurring items until the next one.
+type DigestData struct {
+	Username  string
+	Frequency string
+	Since     time.Time
+	Until     time.Time
+	Accounts  []DigestAccount
+	Upcoming  []DigestItem
+}
+
+func GetDigest(store database.DigestStore, userID *uuid.UUID) (*database.Digest, ErrorResponse) {
+	digest, err := store.SelectDigestDB(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorResponse{
+			Message: "Digest not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		fmt.Println("Error retrieving digest:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve digest",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return digest, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// SubscribeDigest subscribes the user to digests, or changes the frequency of
+// the subscription. The first digest is sent at the start of the next week or
+// month.
+func SubscribeDigest(store database.DigestStore, userID *uuid.UUID, digestForUpdate *DigestForUpdate) (*database.Digest, ErrorResponse) {
+	frequency := strings.ToLower(strings.TrimSpace(digestForUpdate.Frequency))
+	if frequency != DigestFrequencyWeekly && frequency != DigestFrequencyMonthly {
+		return nil, ErrorResponse{
+			Message: fmt.Sprintf("Invalid frequency %q", digestForUpdate.Frequency),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	digest := &database.Digest{
+		UserID:     *userID,
+		Frequency:  frequency,
+		NextSendAt: nextDigestAt(frequency, time.Now()),
+	}
+	if err := store.UpsertDigestDB(digest); err != nil {
+		fmt.Println("Error subscribing to digest:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to subscribe to digest",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return GetDigest(store, userID)
+}
+
+func UnsubscribeDigest(store database.DigestStore, userID *uuid.UUID) ErrorResponse {
+	_, errResp := GetDigest(store, userID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+
+	if err := store.DeleteDigestDB(userID); err != nil {
+		fmt.Println("Error unsubscribing from digest:", err)
+		return ErrorResponse{
+			Message: "Failed to unsubscribe from digest",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return errResp
+}
+
+// SendDueDigests sends every digest that is due and schedules the next one.
+// A digest that cannot be sent is tried again later. Returns the number of
+// digests sent.
+func SendDueDigests(store database.DigestLedgerStore, sender EmailSender, now time.Time) int {
+	digests, err := store.SelectDueDigestsDB(now)
+	if err != nil {
+		fmt.Println("Error retrieving due digests:", err)
+		return 0
+	}
+
+	count := 0
+	for _, digest := range digests {
+		if err := sendDigest(store, sender, digest, now); err != nil {
+			fmt.Println("Error sending digest to user", digest.UserID, ":", err)
+			digest.NextSendAt = now.Add(digestRetryInterval)
+		} else {
+			digest.LastSentAt = &now
+			digest.NextSendAt = nextDigestAt(digest.Frequency, now)
+			count++
+		}
+
+		if err := store.UpdateDigestSentDB(digest); err != nil {
+			fmt.Println("Error updating digest:", err)
+		}
+	}
+	return count
+}
+
+func sendDigest(store database.DigestLedgerStore, sender EmailSender, digest *database.Digest, now time.Time) error {
+	user, err := store.SelectUserByIDDB(&digest.UserID)
+	if err != nil {
+		return err
+	}
+	accounts, err := store.SelectUserAccountsDB(&digest.UserID)
+	if err != nil {
+		return err
+	}
+	entries := map[uuid.UUID][]*database.MoneyEntry{}
+	for _, account := range accounts {
+		if entries[account.ID], err = store.SelectAccountMoneyDB(&account.ID); err != nil {
+			return err
+		}
+	}
+	schedules, err := store.SelectUserRecurringDB(&digest.UserID)
+	if err != nil {
+		return err
+	}
+
+	since := previousDigestAt(digest.Frequency, now)
+	if digest.LastSentAt != nil {
+		since = *digest.LastSentAt
+	}
+	data := buildDigest(user, accounts, entries, schedules, since, now, nextDigestAt(digest.Frequency, now))
+	data.Frequency = digest.Frequency
+
+	subject, textBody, htmlBody, err := renderDigest(&data)
+	if err != nil {
+		return err
+	}
+	return sender.SendEmail(user.Email, subject, textBody, htmlBody)
+}
+
+// buildDigest collects the balance and budget of each account now and their
+// change since the last digest, and the recurring items up to the next
+// digest. Entries are ordered newest first.
+func buildDigest(user *database.User, accounts []*database.Account, entries map[uuid.UUID][]*database.MoneyEntry, schedules []*database.Recurring, since time.Time, now time.Time, next time.Time) DigestData {
+	data := DigestData{
+		Username: user.Username,
+		Since:    since,
+		Until:    now,
+		Accounts: []DigestAccount{},
+		Upcoming: []DigestItem{},
+	}
+
+	currencies := map[uuid.UUID]string{}
+	for _, account := range accounts {
+		currencies[account.ID] = account.Currency
+		digestAccount := DigestAccount{Name: account.Name, Currency: account.Currency}
+		if current := balanceAt(entries[account.ID], now); current != nil {
+			digestAccount.Balance = current.Balance
+			digestAccount.Budget = current.Budget
+		}
+		digestAccount.BalanceChange = digestAccount.Balance
+		digestAccount.BudgetChange = digestAccount.Budget
+		if previous := balanceAt(entries[account.ID], since); previous != nil {
+			digestAccount.BalanceChange -= previous.Balance
+			digestAccount.BudgetChange -= previous.Budget
+		}
+		data.Accounts = append(data.Accounts, digestAccount)
+	}
+
+	for _, occurrence := range upcomingOccurrences(schedules, now, next) {
+		data.Upcoming = append(data.Upcoming, DigestItem{
+			Date:        occurrence.at,
+			Description: occurrence.recurring.Description,
+			Amount:      occurrence.amount,
+			Currency:    currencies[occurrence.recurring.AccountID],
+		})
+	}
+
+	return data
+}
+
+func renderDigest(data *DigestData) (string, string, string, error) {
+	var textBody, htmlBody bytes.Buffer
+	if err := digestTextTemplate.Execute(&textBody, data); err != nil {
+		return "", "", "", err
+	}
+	if err := digestHTMLTemplate.Execute(&htmlBody, data); err != nil {
+		return "", "", "", err
+	}
+
+	subject := fmt.Sprintf("Your %s summary", data.Frequency)
+	return subject, textBody.String(), htmlBody.String(), nil
+}
+
+// nextDigestAt returns the start of the week or month after the one
+// containing now.
+func nextDigestAt(frequency string, now time.Time) time.Time {
+	return nextPeriod(frequency, periodStart(frequency, now.UTC()))
+}
+
+func previousDigestAt(frequency string, now time.Time) time.Time {
+	if frequency == DigestFrequencyWeekly {
+		return now.AddDate(0, 0, -7)
+	}
+	return now.AddDate(0, -1, 0)
+}
@@ -0,0 +1,69 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+func TestBuildDigest(t *testing.T) {
+	account := &database.Account{ID: uuid.New(), Name: "Checking <main>", Currency: "EUR"}
+	entries := []*database.MoneyEntry{
+		{Balance: money.FromInt(1500), Budget: money.FromInt(400), EffectiveAt: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{Balance: money.FromInt(1000), Budget: money.FromInt(300), EffectiveAt: time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC)},
+	}
+	schedules := []*database.Recurring{{
+		Description: "Rent",
+		Amount:      money.FromInt(-800),
+		Frequency:   FrequencyMonthly,
+		Interval:    1,
+		StartDate:   time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Occurrence:  2,
+		AccountID:   account.ID,
+	}}
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+
+	data := buildDigest(&database.User{Username: "alice"}, []*database.Account{account},
+		map[uuid.UUID][]*database.MoneyEntry{account.ID: entries}, schedules, since, now, nextDigestAt(DigestFrequencyWeekly, now))
+	data.Frequency = DigestFrequencyWeekly
+
+	if len(data.Accounts) != 1 {
+		t.Fatalf("Expected 1 account, but got %d", len(data.Accounts))
+	}
+	if data.Accounts[0].Balance != money.FromInt(1500) || data.Accounts[0].BalanceChange != money.FromInt(500) || data.Accounts[0].BudgetChange != money.FromInt(100) {
+		t.Errorf("Unexpected account %+v", data.Accounts[0])
+	}
+	// The next digest is sent on Monday the 18th, the rent is due before
+	if len(data.Upcoming) != 1 || data.Upcoming[0].Description != "Rent" || !data.Upcoming[0].Date.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected rent on 2024-03-15, but got %+v", data.Upcoming)
+	}
+
+	subject, textBody, htmlBody, err := renderDigest(&data)
+	if err != nil {
+		t.Fatalf("Expected digest to render, but got %s", err)
+	}
+	if subject != "Your weekly summary" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if !strings.Contains(textBody, "1500.00 EUR (+500.00)") || !strings.Contains(textBody, "-800.00 EUR") {
+		t.Errorf("Expected balance change and rent in text body, but got:\n%s", textBody)
+	}
+	if !strings.Contains(htmlBody, "Checking &lt;main&gt;") {
+		t.Errorf("Expected escaped account name in HTML body")
+	}
+}
+
+func TestNextDigestAt(t *testing.T) {
+	now := time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)
+	if next := nextDigestAt(DigestFrequencyWeekly, now); !next.Equal(time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected next weekly digest on 2024-03-18, but got %s", next)
+	}
+	if next := nextDigestAt(DigestFrequencyMonthly, now); !next.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected next monthly digest on 2024-04-01, but got %s", next)
+	}
+}
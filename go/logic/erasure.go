@@ -0,0 +1,134 @@
+package logic
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Time between requesting the erasure of an account and carrying it out, in
+// which the user can still cancel it
+const erasureGracePeriod = 14 * 24 * time.Hour
+
+// ErasureRequest confirms the erasure of the account with the user's
+// password.
+type ErasureRequest struct {
+	Password string `json:"password"`
+}
+
+// ErasureStatus reports when the account is erased, nil if no erasure is
+// scheduled.
+type ErasureStatus struct {
+	ScheduledAt *time.Time `json:"scheduled_at"`
+}
+
+func GetErasureStatus(store database.UserStore, userID *uuid.UUID) (*ErasureStatus, ErrorResponse) {
+	scheduledAt, err := store.SelectUserErasureDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving erasure:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve erasure",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return &ErasureStatus{ScheduledAt: scheduledAt}, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// ScheduleErasure schedules the erasure of the user's account and all its data
+// after the grace period. The user has to enter the password again, a token
+// alone is not enough. Requesting it again keeps the original date.
+func ScheduleErasure(store database.AuthStore, mailConfig EmailSender, userID *uuid.UUID, request *ErasureRequest) (*ErasureStatus, ErrorResponse) {
+	user, err := store.SelectUserByIDDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving user:", err)
+		return nil, ErrorResponse{
+			Message: "User not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
+		fmt.Println("Unsuccessful erasure confirmation for", user.Email)
+		return nil, ErrorResponse{
+			Message: "Invalid password",
+			Code:    http.StatusUnauthorized,
+		}
+	}
+
+	status, errResp := GetErasureStatus(store, userID)
+	if errResp.Code != http.StatusOK || status.ScheduledAt != nil {
+		return status, errResp
+	}
+
+	scheduledAt := time.Now().Add(erasureGracePeriod)
+	if err := store.SetUserErasureDB(userID, &scheduledAt); err != nil {
+		fmt.Println("Error scheduling erasure:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to schedule erasure",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	recordAudit(store, userID, AuditErasureScheduled, scheduledAt.Format(time.RFC3339))
+
+	err = mailConfig.SendEmail(user.Email, "Account erasure scheduled",
+		fmt.Sprintf("Your account and all its data will be erased on %s. You can cancel the erasure until then.", scheduledAt.Format(time.DateOnly)),
+		"")
+	if err != nil {
+		fmt.Println("Failed to send erasure email:", err.Error())
+	}
+
+	return &ErasureStatus{ScheduledAt: &scheduledAt}, errResp
+}
+
+func CancelErasure(store database.AuthStore, userID *uuid.UUID) ErrorResponse {
+	status, errResp := GetErasureStatus(store, userID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+	if status.ScheduledAt == nil {
+		return ErrorResponse{
+			Message: "No erasure scheduled",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if err := store.SetUserErasureDB(userID, nil); err != nil {
+		fmt.Println("Error cancelling erasure:", err)
+		return ErrorResponse{
+			Message: "Failed to cancel erasure",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	recordAudit(store, userID, AuditErasureCancelled, "")
+
+	return errResp
+}
+
+// ExecuteDueErasures deletes the users whose grace period is over. All their
+// data, including the audit log, is deleted with them. Returns the number of
+// users deleted.
+func ExecuteDueErasures(store database.UserStore, now time.Time) int {
+	userIDs, err := store.SelectDueErasuresDB(now)
+	if err != nil {
+		fmt.Println("Error retrieving due erasures:", err)
+		return 0
+	}
+
+	count := 0
+	for _, userID := range userIDs {
+		if err := store.DeleteUserDB(&userID); err != nil {
+			fmt.Println("Error erasing user", userID, ":", err)
+			continue
+		}
+		count++
+	}
+	return count
+}
@@ -0,0 +1,66 @@
+package logic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+// Types of balance events
+const (
+	BalanceCreated = "balance.created"
+	BalanceUpdated = "balance.updated"
+	BalanceDeleted = "balance.deleted"
+)
+
+// BalanceEvent reports a change of an account's chain. Entry is the entry
+// that changed, Previous and Latest are copies of the newest entry of the
+// chain before and after the change, nil if the chain is empty. Entries is
+// the recomputed chain, newest first.
+type BalanceEvent struct {
+	Type      string
+	UserID    uuid.UUID
+	AccountID uuid.UUID
+	Entry     *database.MoneyEntry
+	Previous  *database.MoneyEntry
+	Latest    *database.MoneyEntry
+	Entries   []*database.MoneyEntry
+	At        time.Time
+}
+
+// BalanceListener is called for every balance event after the change was
+// stored. Listeners run on the request's goroutine, so anything slow has to
+// be done in the background.
+type BalanceListener func(event *BalanceEvent)
+
+var (
+	balanceListenersMu sync.RWMutex
+	balanceListeners   []BalanceListener
+)
+
+// OnBalanceChange registers a listener for all balance events.
+func OnBalanceChange(listener BalanceListener) {
+	balanceListenersMu.Lock()
+	defer balanceListenersMu.Unlock()
+	balanceListeners = append(balanceListeners, listener)
+}
+
+func publishBalanceEvent(event *BalanceEvent) {
+	balanceListenersMu.RLock()
+	defer balanceListenersMu.RUnlock()
+	for _, listener := range balanceListeners {
+		listener(event)
+	}
+}
+
+// newestEntry returns a copy of the newest entry of the chain, so later
+// budget recalculations on the chain do not change it.
+func newestEntry(entries []*database.MoneyEntry) *database.MoneyEntry {
+	if len(entries) == 0 {
+		return nil
+	}
+	newest := *entries[0]
+	return &newest
+}
@@ -0,0 +1,330 @@
+package logic
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+)
+
+// Currency of accounts created without one and of aggregated views when no
+// reporting currency is requested.
+const defaultCurrency = "EUR"
+
+// Supported formats for exchange rate files
+const (
+	ExchangeRateFormatCSV = "csv"
+	ExchangeRateFormatECB = "ecb"
+)
+
+// currencyConverter converts amounts between currencies using a fixed set of
+// rates, either directly, through the inverse rate or through one common
+// intermediate currency (ECB rates are all quoted against EUR).
+type currencyConverter struct {
+	factors    map[[2]string]*big.Rat
+	currencies []string
+}
+
+func newCurrencyConverter(rates []*database.ExchangeRate) *currencyConverter {
+	converter := &currencyConverter{factors: map[[2]string]*big.Rat{}}
+	for _, rate := range rates {
+		if rate.Rate > 0 {
+			converter.factors[[2]string{rate.Base, rate.Quote}] = rate.Rate.Rat()
+		}
+	}
+	// Explicit rates take precedence over inverted ones
+	for _, rate := range rates {
+		inverse := [2]string{rate.Quote, rate.Base}
+		if _, ok := converter.factors[inverse]; !ok && rate.Rate > 0 {
+			converter.factors[inverse] = new(big.Rat).Inv(rate.Rate.Rat())
+		}
+	}
+
+	// Crossing through the default currency is tried first, the rest in a
+	// stable order so conversions don't depend on map iteration
+	for pair := range converter.factors {
+		if pair[0] != defaultCurrency && !slices.Contains(converter.currencies, pair[0]) {
+			converter.currencies = append(converter.currencies, pair[0])
+		}
+	}
+	slices.Sort(converter.currencies)
+	converter.currencies = slices.Insert(converter.currencies, 0, defaultCurrency)
+	return converter
+}
+
+func (converter *currencyConverter) factor(from string, to string) (*big.Rat, bool) {
+	if from == to {
+		return big.NewRat(1, 1), true
+	}
+	if factor, ok := converter.factors[[2]string{from, to}]; ok {
+		return factor, true
+	}
+	for _, via := range converter.currencies {
+		first, ok := converter.factors[[2]string{from, via}]
+		if !ok {
+			continue
+		}
+		if second, ok := converter.factors[[2]string{via, to}]; ok {
+			return new(big.Rat).Mul(first, second), true
+		}
+	}
+	return nil, false
+}
+
+func (converter *currencyConverter) convert(amount money.Amount, from string, to string) (money.Amount, error) {
+	factor, ok := converter.factor(from, to)
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate from %s to %s", from, to)
+	}
+	return amount.MulRat(factor), nil
+}
+
+func loadCurrencyConverter(store database.ExchangeRateStore) (*currencyConverter, ErrorResponse) {
+	rates, errResp := GetExchangeRates(store, time.Now())
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	return newCurrencyConverter(rates), errResp
+}
+
+// normalizeCurrency turns a currency code into its upper case ISO 4217 form
+// and falls back to the default currency for an empty code.
+func normalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return defaultCurrency, nil
+	}
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency code %q", code)
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("invalid currency code %q", code)
+		}
+	}
+	return code, nil
+}
+
+func GetExchangeRates(store database.ExchangeRateStore, date time.Time) ([]*database.ExchangeRate, ErrorResponse) {
+	rates, err := store.SelectExchangeRatesDB(date)
+	if err != nil {
+		fmt.Println("Error retrieving exchange rates:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve exchange rates",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return rates, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func ImportExchangeRates(store database.ExchangeRateStore, reader io.Reader, format string) (int, ErrorResponse) {
+	var rates []*database.ExchangeRate
+	var err error
+	switch format {
+	case ExchangeRateFormatCSV:
+		rates, err = ParseExchangeRatesCSV(reader)
+	case ExchangeRateFormatECB:
+		rates, err = ParseECBExchangeRates(reader)
+	default:
+		return 0, ErrorResponse{
+			Message: "Unsupported exchange rate format",
+			Code:    http.StatusBadRequest,
+		}
+	}
+	if err != nil {
+		fmt.Println("Error parsing exchange rates:", err)
+		return 0, ErrorResponse{
+			Message: "Invalid exchange rate file: " + err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	if err := store.UpsertExchangeRatesDB(rates); err != nil {
+		fmt.Println("Error storing exchange rates:", err)
+		return 0, ErrorResponse{
+			Message: "Failed to store exchange rates",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return len(rates), ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// LoadExchangeRatesFile imports a local exchange rate file. Files ending in
+// .xml are read as ECB reference rates, everything else as CSV.
+func LoadExchangeRatesFile(store database.ExchangeRateStore, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	format := ExchangeRateFormatCSV
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		format = ExchangeRateFormatECB
+	}
+
+	count, errResp := ImportExchangeRates(store, file, format)
+	if errResp.Code != http.StatusOK {
+		return 0, errors.New(errResp.Message)
+	}
+	return count, nil
+}
+
+// ParseExchangeRatesCSV reads rates from a CSV file with a header naming the
+// columns date, base, quote and rate in any order. Dates use YYYY-MM-DD.
+func ParseExchangeRatesCSV(reader io.Reader) ([]*database.ExchangeRate, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var rates []*database.ExchangeRate
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := csvReader.FieldPos(0)
+		rate, err := newExchangeRate(record[columns["date"]], record[columns["base"]], record[columns["quote"]], record[columns["rate"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECBExchangeRates reads the euro foreign exchange reference rates XML
+// published by the European Central Bank (eurofxref-daily.xml and the
+// historical variants). All rates are quoted against EUR.
+func ParseECBExchangeRates(reader io.Reader) ([]*database.ExchangeRate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(reader).Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	var rates []*database.ExchangeRate
+	for _, day := range envelope.Days {
+		for _, ecbRate := range day.Rates {
+			rate, err := newExchangeRate(day.Time, "EUR", ecbRate.Currency, ecbRate.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", day.Time, err)
+			}
+			rates = append(rates, rate)
+		}
+	}
+	if len(rates) == 0 {
+		return nil, errors.New("no rates found")
+	}
+	return rates, nil
+}
+
+func newExchangeRate(dateStr string, base string, quote string, rateStr string) (*database.ExchangeRate, error) {
+	date, err := time.Parse(time.DateOnly, strings.TrimSpace(dateStr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", dateStr)
+	}
+	if strings.TrimSpace(base) == "" || strings.TrimSpace(quote) == "" {
+		return nil, errors.New("missing currency")
+	}
+	base, err = normalizeCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+	quote, err = normalizeCurrency(quote)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := money.ParseRate(rateStr)
+	if err != nil || rate <= 0 {
+		return nil, fmt.Errorf("invalid rate %q", rateStr)
+	}
+
+	return &database.ExchangeRate{
+		Base:  base,
+		Quote: quote,
+		Date:  date,
+		Rate:  rate,
+	}, nil
+}
+
+// ConvertBalances returns copies of the entries with balance and budget
+// converted into the given currency at the latest known rates.
+func ConvertBalances(store database.ExchangeRateStore, entries []*database.MoneyEntry, currency string) ([]*database.MoneyEntry, ErrorResponse) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	converter, errResp := loadCurrencyConverter(store)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	converted := make([]*database.MoneyEntry, 0, len(entries))
+	for _, entry := range entries {
+		convertedEntry := *entry
+		convertedEntry.Currency = currency
+		convertedEntry.Balance, err = converter.convert(entry.Balance, entry.Currency, currency)
+		if err == nil {
+			convertedEntry.Budget, err = converter.convert(entry.Budget, entry.Currency, currency)
+		}
+		if err != nil {
+			return nil, ErrorResponse{
+				Message: err.Error(),
+				Code:    http.StatusUnprocessableEntity,
+			}
+		}
+		converted = append(converted, &convertedEntry)
+	}
+
+	return converted, errResp
+}
@@ -0,0 +1,100 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/money"
+)
+
+const testECBRates = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time="2024-01-05">
+			<Cube currency="USD" rate="1.0921"/>
+			<Cube currency="JPY" rate="158.08"/>
+		</Cube>
+		<Cube time="2024-01-04">
+			<Cube currency="USD" rate="1.0953"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestParseECBExchangeRates(t *testing.T) {
+	rates, err := ParseECBExchangeRates(strings.NewReader(testECBRates))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, but got %d", len(rates))
+	}
+	first := rates[0]
+	if first.Base != "EUR" || first.Quote != "USD" || first.Rate != money.MustParseRate("1.0921") {
+		t.Errorf("Unexpected rate %+v", first)
+	}
+	if !first.Date.Equal(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %s", first.Date)
+	}
+}
+
+func TestParseExchangeRatesCSV(t *testing.T) {
+	input := "rate,date,base,quote\n0.8567,2024-01-05,usd,GBP\n"
+
+	rates, err := ParseExchangeRatesCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(rates) != 1 {
+		t.Fatalf("Expected 1 rate, but got %d", len(rates))
+	}
+	if rates[0].Base != "USD" || rates[0].Quote != "GBP" || rates[0].Rate != money.MustParseRate("0.8567") {
+		t.Errorf("Unexpected rate %+v", rates[0])
+	}
+
+	_, err = ParseExchangeRatesCSV(strings.NewReader("date,base,quote,rate\n2024-01-05,EUR,,1.1\n"))
+	if err == nil {
+		t.Errorf("Expected error for missing currency")
+	}
+}
+
+func TestCurrencyConverter(t *testing.T) {
+	rates, err := ParseECBExchangeRates(strings.NewReader(testECBRates))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	converter := newCurrencyConverter(rates[:2])
+
+	tests := []struct {
+		amount   money.Amount
+		from     string
+		to       string
+		expected money.Amount
+	}{
+		{money.FromInt(100), "EUR", "USD", money.MustParseAmount("109.21")},
+		{money.FromInt(100), "USD", "EUR", money.MustParseAmount("91.57")},
+		{money.FromInt(100), "USD", "JPY", money.MustParseAmount("14474.86")},
+		{money.FromInt(100), "JPY", "JPY", money.FromInt(100)},
+	}
+
+	for _, test := range tests {
+		converted, err := converter.convert(test.amount, test.from, test.to)
+		if err != nil {
+			t.Errorf("Unexpected error converting %s to %s: %v", test.from, test.to, err)
+			continue
+		}
+		if converted != test.expected {
+			t.Errorf("Expected %s %s to be %s %s, but got %s", test.amount, test.from, test.expected, test.to, converted)
+		}
+	}
+
+	if _, err := converter.convert(money.FromInt(1), "USD", "CHF"); err == nil {
+		t.Errorf("Expected error for unknown currency")
+	}
+}
@@ -0,0 +1,385 @@
+package logic
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+// Supported export formats. CSV has one row per entry with its account and
+// transaction, JSON holds all records as stored and OFX the transactions of
+// each account as a bank statement.
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+	ExportFormatOFX  = "ofx"
+)
+
+// Number of entries read from the database at a time while exporting
+const exportPageSize = 1000
+
+// Columns of the CSV export. New columns are only ever appended, so
+// spreadsheets built on an export keep working.
+var exportCSVHeader = []string{
+	"entry_id", "effective_at", "account_id", "account_name", "currency", "balance", "budget", "ratio",
+	"description", "transaction_id", "amount", "category", "payee", "note", "recurring_id", "created_at",
+}
+
+// Export is all data of a user, or of one of the user's accounts, in one
+// format. Accounts are read up front, entries and transactions are streamed
+// page by page while writing.
+type Export struct {
+	Format     string
+	Accounts   []*database.Account
+	ExportedAt time.Time
+	// entries returns the next page of entries, newest first, and an empty
+	// page once all entries were returned
+	entries func() ([]*database.MoneyEntry, error)
+	// transactions returns a function like entries for the transactions of
+	// an account, or of all exported accounts without one
+	transactions func(accountID *uuid.UUID) func() ([]*database.Transaction, error)
+	// transactionsByID returns the transactions with the given IDs
+	transactionsByID func(ids []uuid.UUID) ([]*database.Transaction, error)
+	// latest returns the newest entry of an account up to the export, or nil
+	latest func(accountID uuid.UUID) (*database.MoneyEntry, error)
+}
+
+// NewExport prepares the export of the user's data. With an account only the
+// data of that account is exported.
+func NewExport(store database.LedgerStore, userID *uuid.UUID, accountID *uuid.UUID, format string) (*Export, ErrorResponse) {
+	switch format {
+	case ExportFormatCSV, ExportFormatJSON, ExportFormatOFX:
+	default:
+		return nil, ErrorResponse{
+			Message: "Unsupported export format",
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	accounts, errResp := GetAccounts(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	if accountID != nil {
+		account, errResp := GetAccountByID(store, userID, accountID)
+		if errResp.Code != http.StatusOK {
+			return nil, errResp
+		}
+		accounts = []*database.Account{account}
+	}
+	if accounts == nil {
+		accounts = []*database.Account{}
+	}
+
+	query := &database.MoneyQuery{AccountID: accountID, Limit: exportPageSize}
+	done := false
+	export := &Export{
+		Format:     format,
+		Accounts:   accounts,
+		ExportedAt: time.Now(),
+		entries: func() ([]*database.MoneyEntry, error) {
+			if done {
+				return nil, nil
+			}
+			entries, err := store.SelectUserMoneyPageDB(userID, query)
+			if err != nil {
+				return nil, err
+			}
+			if len(entries) < exportPageSize {
+				done = true
+			}
+			if len(entries) > 0 {
+				last := entries[len(entries)-1]
+				query.After = &database.MoneyCursor{EffectiveAt: last.EffectiveAt, CreatedAt: last.CreatedAt, ID: last.ID}
+			}
+			return entries, nil
+		},
+		transactions: func(transactionAccountID *uuid.UUID) func() ([]*database.Transaction, error) {
+			query := &database.TransactionQuery{AccountID: cmp.Or(transactionAccountID, accountID), Limit: exportPageSize}
+			done := false
+			return func() ([]*database.Transaction, error) {
+				if done {
+					return nil, nil
+				}
+				transactions, err := store.SelectUserTransactionsPageDB(userID, query)
+				if err != nil {
+					return nil, err
+				}
+				if len(transactions) < exportPageSize {
+					done = true
+				}
+				if len(transactions) > 0 {
+					last := transactions[len(transactions)-1]
+					query.After = &database.TransactionCursor{Date: last.Date, CreatedAt: last.CreatedAt, ID: last.ID}
+				}
+				return transactions, nil
+			}
+		},
+		transactionsByID: store.SelectTransactionsByIDsDB,
+	}
+	export.latest = func(accountID uuid.UUID) (*database.MoneyEntry, error) {
+		// To is exclusive, entries at the time of the export are included
+		to := export.ExportedAt.Add(time.Microsecond)
+		entries, err := store.SelectUserMoneyPageDB(userID, &database.MoneyQuery{AccountID: &accountID, To: &to, Limit: 1})
+		if err != nil || len(entries) == 0 {
+			return nil, err
+		}
+		return entries[0], nil
+	}
+
+	return export, errResp
+}
+
+func (export *Export) ContentType() string {
+	switch export.Format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	case ExportFormatOFX:
+		return "application/x-ofx"
+	default:
+		return "application/json"
+	}
+}
+
+// FileName is the name the export is downloaded as.
+func (export *Export) FileName() string {
+	return fmt.Sprintf("money_manager_%s.%s", export.ExportedAt.Format("20060102"), export.Format)
+}
+
+// Write writes the export. Entries are read while writing, so an error may
+// occur after part of the export was written already.
+func (export *Export) Write(w io.Writer) error {
+	switch export.Format {
+	case ExportFormatCSV:
+		return export.writeCSV(w)
+	case ExportFormatOFX:
+		return export.writeOFX(w)
+	default:
+		return export.writeJSON(w)
+	}
+}
+
+func (export *Export) writeCSV(w io.Writer) error {
+	accounts := map[uuid.UUID]*database.Account{}
+	for _, account := range export.Accounts {
+		accounts[account.ID] = account
+	}
+
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(exportCSVHeader); err != nil {
+		return err
+	}
+	for {
+		entries, err := export.entries()
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		// Only the transactions of the page's entries are read
+		transactionIDs := []uuid.UUID{}
+		for _, entry := range entries {
+			if entry.TransactionID != nil {
+				transactionIDs = append(transactionIDs, *entry.TransactionID)
+			}
+		}
+		transactions := map[uuid.UUID]*database.Transaction{}
+		if len(transactionIDs) > 0 {
+			page, err := export.transactionsByID(transactionIDs)
+			if err != nil {
+				return err
+			}
+			for _, transaction := range page {
+				transactions[transaction.ID] = transaction
+			}
+		}
+
+		for _, entry := range entries {
+			record := []string{
+				entry.ID.String(), entry.EffectiveAt.UTC().Format(time.RFC3339), entry.AccountID.String(), "", entry.Currency,
+				entry.Balance.String(), entry.Budget.String(), entry.Ratio.String(), entry.Description,
+				"", "", "", "", "", "", entry.CreatedAt,
+			}
+			if account, ok := accounts[entry.AccountID]; ok {
+				record[3] = account.Name
+			}
+			if entry.TransactionID != nil {
+				record[9] = entry.TransactionID.String()
+				if transaction, ok := transactions[*entry.TransactionID]; ok {
+					record[10] = transaction.Amount.String()
+					record[11] = transaction.Category
+					record[12] = transaction.Payee
+					record[13] = transaction.Note
+				}
+			}
+			if entry.RecurringID != nil {
+				record[14] = entry.RecurringID.String()
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+		}
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// writeJSON writes an object with the accounts, transactions and entries.
+// Transactions and entries are encoded one at a time, so neither list is ever
+// held in memory.
+func (export *Export) writeJSON(w io.Writer) error {
+	header := struct {
+		ExportedAt time.Time           `json:"exported_at"`
+		Accounts   []*database.Account `json:"accounts"`
+	}{export.ExportedAt, export.Accounts}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	// Leave the object open to append the transactions and entries
+	if _, err := fmt.Fprintf(w, "%s,\"transactions\":", data[:len(data)-1]); err != nil {
+		return err
+	}
+	if err := writeJSONArray(w, export.transactions(nil)); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, ",\"entries\":"); err != nil {
+		return err
+	}
+	if err := writeJSONArray(w, export.entries); err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+// writeJSONArray writes the items of all pages as a JSON array, reading the
+// next page only after the previous one was written.
+func writeJSONArray[T any](w io.Writer, next func() ([]T, error)) error {
+	encoder := json.NewEncoder(w)
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	for {
+		items, err := next()
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		for _, item := range items {
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// writeOFX writes an OFX 2 statement per account with its transactions and
+// latest balance, which the OFX import reads back. Transactions are read and
+// written a page at a time.
+func (export *Export) writeOFX(w io.Writer) error {
+	// Write errors are kept by the buffer and returned by Flush
+	b := bufio.NewWriter(w)
+	b.WriteString(xml.Header)
+	b.WriteString("<?OFX OFXHEADER=\"200\" VERSION=\"220\" SECURITY=\"NONE\" OLDFILEUID=\"NONE\" NEWFILEUID=\"NONE\"?>\n")
+	b.WriteString("<OFX>\n<SIGNONMSGSRSV1><SONRS>\n")
+	b.WriteString("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	fmt.Fprintf(b, "<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE>\n", ofxDate(export.ExportedAt))
+	b.WriteString("</SONRS></SIGNONMSGSRSV1>\n<BANKMSGSRSV1>\n")
+	for _, account := range export.Accounts {
+		accountID := account.BankAccount
+		if accountID == "" {
+			accountID = account.ID.String()
+		}
+
+		b.WriteString("<STMTTRNRS>\n")
+		fmt.Fprintf(b, "<TRNUID>%s</TRNUID>\n", account.ID)
+		b.WriteString("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+		fmt.Fprintf(b, "<STMTRS>\n<CURDEF>%s</CURDEF>\n", ofxText(account.Currency))
+		fmt.Fprintf(b, "<BANKACCTFROM><BANKID></BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n", ofxText(accountID))
+		b.WriteString("<BANKTRANLIST>\n")
+		transactions := export.transactions(&account.ID)
+		for {
+			page, err := transactions()
+			if err != nil {
+				return err
+			}
+			if len(page) == 0 {
+				break
+			}
+
+			for _, transaction := range page {
+				transactionType := "CREDIT"
+				if transaction.Amount < 0 {
+					transactionType = "DEBIT"
+				}
+				fmt.Fprintf(b, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID>",
+					transactionType, ofxDate(transaction.Date), transaction.Amount, transaction.ID)
+				if transaction.Payee != "" {
+					fmt.Fprintf(b, "<NAME>%s</NAME>", ofxText(transaction.Payee))
+				}
+				if transaction.Note != "" {
+					fmt.Fprintf(b, "<MEMO>%s</MEMO>", ofxText(transaction.Note))
+				}
+				b.WriteString("</STMTTRN>\n")
+			}
+			if err := b.Flush(); err != nil {
+				return err
+			}
+		}
+		b.WriteString("</BANKTRANLIST>\n")
+
+		entry, err := export.latest(account.ID)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			fmt.Fprintf(b, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", entry.Balance, ofxDate(entry.EffectiveAt))
+		}
+		b.WriteString("</STMTRS>\n</STMTTRNRS>\n")
+	}
+	b.WriteString("</BANKMSGSRSV1>\n</OFX>\n")
+
+	return b.Flush()
+}
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:UTC]"
+}
+
+func ofxText(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
@@ -0,0 +1,166 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/importer"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// testExport returns an export of one account with a transaction and a
+// manual entry, whose entries and transactions are returned in pages of one.
+func testExport(format string) (*Export, *database.Transaction) {
+	account := &database.Account{ID: uuid.New(), Name: "Checking", Currency: "EUR", BankAccount: "DE89370400440532013000"}
+	transaction := &database.Transaction{
+		ID:        uuid.New(),
+		Amount:    money.FromInt(-20),
+		Category:  "Food",
+		Payee:     "Bakery & Sons",
+		Date:      time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		AccountID: account.ID,
+	}
+	latest := &database.MoneyEntry{ID: uuid.New(), Balance: money.FromInt(80), Currency: "EUR", EffectiveAt: transaction.Date, AccountID: account.ID, TransactionID: &transaction.ID}
+	entries := []*database.MoneyEntry{
+		latest,
+		{ID: uuid.New(), Balance: money.FromInt(100), Currency: "EUR", EffectiveAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), AccountID: account.ID, Description: "Opening"},
+	}
+
+	return &Export{
+		Format:     format,
+		Accounts:   []*database.Account{account},
+		ExportedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		entries: func() ([]*database.MoneyEntry, error) {
+			if len(entries) == 0 {
+				return nil, nil
+			}
+			page := entries[:1]
+			entries = entries[1:]
+			return page, nil
+		},
+		transactions: func(accountID *uuid.UUID) func() ([]*database.Transaction, error) {
+			transactions := []*database.Transaction{transaction}
+			return func() ([]*database.Transaction, error) {
+				if len(transactions) == 0 {
+					return nil, nil
+				}
+				page := transactions[:1]
+				transactions = transactions[1:]
+				return page, nil
+			}
+		},
+		transactionsByID: func(ids []uuid.UUID) ([]*database.Transaction, error) {
+			if len(ids) != 1 || ids[0] != transaction.ID {
+				return nil, fmt.Errorf("unexpected transaction IDs %v", ids)
+			}
+			return []*database.Transaction{transaction}, nil
+		},
+		latest: func(accountID uuid.UUID) (*database.MoneyEntry, error) {
+			return latest, nil
+		},
+	}, transaction
+}
+
+func TestExport_CSV(t *testing.T) {
+	var buffer bytes.Buffer
+	export, _ := testExport(ExportFormatCSV)
+	if err := export.Write(&buffer); err != nil {
+		t.Fatalf("Expected export to succeed, but got %s", err)
+	}
+
+	records, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, but got %s", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected header and 2 rows, but got %d records", len(records))
+	}
+	for _, record := range records {
+		if len(record) != len(exportCSVHeader) {
+			t.Errorf("Expected %d columns, but got %d", len(exportCSVHeader), len(record))
+		}
+	}
+	if records[1][3] != "Checking" || records[1][5] != "80.00" || records[1][10] != "-20.00" || records[1][12] != "Bakery & Sons" {
+		t.Errorf("Unexpected transaction row %v", records[1])
+	}
+	if records[2][8] != "Opening" || records[2][9] != "" {
+		t.Errorf("Expected manual entry without transaction, but got %v", records[2])
+	}
+}
+
+func TestExport_JSON(t *testing.T) {
+	var buffer bytes.Buffer
+	export, _ := testExport(ExportFormatJSON)
+	if err := export.Write(&buffer); err != nil {
+		t.Fatalf("Expected export to succeed, but got %s", err)
+	}
+
+	var data struct {
+		Accounts     []*database.Account     `json:"accounts"`
+		Transactions []*database.Transaction `json:"transactions"`
+		Entries      []*database.MoneyEntry  `json:"entries"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &data); err != nil {
+		t.Fatalf("Expected valid JSON, but got %s", err)
+	}
+	if len(data.Accounts) != 1 || len(data.Transactions) != 1 || len(data.Entries) != 2 {
+		t.Errorf("Expected 1 account, 1 transaction and 2 entries, but got %d, %d and %d", len(data.Accounts), len(data.Transactions), len(data.Entries))
+	}
+}
+
+func TestExport_JSONEmpty(t *testing.T) {
+	noPages := func() ([]*database.MoneyEntry, error) { return nil, nil }
+	export := &Export{
+		Format:   ExportFormatJSON,
+		Accounts: []*database.Account{},
+		entries:  noPages,
+		transactions: func(accountID *uuid.UUID) func() ([]*database.Transaction, error) {
+			return func() ([]*database.Transaction, error) { return nil, nil }
+		},
+	}
+	var buffer bytes.Buffer
+	if err := export.Write(&buffer); err != nil {
+		t.Fatalf("Expected export to succeed, but got %s", err)
+	}
+
+	output := buffer.String()
+	for _, field := range []string{`"accounts":[]`, `"transactions":[]`, `"entries":[]`} {
+		if !strings.Contains(output, field) {
+			t.Errorf("Expected %s in %s", field, output)
+		}
+	}
+}
+
+func TestExport_OFXRoundTrip(t *testing.T) {
+	export, transaction := testExport(ExportFormatOFX)
+	var buffer bytes.Buffer
+	if err := export.Write(&buffer); err != nil {
+		t.Fatalf("Expected export to succeed, but got %s", err)
+	}
+
+	if !strings.Contains(buffer.String(), "<BALAMT>80.00</BALAMT>") {
+		t.Errorf("Expected the latest balance 80.00 in the statement")
+	}
+
+	statement, err := importer.ParseOFX(&buffer)
+	if err != nil {
+		t.Fatalf("Expected exported OFX to parse, but got %s", err)
+	}
+	if statement.Account != "DE89370400440532013000" || statement.Currency != "EUR" {
+		t.Errorf("Expected account DE89370400440532013000 in EUR, but got %s in %s", statement.Account, statement.Currency)
+	}
+	if len(statement.Lines) != 1 {
+		t.Fatalf("Expected 1 line, but got %d", len(statement.Lines))
+	}
+	line := statement.Lines[0]
+	if line.ID != transaction.ID.String() || line.Amount != money.FromInt(-20) || line.Payee != "Bakery & Sons" || !line.Date.Equal(transaction.Date) {
+		t.Errorf("Unexpected line %+v", line)
+	}
+}
@@ -0,0 +1,218 @@
+package logic
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Number of days forecast when none are requested, and the most we allow
+const (
+	defaultForecastDays = 30
+	maxForecastDays     = 366
+)
+
+// ForecastPoint is the projected balance and budget at the end of a day.
+type ForecastPoint struct {
+	Date    time.Time    `json:"date"`
+	Balance money.Amount `json:"balance"`
+	Budget  money.Amount `json:"budget"`
+}
+
+// Forecast projects the balance and budget of an account forward. The daily
+// inflow and outflow are averaged from the entries that were not booked by a
+// recurring schedule, those are projected from the schedules themselves.
+type Forecast struct {
+	AccountID    uuid.UUID       `json:"account_id"`
+	Currency     string          `json:"currency"`
+	Days         int             `json:"days"`
+	Ratio        money.Rate      `json:"ratio"`
+	DailyInflow  money.Amount    `json:"daily_inflow"`
+	DailyOutflow money.Amount    `json:"daily_outflow"`
+	Balance      money.Amount    `json:"balance"`
+	Budget       money.Amount    `json:"budget"`
+	Points       []ForecastPoint `json:"points"`
+}
+
+// GetForecast projects the balance and budget of the account over the next
+// days. Without an account the user's default account is used.
+func GetForecast(store database.SchedulingStore, actorID *uuid.UUID, accountID *uuid.UUID, days int) (*Forecast, ErrorResponse) {
+	if days == 0 {
+		days = defaultForecastDays
+	}
+	if days < 1 || days > maxForecastDays {
+		return nil, ErrorResponse{
+			Message: fmt.Sprintf("Days must be between 1 and %d", maxForecastDays),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	account, errResp := resolveAccount(store, actorID, accountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	entries, errResp := GetAccountBalances(store, &account.ID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	schedules, err := store.SelectUserRecurringDB(actorID)
+	if err != nil {
+		fmt.Println("Error retrieving recurring schedules:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve recurring schedules",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	accountSchedules := []*database.Recurring{}
+	for _, recurring := range schedules {
+		if recurring.AccountID == account.ID {
+			accountSchedules = append(accountSchedules, recurring)
+		}
+	}
+
+	forecast := calculateForecast(entries, accountSchedules, days, time.Now())
+	forecast.AccountID = account.ID
+	forecast.Currency = account.Currency
+
+	return forecast, errResp
+}
+
+// calculateForecast steps through the next days and books the average inflow
+// and outflow and every recurring occurrence of each day with the same rules
+// as real entries, see calculateBudget. Entries are ordered newest first.
+func calculateForecast(entries []*database.MoneyEntry, schedules []*database.Recurring, days int, now time.Time) *Forecast {
+	current := &database.MoneyEntry{Ratio: defaultRatio}
+	if lastEntry := balanceAt(entries, now); lastEntry != nil {
+		current = lastEntry
+	}
+
+	inflow, outflow, span := historicalFlows(entries, now)
+	forecast := &Forecast{
+		Days:    days,
+		Ratio:   current.Ratio,
+		Balance: current.Balance,
+		Budget:  current.Budget,
+		Points:  []ForecastPoint{},
+	}
+	if span != nil {
+		perDay := new(big.Rat).Inv(span)
+		forecast.DailyInflow = inflow.MulRat(perDay)
+		forecast.DailyOutflow = outflow.MulRat(perDay)
+	}
+
+	occurrences := upcomingOccurrences(schedules, now, now.AddDate(0, 0, days))
+
+	for day := 1; day <= days; day++ {
+		date := now.AddDate(0, 0, day)
+
+		// Book the cumulative average so rounding to cents does not drift
+		if span != nil {
+			elapsed := new(big.Rat).Quo(big.NewRat(int64(day), 1), span)
+			previous := new(big.Rat).Quo(big.NewRat(int64(day-1), 1), span)
+			current = forecastStep(current, inflow.MulRat(elapsed)-inflow.MulRat(previous))
+			current = forecastStep(current, outflow.MulRat(previous)-outflow.MulRat(elapsed))
+		}
+
+		for len(occurrences) > 0 && !occurrences[0].at.After(date) {
+			current = forecastStep(current, occurrences[0].amount)
+			occurrences = occurrences[1:]
+		}
+
+		forecast.Points = append(forecast.Points, ForecastPoint{
+			Date:    date,
+			Balance: current.Balance,
+			Budget:  current.Budget,
+		})
+	}
+
+	forecast.Balance = current.Balance
+	forecast.Budget = current.Budget
+	return forecast
+}
+
+func forecastStep(last *database.MoneyEntry, amount money.Amount) *database.MoneyEntry {
+	if amount == 0 {
+		return last
+	}
+	next := &database.MoneyEntry{
+		Balance: last.Balance + amount,
+		Ratio:   last.Ratio,
+	}
+	next.Budget = calculateBudget(next, last)
+	return next
+}
+
+// historicalFlows sums the increases and decreases of the balance that were
+// not booked by a recurring schedule and returns them with the number of
+// days they span. The span is nil if there is less than a day of history.
+func historicalFlows(entries []*database.MoneyEntry, now time.Time) (money.Amount, money.Amount, *big.Rat) {
+	var inflow, outflow money.Amount
+	var first *database.MoneyEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.EffectiveAt.After(now) {
+			break
+		}
+		if first == nil {
+			first = entry
+			continue
+		}
+		if entry.RecurringID != nil {
+			continue
+		}
+		diff := entry.Balance - entries[i+1].Balance
+		if diff > 0 {
+			inflow += diff
+		} else {
+			outflow -= diff
+		}
+	}
+
+	if first == nil {
+		return 0, 0, nil
+	}
+	hours := int64(now.Sub(first.EffectiveAt) / time.Hour)
+	if hours < 24 {
+		return 0, 0, nil
+	}
+	return inflow, outflow, big.NewRat(hours, 24)
+}
+
+type occurrence struct {
+	at        time.Time
+	amount    money.Amount
+	recurring *database.Recurring
+}
+
+// upcomingOccurrences returns the occurrences of the schedules that are not
+// booked yet up to the given time, ordered by date.
+func upcomingOccurrences(schedules []*database.Recurring, now time.Time, until time.Time) []occurrence {
+	occurrences := []occurrence{}
+	for _, recurring := range schedules {
+		for n := recurring.Occurrence; ; n++ {
+			at := occurrenceAt(recurring, n)
+			if at.After(until) || (recurring.EndDate != nil && at.After(*recurring.EndDate)) {
+				break
+			}
+			// Due occurrences are booked by the scheduler within a minute
+			if at.Before(now) {
+				at = now
+			}
+			occurrences = append(occurrences, occurrence{at: at, amount: recurring.Amount, recurring: recurring})
+		}
+	}
+
+	slices.SortStableFunc(occurrences, func(a, b occurrence) int {
+		return a.at.Compare(b.at)
+	})
+	return occurrences
+}
@@ -0,0 +1,82 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+func TestCalculateForecast(t *testing.T) {
+	ratio := money.MustParseRate("0.5")
+	recurringID := uuid.New()
+	// Entries are ordered newest first
+	entries := []*database.MoneyEntry{
+		{Balance: money.FromInt(1500), Budget: money.FromInt(100), Ratio: ratio, RecurringID: &recurringID, EffectiveAt: time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC)},
+		{Balance: money.FromInt(1300), Budget: money.FromInt(0), Ratio: ratio, EffectiveAt: time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)},
+		{Balance: money.FromInt(1600), Budget: money.FromInt(300), Ratio: ratio, EffectiveAt: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{Balance: money.FromInt(1000), Budget: money.FromInt(0), Ratio: ratio, EffectiveAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	schedules := []*database.Recurring{{
+		ID:        recurringID,
+		Amount:    money.FromInt(200),
+		Frequency: FrequencyMonthly,
+		Interval:  1,
+		StartDate: time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC),
+		// The February occurrence is already booked
+		Occurrence: 1,
+	}}
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	forecast := calculateForecast(entries, schedules, 10, now)
+
+	// 600 in and 300 out over 60 days, the recurring entry is not averaged
+	if forecast.DailyInflow != money.FromInt(10) {
+		t.Errorf("Expected daily inflow %s, but got %s", money.FromInt(10), forecast.DailyInflow)
+	}
+	if forecast.DailyOutflow != money.FromInt(5) {
+		t.Errorf("Expected daily outflow %s, but got %s", money.FromInt(5), forecast.DailyOutflow)
+	}
+	if len(forecast.Points) != 10 {
+		t.Fatalf("Expected 10 forecast points, but got %d", len(forecast.Points))
+	}
+
+	// Half of every inflow goes to the budget and all outflow comes out of it
+	expected := []struct {
+		day     int
+		balance money.Amount
+		budget  money.Amount
+	}{
+		{0, money.FromInt(1505), money.FromInt(100)},
+		{3, money.FromInt(1720), money.FromInt(200)},
+		{9, money.FromInt(1750), money.FromInt(200)},
+	}
+	for _, e := range expected {
+		point := forecast.Points[e.day]
+		if point.Balance != e.balance {
+			t.Errorf("Expected balance %s on day %d, but got %s", e.balance, e.day+1, point.Balance)
+		}
+		if point.Budget != e.budget {
+			t.Errorf("Expected budget %s on day %d, but got %s", e.budget, e.day+1, point.Budget)
+		}
+	}
+	if forecast.Balance != money.FromInt(1750) || forecast.Budget != money.FromInt(200) {
+		t.Errorf("Expected final balance 1750 and budget 200, but got %s and %s", forecast.Balance, forecast.Budget)
+	}
+}
+
+func TestCalculateForecast_NoHistory(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	forecast := calculateForecast(nil, nil, 5, now)
+
+	if forecast.Ratio != defaultRatio {
+		t.Errorf("Expected default ratio %s, but got %s", defaultRatio, forecast.Ratio)
+	}
+	for _, point := range forecast.Points {
+		if point.Balance != 0 || point.Budget != 0 {
+			t.Errorf("Expected empty forecast, but got balance %s and budget %s", point.Balance, point.Budget)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Average number of hours in a month (365.25 * 24 / 12), used to turn time
+// spans into months
+var hoursPerMonth = big.NewRat(1461, 2)
+
+// Goals further away than this many months get no projected completion, a
+// time.Duration only spans about 292 years
+var maxProjectionMonths = big.NewRat(1200, 1)
+
+type GoalForUpdate struct {
+	Name         string       `json:"name"`
+	TargetAmount money.Amount `json:"target_amount"`
+	Deadline     *time.Time   `json:"deadline"`
+	AccountID    uuid.UUID    `json:"account_id"`
+}
+
+// GoalProgress reports how far a savings goal is. All amounts are in the
+// currency of the goal's account. The savings of an account are its balance
+// minus its budget, i.e. the share of income the ratio kept out of the budget.
+type GoalProgress struct {
+	Goal                *database.Goal `json:"goal"`
+	Saved               money.Amount   `json:"saved"`
+	Remaining           money.Amount   `json:"remaining"`
+	Progress            float64        `json:"progress"`
+	MonthlySavings      money.Amount   `json:"monthly_savings"`
+	RequiredMonthly     *money.Amount  `json:"required_monthly"`
+	ProjectedCompletion *time.Time     `json:"projected_completion"`
+	Reached             bool           `json:"reached"`
+}
+
+func CreateGoal(store database.GoalLedgerStore, userID *uuid.UUID, goal *database.Goal) (*database.Goal, ErrorResponse) {
+	account, errResp := resolveAccount(store, userID, &goal.AccountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	goal.AccountID = account.ID
+	goal.UserID = *userID
+	goal.Name = strings.TrimSpace(goal.Name)
+
+	if err := validateGoal(goal); err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	goalID, err := store.InsertGoalDB(goal)
+	if err != nil {
+		fmt.Println("Error inserting goal:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to insert goal",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return GetGoalByID(store, userID, &goalID)
+}
+
+func GetGoals(store database.GoalStore, userID *uuid.UUID) ([]*database.Goal, ErrorResponse) {
+	goals, err := store.SelectUserGoalsDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving goals:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve goals",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return goals, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func GetGoalByID(store database.GoalStore, actorID *uuid.UUID, goalID *uuid.UUID) (*database.Goal, ErrorResponse) {
+	goal, err := store.SelectGoalByIDDB(goalID)
+	if err != nil {
+		fmt.Println("Error retrieving goal:", err)
+		return nil, ErrorResponse{
+			Message: "Goal not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if goal.UserID != *actorID {
+		return nil, ErrorResponse{
+			Message: "Forbidden: cannot access another user's goal",
+			Code:    http.StatusForbidden,
+		}
+	}
+
+	return goal, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func UpdateGoal(store database.GoalLedgerStore, actorID *uuid.UUID, goalID *uuid.UUID, goalForUpdate *GoalForUpdate) (*database.Goal, ErrorResponse) {
+	goal, errResp := GetGoalByID(store, actorID, goalID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	if goalForUpdate.AccountID != uuid.Nil {
+		account, errResp := GetAccountByID(store, actorID, &goalForUpdate.AccountID)
+		if errResp.Code != http.StatusOK {
+			return nil, errResp
+		}
+		goal.AccountID = account.ID
+	}
+	goal.Name = strings.TrimSpace(goalForUpdate.Name)
+	goal.TargetAmount = goalForUpdate.TargetAmount
+	goal.Deadline = goalForUpdate.Deadline
+
+	if err := validateGoal(goal); err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	if err := store.UpdateGoalDB(goal); err != nil {
+		fmt.Println("Error updating goal:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to update goal",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return goal, errResp
+}
+
+func DeleteGoal(store database.GoalStore, actorID *uuid.UUID, goalID *uuid.UUID) ErrorResponse {
+	_, errResp := GetGoalByID(store, actorID, goalID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+
+	if err := store.DeleteGoalDB(goalID); err != nil {
+		fmt.Println("Error deleting goal:", err)
+		return ErrorResponse{
+			Message: "Failed to delete goal",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return errResp
+}
+
+func GetGoalProgress(store database.GoalLedgerStore, actorID *uuid.UUID, goalID *uuid.UUID) (*GoalProgress, ErrorResponse) {
+	goal, errResp := GetGoalByID(store, actorID, goalID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	entries, errResp := GetAccountBalances(store, &goal.AccountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	progress := calculateGoalProgress(goal, entries, time.Now())
+	return &progress, errResp
+}
+
+func GetAllGoalProgress(store database.GoalLedgerStore, userID *uuid.UUID) ([]GoalProgress, ErrorResponse) {
+	goals, errResp := GetGoals(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	// Goals often share an account, only load each chain once
+	chains := map[uuid.UUID][]*database.MoneyEntry{}
+	now := time.Now()
+	progress := []GoalProgress{}
+	for _, goal := range goals {
+		entries, ok := chains[goal.AccountID]
+		if !ok {
+			entries, errResp = GetAccountBalances(store, &goal.AccountID)
+			if errResp.Code != http.StatusOK {
+				return nil, errResp
+			}
+			chains[goal.AccountID] = entries
+		}
+		progress = append(progress, calculateGoalProgress(goal, entries, now))
+	}
+
+	return progress, errResp
+}
+
+func validateGoal(goal *database.Goal) error {
+	if goal.Name == "" {
+		return errors.New("goal name is required")
+	}
+	if goal.TargetAmount <= 0 {
+		return errors.New("target amount must be positive")
+	}
+	return nil
+}
+
+// calculateGoalProgress compares the savings of the goal's account with its
+// target. The monthly savings rate is the part of every balance increase the
+// entry's ratio kept out of the budget, averaged from the first entry until
+// now. Entries are ordered newest first.
+func calculateGoalProgress(goal *database.Goal, entries []*database.MoneyEntry, now time.Time) GoalProgress {
+	progress := GoalProgress{Goal: goal}
+	if len(entries) > 0 {
+		progress.Saved = entries[0].Balance - entries[0].Budget
+	}
+
+	progress.Remaining = max(goal.TargetAmount-progress.Saved, 0)
+	progress.Progress = min(max(progress.Saved.Float64()/goal.TargetAmount.Float64(), 0), 1)
+	progress.MonthlySavings = averageMonthlySavings(entries, now)
+
+	if progress.Remaining == 0 {
+		progress.Reached = true
+		return progress
+	}
+
+	if goal.Deadline != nil {
+		required := progress.Remaining
+		if months := monthsBetween(now, *goal.Deadline); months != nil && months.Cmp(big.NewRat(1, 1)) > 0 {
+			required = progress.Remaining.MulRat(new(big.Rat).Inv(months))
+		}
+		progress.RequiredMonthly = &required
+	}
+
+	if progress.MonthlySavings > 0 {
+		months := new(big.Rat).SetFrac64(int64(progress.Remaining), int64(progress.MonthlySavings))
+		if months.Cmp(maxProjectionMonths) > 0 {
+			return progress
+		}
+		hours, _ := new(big.Rat).Mul(months, hoursPerMonth).Float64()
+		completion := now.Add(time.Duration(hours * float64(time.Hour)))
+		progress.ProjectedCompletion = &completion
+	}
+
+	return progress
+}
+
+// averageMonthlySavings returns the savings added per month since the first
+// entry. Decreases are taken from the budget by calculateBudget, so only the
+// ratio's complement of every increase ends up in savings.
+func averageMonthlySavings(entries []*database.MoneyEntry, now time.Time) money.Amount {
+	if len(entries) < 2 {
+		return 0
+	}
+
+	var saved money.Amount
+	for i := len(entries) - 2; i >= 0; i-- {
+		diff := entries[i].Balance - entries[i+1].Balance
+		if diff > 0 {
+			saved += diff - diff.MulRate(entries[i].Ratio)
+		}
+	}
+
+	months := monthsBetween(entries[len(entries)-1].EffectiveAt, now)
+	if months == nil || months.Cmp(big.NewRat(1, 30)) < 0 {
+		return 0
+	}
+	return saved.MulRat(new(big.Rat).Inv(months))
+}
+
+// monthsBetween returns the number of average months from start to end, or
+// nil if end is not after start.
+func monthsBetween(start time.Time, end time.Time) *big.Rat {
+	hours := int64(end.Sub(start) / time.Hour)
+	if hours <= 0 {
+		return nil
+	}
+	return new(big.Rat).Quo(big.NewRat(hours, 1), hoursPerMonth)
+}
@@ -0,0 +1,106 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+)
+
+func TestCalculateGoalProgress(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	month := 2922 * time.Hour / 4
+	now := start.Add(4 * month)
+
+	entries := []*database.MoneyEntry{
+		{
+			Balance:     money.FromInt(2200),
+			Budget:      money.FromInt(400),
+			Ratio:       money.MustParseRate("0.25"),
+			EffectiveAt: start.Add(3 * month),
+		},
+		{
+			Balance:     money.FromInt(1800),
+			Budget:      money.FromInt(300),
+			Ratio:       money.MustParseRate("0.5"),
+			EffectiveAt: start.Add(2 * month),
+		},
+		{
+			Balance:     money.FromInt(2000),
+			Budget:      money.FromInt(500),
+			Ratio:       money.MustParseRate("0.5"),
+			EffectiveAt: start.Add(month),
+		},
+		{
+			Balance:     money.FromInt(1000),
+			Budget:      money.FromInt(0),
+			Ratio:       money.MustParseRate("0.5"),
+			EffectiveAt: start,
+		},
+	}
+
+	deadline := now.Add(4 * month)
+	goal := &database.Goal{
+		Name:         "Holiday",
+		TargetAmount: money.FromInt(3000),
+		Deadline:     &deadline,
+	}
+
+	progress := calculateGoalProgress(goal, entries, now)
+
+	if progress.Saved != money.FromInt(1800) {
+		t.Errorf("Expected saved %s, but got %s", money.FromInt(1800), progress.Saved)
+	}
+	if progress.Remaining != money.FromInt(1200) {
+		t.Errorf("Expected remaining %s, but got %s", money.FromInt(1200), progress.Remaining)
+	}
+	if progress.MonthlySavings != money.FromInt(200) {
+		t.Errorf("Expected monthly savings %s, but got %s", money.FromInt(200), progress.MonthlySavings)
+	}
+	if progress.RequiredMonthly == nil || *progress.RequiredMonthly != money.FromInt(300) {
+		t.Errorf("Expected required monthly %s, but got %v", money.FromInt(300), progress.RequiredMonthly)
+	}
+	expectedCompletion := now.Add(6 * month)
+	if progress.ProjectedCompletion == nil || !progress.ProjectedCompletion.Equal(expectedCompletion) {
+		t.Errorf("Expected projected completion %s, but got %v", expectedCompletion, progress.ProjectedCompletion)
+	}
+	if progress.Reached {
+		t.Errorf("Expected goal not to be reached")
+	}
+}
+
+func TestCalculateGoalProgress_Reached(t *testing.T) {
+	entries := []*database.MoneyEntry{
+		{Balance: money.FromInt(5000), Budget: money.FromInt(1000), EffectiveAt: time.Now()},
+	}
+	goal := &database.Goal{Name: "Bike", TargetAmount: money.FromInt(2000)}
+
+	progress := calculateGoalProgress(goal, entries, time.Now())
+
+	if !progress.Reached || progress.Remaining != 0 || progress.Progress != 1 {
+		t.Errorf("Expected goal to be reached, but got %+v", progress)
+	}
+	if progress.ProjectedCompletion != nil || progress.RequiredMonthly != nil {
+		t.Errorf("Expected no projection for a reached goal")
+	}
+}
+
+func TestCalculateGoalProgress_FarProjection(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start.AddDate(1, 0, 0)
+	entries := []*database.MoneyEntry{
+		{Balance: money.FromInt(20), Budget: money.FromInt(0), Ratio: money.MustParseRate("0"), EffectiveAt: start.AddDate(0, 6, 0)},
+		{Balance: money.FromInt(10), Budget: money.FromInt(0), Ratio: money.MustParseRate("0"), EffectiveAt: start},
+	}
+	goal := &database.Goal{Name: "House", TargetAmount: money.FromInt(1000000000)}
+
+	progress := calculateGoalProgress(goal, entries, now)
+
+	if progress.MonthlySavings <= 0 {
+		t.Fatalf("Expected positive monthly savings, but got %s", progress.MonthlySavings)
+	}
+	if progress.ProjectedCompletion != nil {
+		t.Errorf("Expected no projected completion centuries away, but got %s", progress.ProjectedCompletion)
+	}
+}
@@ -0,0 +1,625 @@
+package logic
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/importer"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Supported statement formats. QFX is the OFX dialect of Quicken, camt.053
+// and MT940 are the end of day statements of European banks.
+const (
+	StatementFormatCSV     = "csv"
+	StatementFormatOFX     = "ofx"
+	StatementFormatQFX     = "qfx"
+	StatementFormatQIF     = "qif"
+	StatementFormatCamt053 = "camt053"
+	StatementFormatMT940   = "mt940"
+)
+
+// Most rows a single statement may have
+const maxImportRows = 10000
+
+// Longest description, category and payee stored for an imported row
+const (
+	maxImportDescriptionLength = 255
+	maxImportCategoryLength    = 50
+	maxImportPayeeLength       = 100
+)
+
+// Date layouts tried when a CSV mapping has no date format
+var importDateLayouts = []string{time.DateOnly, "02.01.2006", "2006/01/02", time.RFC3339}
+
+// CSVMapping names the columns of a bank's CSV export. Date and one of Amount
+// or Balance are required. DateFormat is written with YYYY, MM and DD, e.g.
+// DD.MM.YYYY. With DecimalComma amounts are read like 1.234,56.
+type CSVMapping struct {
+	Date         string
+	Amount       string
+	Balance      string
+	Description  string
+	DateFormat   string
+	Delimiter    rune
+	DecimalComma bool
+}
+
+// ImportOptions selects the account a statement is imported into and how it
+// is read. Without an account, the statement is matched to an account by its
+// bank account number. Mapping is used for CSV and DayFirst for QIF, which
+// has no standard date order. With Preview nothing is stored.
+type ImportOptions struct {
+	AccountID uuid.UUID
+	Format    string
+	Mapping   CSVMapping
+	DayFirst  bool
+	Preview   bool
+}
+
+// ImportRow is a single statement line. Amount is the change of the balance
+// and Balance the balance after it, at least one of them is set. Reference
+// identifies the line at the bank if the statement format has one.
+type ImportRow struct {
+	Line        int           `json:"line"`
+	Date        time.Time     `json:"date"`
+	Amount      *money.Amount `json:"amount"`
+	Balance     *money.Amount `json:"balance"`
+	Description string        `json:"description"`
+	Payee       string        `json:"payee"`
+	Category    string        `json:"category"`
+	Reference   string        `json:"reference"`
+}
+
+// ImportedRow is a statement line with the entry it results in and, for
+// lines with an amount, the transaction. Duplicates are lines that already
+// have an entry and are skipped. The line's fields are embedded, so line stays
+// at the top level of the JSON.
+type ImportedRow struct {
+	ImportRow
+	Entry       *database.MoneyEntry  `json:"entry"`
+	Transaction *database.Transaction `json:"transaction"`
+	Duplicate   bool                  `json:"duplicate"`
+}
+
+type ImportResult struct {
+	AccountID  uuid.UUID     `json:"account_id"`
+	Preview    bool          `json:"preview"`
+	Imported   int           `json:"imported"`
+	Duplicates int           `json:"duplicates"`
+	Rows       []ImportedRow `json:"rows"`
+}
+
+// ImportStatement reads a bank statement and books its lines on the account
+// in a single transaction. Lines are deduplicated against the account's
+// entries, so importing overlapping statements is safe.
+func ImportStatement(store database.LedgerStore, userID *uuid.UUID, options *ImportOptions, reader io.Reader) (*ImportResult, ErrorResponse) {
+	var rows []ImportRow
+	var statement *importer.Statement
+	var err error
+	switch options.Format {
+	case StatementFormatCSV:
+		rows, err = ParseStatementCSV(reader, &options.Mapping)
+	case StatementFormatOFX, StatementFormatQFX:
+		statement, err = importer.ParseOFX(reader)
+	case StatementFormatQIF:
+		statement, err = importer.ParseQIF(reader, options.DayFirst)
+	case StatementFormatCamt053:
+		statement, err = importer.ParseCamt053(reader)
+	case StatementFormatMT940:
+		statement, err = importer.ParseMT940(reader)
+	default:
+		return nil, ErrorResponse{
+			Message: "Unsupported statement format",
+			Code:    http.StatusBadRequest,
+		}
+	}
+	if err != nil {
+		fmt.Println("Error parsing statement:", err)
+		return nil, ErrorResponse{
+			Message: "Invalid statement: " + err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	accountID := options.AccountID
+	if statement != nil {
+		rows = statementRows(statement)
+
+		account, errResp := statementAccount(store, userID, &accountID, statement)
+		if errResp.Code != http.StatusOK {
+			return nil, errResp
+		}
+		accountID = account.ID
+	}
+
+	return ImportBalances(store, userID, &accountID, rows, options.Preview)
+}
+
+// statementAccount returns the account a parsed statement is booked on, the
+// given one or else the account with the statement's bank account number.
+// The statement's currency has to match the account's.
+func statementAccount(store database.AccountStore, userID *uuid.UUID, accountID *uuid.UUID, statement *importer.Statement) (*database.Account, ErrorResponse) {
+	var account *database.Account
+	errResp := ErrorResponse{Message: "", Code: http.StatusOK}
+	if *accountID == uuid.Nil && statement.Account != "" {
+		account, errResp = matchAccount(store, userID, statement.Account)
+		if errResp.Code != http.StatusOK {
+			return nil, errResp
+		}
+	}
+	if account == nil {
+		account, errResp = resolveAccount(store, userID, accountID)
+		if errResp.Code != http.StatusOK {
+			return nil, errResp
+		}
+	}
+
+	if statement.Currency != "" && !strings.EqualFold(statement.Currency, account.Currency) {
+		return nil, ErrorResponse{
+			Message: fmt.Sprintf("Statement currency %s does not match account currency %s", statement.Currency, account.Currency),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	return account, errResp
+}
+
+// statementRows turns the lines of a parsed statement into import rows. The
+// bank's ID of a line becomes its reference for duplicate detection. The
+// opening and closing balances become balance rows before and after the
+// lines, so the chain matches the bank's balances even if lines are missing.
+func statementRows(statement *importer.Statement) []ImportRow {
+	rows := []ImportRow{}
+	if opening := statement.Opening; opening != nil {
+		row := ImportRow{Date: opening.Date, Balance: &opening.Amount, Description: "Opening balance"}
+		for _, line := range statement.Lines {
+			if line.Date.Before(row.Date) {
+				row.Date = line.Date
+			}
+		}
+		rows = append(rows, row)
+	}
+	for i, line := range statement.Lines {
+		amount := line.Amount
+		rows = append(rows, ImportRow{
+			Line:        i + 1,
+			Date:        line.Date,
+			Amount:      &amount,
+			Description: line.Memo,
+			Payee:       line.Payee,
+			Category:    line.Category,
+			Reference:   line.ID,
+		})
+	}
+	if closing := statement.Closing; closing != nil {
+		// Balances are dated by day, lines may have a time
+		row := ImportRow{Date: closing.Date, Balance: &closing.Amount, Description: "Closing balance"}
+		for _, line := range statement.Lines {
+			if line.Date.After(row.Date) {
+				row.Date = line.Date
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// ImportBalances books the rows on the account, or only computes the entries
+// they would result in when preview is set.
+func ImportBalances(store database.LedgerStore, userID *uuid.UUID, accountID *uuid.UUID, rows []ImportRow, preview bool) (*ImportResult, ErrorResponse) {
+	if len(rows) == 0 {
+		return nil, ErrorResponse{
+			Message: "Statement has no rows",
+			Code:    http.StatusBadRequest,
+		}
+	}
+	if len(rows) > maxImportRows {
+		return nil, ErrorResponse{
+			Message: fmt.Sprintf("Statement has more than %d rows", maxImportRows),
+			Code:    http.StatusBadRequest,
+		}
+	}
+	truncateImportRows(rows)
+
+	account, errResp := resolveAccount(store, userID, accountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	var previous *database.MoneyEntry
+	var imported []ImportedRow
+	var change *database.LedgerChange
+	plan := func(entries []*database.MoneyEntry) (*database.LedgerChange, error) {
+		previous = newestEntry(entries)
+		imported, change = importChange(account, userID, entries, rows)
+		return change, nil
+	}
+
+	if preview {
+		entries, errResp := GetAccountBalances(store, &account.ID)
+		if errResp.Code != http.StatusOK {
+			return nil, errResp
+		}
+		plan(entries)
+	} else if err := store.UpdateChainDB(&account.ID, plan); err != nil {
+		fmt.Println("Error importing balances:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to import balances",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	newEntries := change.NewEntries
+	result := &ImportResult{
+		AccountID:  account.ID,
+		Preview:    preview,
+		Imported:   len(newEntries),
+		Duplicates: len(imported) - len(newEntries),
+		Rows:       imported,
+	}
+	if preview || len(newEntries) == 0 {
+		return result, errResp
+	}
+
+	// One event for the whole import, with the newest imported entry and the
+	// chain as stored
+	chain, chainErrResp := GetAccountBalances(store, &account.ID)
+	if chainErrResp.Code == http.StatusOK {
+		publishBalanceEvent(&BalanceEvent{
+			Type:      BalanceCreated,
+			UserID:    *userID,
+			AccountID: account.ID,
+			Entry:     newestImportedEntry(chain, newEntries),
+			Previous:  previous,
+			Latest:    newestEntry(chain),
+			Entries:   chain,
+			At:        time.Now(),
+		})
+	}
+
+	return result, errResp
+}
+
+// importChange plans the import of the rows into the account's chain and
+// returns the rows with their entries and the change to write. Rows with an
+// amount are recorded as transactions as well.
+func importChange(account *database.Account, userID *uuid.UUID, entries []*database.MoneyEntry, rows []ImportRow) ([]ImportedRow, *database.LedgerChange) {
+	imported, newEntries, entriesToUpdate := planImport(entries, rows)
+	change := &database.LedgerChange{NewEntries: newEntries, UpdatedEntries: entriesToUpdate}
+	for i := range imported {
+		if imported[i].Duplicate {
+			continue
+		}
+		entry := imported[i].Entry
+		entry.AccountID = account.ID
+		entry.Currency = account.Currency
+		entry.UserID = *userID
+
+		row := imported[i].ImportRow
+		if row.Amount == nil {
+			continue
+		}
+		transaction := &database.Transaction{
+			ID:        uuid.New(),
+			Amount:    *row.Amount,
+			Category:  row.Category,
+			Payee:     row.Payee,
+			Note:      row.Description,
+			Date:      entry.EffectiveAt,
+			UserID:    *userID,
+			AccountID: account.ID,
+		}
+		entry.TransactionID = &transaction.ID
+		imported[i].Transaction = transaction
+		change.NewTransactions = append(change.NewTransactions, transaction)
+	}
+	return imported, change
+}
+
+// newestImportedEntry returns the stored entry of the newest imported entry.
+// The new entries are ordered oldest first.
+func newestImportedEntry(chain []*database.MoneyEntry, newEntries []*database.MoneyEntry) *database.MoneyEntry {
+	newest := newEntries[len(newEntries)-1]
+	for _, entry := range chain {
+		if entry.ID == newest.ID {
+			return entry
+		}
+	}
+	return newest
+}
+
+// truncateImportRows cuts long memos, categories and payees, which some
+// banks put into statements, to the length of their columns.
+func truncateImportRows(rows []ImportRow) {
+	for i := range rows {
+		rows[i].Description = truncate(rows[i].Description, maxImportDescriptionLength)
+		rows[i].Category = truncate(rows[i].Category, maxImportCategoryLength)
+		rows[i].Payee = truncate(rows[i].Payee, maxImportPayeeLength)
+	}
+}
+
+// planImport places the rows into the chain oldest first and returns them with
+// their entries, the entries to insert and the existing entries whose budgets
+// changed. A row is a duplicate if its reference was imported before, if an
+// entry that was not imported has the same balance on the same day, or if it
+// only has a balance and the chain already has that balance. Entries are
+// ordered newest first.
+func planImport(entries []*database.MoneyEntry, rows []ImportRow) ([]ImportedRow, []*database.MoneyEntry, []*database.MoneyEntry) {
+	rows = sortImportRows(rows)
+	chain := slices.Clone(entries)
+
+	refs := map[string]bool{}
+	for _, entry := range entries {
+		if entry.ImportRef != nil {
+			refs[*entry.ImportRef] = true
+		}
+	}
+
+	imported := []ImportedRow{}
+	newEntries := []*database.MoneyEntry{}
+	updated := map[uuid.UUID]bool{}
+	seen := map[string]int{}
+	var lastDate time.Time
+	sameDay := 0
+	for _, row := range rows {
+		key := importKey(&row)
+		seen[key]++
+		ref := importRef(key, seen[key])
+
+		// Rows of the same day are spaced a second apart to keep their order
+		if row.Date.Equal(lastDate) {
+			sameDay++
+		} else {
+			lastDate = row.Date
+			sameDay = 0
+		}
+		at := row.Date.Add(time.Duration(sameDay) * time.Second)
+
+		var entry database.MoneyEntry
+		if row.Balance != nil {
+			entry = deltaEntry(chain, 0, at)
+			entry.Balance = *row.Balance
+		} else {
+			entry = deltaEntry(chain, *row.Amount, at)
+		}
+		entry.Description = cmp.Or(row.Description, row.Payee)
+		entry.ImportRef = &ref
+
+		unchanged := false
+		if row.Amount == nil {
+			last := balanceAt(chain, at)
+			unchanged = last != nil && last.Balance == entry.Balance
+		}
+
+		if refs[ref] || unchanged || hasManualEntry(entries, &entry) {
+			imported = append(imported, ImportedRow{ImportRow: row, Entry: &entry, Duplicate: true})
+			continue
+		}
+
+		var laterEntries []*database.MoneyEntry
+		if row.Amount != nil && row.Balance == nil {
+			chain, laterEntries = insertDeltaEntry(chain, &entry, *row.Amount)
+		} else {
+			chain, laterEntries = insertBalanceEntry(chain, &entry)
+		}
+		for _, laterEntry := range laterEntries {
+			if laterEntry.ID != uuid.Nil {
+				updated[laterEntry.ID] = true
+			}
+		}
+		refs[ref] = true
+		newEntries = append(newEntries, &entry)
+		imported = append(imported, ImportedRow{ImportRow: row, Entry: &entry})
+	}
+
+	entriesToUpdate := []*database.MoneyEntry{}
+	for _, entry := range chain {
+		if updated[entry.ID] {
+			entriesToUpdate = append(entriesToUpdate, entry)
+		}
+	}
+
+	return imported, newEntries, entriesToUpdate
+}
+
+// sortImportRows orders the rows oldest first. Banks often list the newest
+// line first, so a statement that runs backwards is reversed before sorting
+// to keep the order of lines on the same day.
+func sortImportRows(rows []ImportRow) []ImportRow {
+	rows = slices.Clone(rows)
+	if rows[0].Date.After(rows[len(rows)-1].Date) {
+		slices.Reverse(rows)
+	}
+	slices.SortStableFunc(rows, func(a, b ImportRow) int {
+		return a.Date.Compare(b.Date)
+	})
+	return rows
+}
+
+func hasManualEntry(entries []*database.MoneyEntry, entry *database.MoneyEntry) bool {
+	year, month, day := entry.EffectiveAt.Date()
+	for _, existing := range entries {
+		if existing.ImportRef != nil || existing.Balance != entry.Balance {
+			continue
+		}
+		if y, m, d := existing.EffectiveAt.In(entry.EffectiveAt.Location()).Date(); y == year && m == month && d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func importKey(row *ImportRow) string {
+	if row.Reference != "" {
+		return "ref|" + row.Reference
+	}
+	key := []string{row.Date.Format(time.RFC3339), "", "", row.Description}
+	if row.Amount != nil {
+		key[1] = row.Amount.String()
+	}
+	if row.Balance != nil {
+		key[2] = row.Balance.String()
+	}
+	if row.Payee != "" {
+		key = append(key, row.Payee)
+	}
+	return strings.Join(key, "|")
+}
+
+// importRef identifies the n-th line with the given key. Counting identical
+// lines lets a statement contain the same payment twice on one day.
+func importRef(key string, n int) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%d", key, n))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseStatementCSV reads a CSV export with a header line using the column
+// mapping. Empty lines are skipped.
+func ParseStatementCSV(reader io.Reader, mapping *CSVMapping) ([]ImportRow, error) {
+	if mapping.Date == "" {
+		return nil, errors.New("date column is required")
+	}
+	if mapping.Amount == "" && mapping.Balance == "" {
+		return nil, errors.New("amount or balance column is required")
+	}
+
+	csvReader := csv.NewReader(reader)
+	if mapping.Delimiter != 0 {
+		csvReader.Comma = mapping.Delimiter
+	}
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+	column := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		index, ok := columns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return -1, fmt.Errorf("column %q not found", name)
+		}
+		return index, nil
+	}
+
+	var indices [4]int
+	for i, name := range []string{mapping.Date, mapping.Amount, mapping.Balance, mapping.Description} {
+		if indices[i], err = column(name); err != nil {
+			return nil, err
+		}
+	}
+	dateIndex, amountIndex, balanceIndex, descriptionIndex := indices[0], indices[1], indices[2], indices[3]
+
+	layouts := importDateLayouts
+	if mapping.DateFormat != "" {
+		layouts = []string{dateLayout(mapping.DateFormat)}
+	}
+
+	rows := []ImportRow{}
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		line, _ := csvReader.FieldPos(0)
+		field := func(index int) string {
+			if index < 0 || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		row := ImportRow{Line: line, Description: field(descriptionIndex)}
+		if row.Date, err = parseImportDate(field(dateIndex), layouts); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if amountIndex >= 0 && field(amountIndex) != "" {
+			amount, err := parseImportAmount(field(amountIndex), mapping.DecimalComma)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			row.Amount = &amount
+		}
+		if balanceIndex >= 0 && field(balanceIndex) != "" {
+			balance, err := parseImportAmount(field(balanceIndex), mapping.DecimalComma)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			row.Balance = &balance
+		}
+		if row.Amount == nil && row.Balance == nil {
+			return nil, fmt.Errorf("line %d: amount or balance is required", line)
+		}
+
+		rows = append(rows, row)
+		if len(rows) > maxImportRows {
+			return nil, fmt.Errorf("more than %d rows", maxImportRows)
+		}
+	}
+
+	return rows, nil
+}
+
+// dateLayout turns a date format like DD.MM.YYYY into a Go time layout.
+func dateLayout(format string) string {
+	return strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02").Replace(strings.ToUpper(format))
+}
+
+func parseImportDate(value string, layouts []string) (time.Time, error) {
+	for _, layout := range layouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseImportAmount reads amounts as banks write them, with thousands
+// separators and a trailing minus sign.
+func parseImportAmount(value string, decimalComma bool) (money.Amount, error) {
+	normalized := strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(value)
+	if decimalComma {
+		normalized = strings.ReplaceAll(normalized, ".", "")
+		normalized = strings.ReplaceAll(normalized, ",", ".")
+	} else {
+		normalized = strings.ReplaceAll(normalized, ",", "")
+	}
+	if trimmed, ok := strings.CutSuffix(normalized, "-"); ok {
+		normalized = "-" + trimmed
+	}
+
+	amount, err := money.ParseAmount(normalized)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return amount, nil
+}
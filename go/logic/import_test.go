@@ -0,0 +1,207 @@
+package logic
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/importer"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+func TestParseStatementCSV(t *testing.T) {
+	statement := "Buchungstag;Verwendungszweck;Betrag\n" +
+		"03.01.2024;Supermarkt;-1.234,56\n" +
+		"\n" +
+		"02.01.2024;Gehalt;2.500,00\n"
+	mapping := &CSVMapping{
+		Date:         "Buchungstag",
+		Amount:       "betrag",
+		Description:  "Verwendungszweck",
+		DateFormat:   "DD.MM.YYYY",
+		Delimiter:    ';',
+		DecimalComma: true,
+	}
+
+	rows, err := ParseStatementCSV(strings.NewReader(statement), mapping)
+	if err != nil {
+		t.Fatalf("Expected statement to parse, but got %s", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, but got %d", len(rows))
+	}
+	if *rows[0].Amount != money.MustParseAmount("-1234.56") {
+		t.Errorf("Expected amount -1234.56, but got %s", rows[0].Amount)
+	}
+	if !rows[1].Date.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) || rows[1].Line != 4 {
+		t.Errorf("Expected second row on 2024-01-02 in line 4, but got %s in line %d", rows[1].Date, rows[1].Line)
+	}
+
+	mapping.Amount = "Saldo"
+	if _, err := ParseStatementCSV(strings.NewReader(statement), mapping); err == nil {
+		t.Errorf("Expected missing column to be rejected")
+	}
+}
+
+func TestPlanImport_Deduplicates(t *testing.T) {
+	ratio := money.MustParseRate("0.5")
+	ref := importRef(importKey(&ImportRow{
+		Date:    time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		Amount:  amountPtr(money.FromInt(-100)),
+		Balance: amountPtr(money.FromInt(1100)),
+	}), 1)
+	laterID := uuid.New()
+	// Entries are ordered newest first
+	entries := []*database.MoneyEntry{
+		{ID: laterID, Balance: money.FromInt(1300), Budget: money.FromInt(150), Ratio: ratio, EffectiveAt: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), Balance: money.FromInt(1100), Budget: money.FromInt(50), Ratio: ratio, ImportRef: &ref, EffectiveAt: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), Balance: money.FromInt(1200), Budget: money.FromInt(100), Ratio: ratio, EffectiveAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	// Newest first, as many banks export them
+	rows := []ImportRow{
+		{Line: 4, Date: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), Amount: amountPtr(money.FromInt(200)), Balance: amountPtr(money.FromInt(1300))},
+		{Line: 3, Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Amount: amountPtr(money.FromInt(-100)), Balance: amountPtr(money.FromInt(1100))},
+		{Line: 2, Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Amount: amountPtr(money.FromInt(200)), Balance: amountPtr(money.FromInt(1200))},
+	}
+
+	imported, newEntries, entriesToUpdate := planImport(entries, rows)
+
+	// Line 2 matches a manual entry of the same day, line 3 was imported before
+	for i, duplicate := range []bool{true, true, false} {
+		if imported[i].Duplicate != duplicate {
+			t.Errorf("Expected duplicate %t for line %d, but got %t", duplicate, imported[i].Line, imported[i].Duplicate)
+		}
+	}
+	if len(newEntries) != 1 || newEntries[0].Balance != money.FromInt(1300) {
+		t.Fatalf("Expected one new entry with balance 1300, but got %d entries", len(newEntries))
+	}
+	if newEntries[0].Budget != money.FromInt(150) {
+		t.Errorf("Expected budget %s, but got %s", money.FromInt(150), newEntries[0].Budget)
+	}
+	// The later entry no longer changes the balance
+	if len(entriesToUpdate) != 1 || entriesToUpdate[0].ID != laterID || entriesToUpdate[0].Budget != money.FromInt(150) {
+		t.Errorf("Expected the later entry to be updated to budget 150")
+	}
+}
+
+func TestPlanImport_BackdatedAmount(t *testing.T) {
+	ratio := money.MustParseRate("0.5")
+	laterID := uuid.New()
+	entries := []*database.MoneyEntry{
+		{ID: laterID, Balance: money.FromInt(900), Budget: money.FromInt(-100), Ratio: ratio, EffectiveAt: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), Balance: money.FromInt(1000), Budget: money.FromInt(0), Ratio: ratio, EffectiveAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	rows := []ImportRow{
+		{Line: 2, Date: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), Amount: amountPtr(money.FromInt(-50))},
+	}
+
+	_, newEntries, entriesToUpdate := planImport(entries, rows)
+
+	if len(newEntries) != 1 || newEntries[0].Balance != money.FromInt(950) {
+		t.Fatalf("Expected one new entry with balance 950, but got %d entries", len(newEntries))
+	}
+	// The later snapshot did not include the booking yet
+	if len(entriesToUpdate) != 1 || entriesToUpdate[0].ID != laterID || entriesToUpdate[0].Balance != money.FromInt(850) {
+		t.Errorf("Expected the later entry to be shifted to 850")
+	}
+}
+
+func TestTruncateImportRows(t *testing.T) {
+	rows := []ImportRow{
+		{Description: strings.Repeat("ü", 300), Category: strings.Repeat("c", 60), Payee: strings.Repeat("p", 120)},
+		{Description: "Rent", Category: "Housing", Payee: "Landlord"},
+	}
+
+	truncateImportRows(rows)
+
+	if rows[0].Description != strings.Repeat("ü", maxImportDescriptionLength) {
+		t.Errorf("Expected description to be cut to %d characters, but got %d", maxImportDescriptionLength, len([]rune(rows[0].Description)))
+	}
+	if rows[0].Category != strings.Repeat("c", maxImportCategoryLength) || rows[0].Payee != strings.Repeat("p", maxImportPayeeLength) {
+		t.Errorf("Expected category and payee to be cut to their column lengths")
+	}
+	if rows[1].Description != "Rent" || rows[1].Category != "Housing" || rows[1].Payee != "Landlord" {
+		t.Errorf("Expected short values to be kept, but got %+v", rows[1])
+	}
+}
+
+func TestImportedRow_JSON(t *testing.T) {
+	data, err := json.Marshal(ImportedRow{ImportRow: ImportRow{Line: 3, Payee: "Landlord"}, Duplicate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	json.Unmarshal(data, &fields)
+	if fields["line"] != float64(3) || fields["payee"] != "Landlord" || fields["duplicate"] != true {
+		t.Errorf("Expected line, payee and duplicate at the top level, but got %s", data)
+	}
+}
+
+func TestPlanImport_StatementReferences(t *testing.T) {
+	statement := &importer.Statement{Lines: []importer.Line{
+		{ID: "FIT-1", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: money.FromInt(500), Payee: "Employer"},
+		{ID: "FIT-2", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: money.FromInt(-20), Payee: "Bakery"},
+	}}
+
+	_, firstImport, _ := planImport(nil, statementRows(statement))
+	if len(firstImport) != 2 {
+		t.Fatalf("Expected 2 new entries, but got %d", len(firstImport))
+	}
+	if firstImport[1].Balance != money.FromInt(480) || firstImport[1].Description != "Bakery" {
+		t.Errorf("Expected second entry with balance 480 from Bakery, but got %s from %s", firstImport[1].Balance, firstImport[1].Description)
+	}
+
+	// The bank changed the text, but the FITID identifies the line
+	statement.Lines[1].Payee = "BAKERY 123"
+	slices.Reverse(firstImport)
+	imported, secondImport, _ := planImport(firstImport, statementRows(statement))
+	if len(secondImport) != 0 {
+		t.Errorf("Expected no new entries when importing again, but got %d", len(secondImport))
+	}
+	for _, row := range imported {
+		if !row.Duplicate {
+			t.Errorf("Expected line %d to be a duplicate", row.Line)
+		}
+	}
+}
+
+func TestPlanImport_StatementBalances(t *testing.T) {
+	statement := &importer.Statement{
+		Opening: &importer.Balance{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: money.FromInt(1000)},
+		Closing: &importer.Balance{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: money.FromInt(1450)},
+		Lines: []importer.Line{
+			{ID: "REF-1", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: money.FromInt(500)},
+			{ID: "REF-2", Date: time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC), Amount: money.FromInt(-50)},
+		},
+	}
+
+	imported, newEntries, _ := planImport(nil, statementRows(statement))
+	if len(imported) != 4 {
+		t.Fatalf("Expected opening, 2 lines and closing, but got %d rows", len(imported))
+	}
+	if imported[0].Description != "Opening balance" || imported[0].Entry.Balance != money.FromInt(1000) {
+		t.Errorf("Expected opening balance 1000 first, but got %s %s", imported[0].Description, imported[0].Entry.Balance)
+	}
+	// The closing balance matches the lines, so there is nothing to correct
+	if !imported[3].Duplicate || imported[3].Description != "Closing balance" {
+		t.Errorf("Expected matching closing balance to be skipped")
+	}
+	if len(newEntries) != 3 || newEntries[2].Balance != money.FromInt(1450) {
+		t.Errorf("Expected 3 new entries ending at 1450, but got %d", len(newEntries))
+	}
+
+	// A closing balance that differs corrects the chain
+	statement.Closing.Amount = money.FromInt(1400)
+	_, newEntries, _ = planImport(nil, statementRows(statement))
+	if len(newEntries) != 4 || newEntries[3].Balance != money.FromInt(1400) {
+		t.Errorf("Expected closing entry with balance 1400, but got %d entries", len(newEntries))
+	}
+}
+
+func amountPtr(amount money.Amount) *money.Amount {
+	return &amount
+}
@@ -1,26 +1,98 @@
 package logic
 
 import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"slices"
+	"time"
 
 	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
 	"github.com/google/uuid"
 )
 
+// Returned by a chain update when the entry was deleted in the meantime
+var errBalanceNotFound = errors.New("balance not found")
+
+// Number of balances in a page when no limit is requested, and the most we allow
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// BalanceQuery filters and pages the balances of a user. From is inclusive
+// and To exclusive. Cursor is the NextCursor of the previous page.
+type BalanceQuery struct {
+	AccountID *uuid.UUID
+	From      *time.Time
+	To        *time.Time
+	Cursor    string
+	Limit     int
+}
+
+// BalancePage holds a page of balances, newest first. NextCursor is empty on
+// the last page.
+type BalancePage struct {
+	Entries    []*database.MoneyEntry `json:"entries"`
+	NextCursor string                 `json:"next_cursor"`
+}
+
 type EntryForUpdate struct {
-	ID      uuid.UUID `json:"id"`
-	Balance float64   `json:"balance"`
-	Ratio   float64   `json:"ratio"`
+	ID      uuid.UUID    `json:"id"`
+	Balance money.Amount `json:"balance"`
+	Ratio   money.Rate   `json:"ratio"`
+}
+
+func InsertBalance(store database.LedgerStore, entry *database.MoneyEntry, userID *uuid.UUID) (*database.MoneyEntry, ErrorResponse) {
+	return insertEntry(store, nil, entry, nil, userID)
+}
+
+// insertDelta inserts an entry that moves the balance at its effective date by
+// amount, inheriting the ratio of the entry it follows. Later entries are
+// shifted by the same amount. The transaction the entry was created for, if
+// any, is inserted with it.
+func insertDelta(store database.LedgerStore, transaction *database.Transaction, entry *database.MoneyEntry, amount money.Amount, userID *uuid.UUID) (*database.MoneyEntry, ErrorResponse) {
+	return insertEntry(store, transaction, entry, &amount, userID)
 }
 
-func InsertBalance(store database.MoneyStore, entry *database.MoneyEntry, userID *uuid.UUID) (*database.MoneyEntry, ErrorResponse) {
-	lastEntry, _ := GetLastBalance(store, userID)
+// insertEntry inserts a snapshot, or a delta entry when amount is set, and
+// recalculates the chain.
+func insertEntry(store database.LedgerStore, transaction *database.Transaction, entry *database.MoneyEntry, amount *money.Amount, userID *uuid.UUID) (*database.MoneyEntry, ErrorResponse) {
+	account, errResp := resolveAccount(store, userID, &entry.AccountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	entry.AccountID = account.ID
+	entry.Currency = account.Currency
+	entry.UserID = *userID
+	if entry.EffectiveAt.IsZero() {
+		entry.EffectiveAt = time.Now()
+	}
 
-	entry.Budget = calculateBudget(entry, lastEntry)
+	var previous *database.MoneyEntry
+	var chain []*database.MoneyEntry
+	err := store.UpdateChainDB(&entry.AccountID, func(entries []*database.MoneyEntry) (*database.LedgerChange, error) {
+		previous = newestEntry(entries)
+		var entriesToUpdate []*database.MoneyEntry
+		if amount != nil {
+			delta := deltaEntry(entries, *amount, entry.EffectiveAt)
+			entry.Balance = delta.Balance
+			entry.Ratio = delta.Ratio
+			chain, entriesToUpdate = insertDeltaEntry(entries, entry, *amount)
+		} else {
+			chain, entriesToUpdate = insertBalanceEntry(entries, entry)
+		}
 
-	newEntryID, err := store.InsertMoneyDB(entry)
+		change := &database.LedgerChange{NewEntries: []*database.MoneyEntry{entry}, UpdatedEntries: entriesToUpdate}
+		if transaction != nil {
+			change.NewTransactions = []*database.Transaction{transaction}
+		}
+		return change, nil
+	})
 	if err != nil {
 		fmt.Println("Error inserting balance:", err)
 		return nil, ErrorResponse{
@@ -29,7 +101,7 @@ func InsertBalance(store database.MoneyStore, entry *database.MoneyEntry, userID
 		}
 	}
 
-	newEntry, err := store.SelectMoneyByIDDB(&newEntryID)
+	newEntry, err := store.SelectMoneyByIDDB(&entry.ID)
 	if err != nil {
 		fmt.Println("Error retrieving new balance:", err)
 		return nil, ErrorResponse{
@@ -38,15 +110,35 @@ func InsertBalance(store database.MoneyStore, entry *database.MoneyEntry, userID
 		}
 	}
 
+	// The stored entry holds the fields set by the database
+	for i, e := range chain {
+		if e.ID == newEntry.ID {
+			chain[i] = newEntry
+		}
+	}
+	publishBalanceEvent(&BalanceEvent{
+		Type:      BalanceCreated,
+		UserID:    *userID,
+		AccountID: newEntry.AccountID,
+		Entry:     newEntry,
+		Previous:  previous,
+		Latest:    newestEntry(chain),
+		Entries:   chain,
+		At:        time.Now(),
+	})
+
 	return newEntry, ErrorResponse{
 		Message: "",
 		Code:    http.StatusOK,
 	}
 }
 
-func calculateBudget(currentBalance *database.MoneyEntry, lastBalance *database.MoneyEntry) float64 {
+// calculateBudget derives the budget of currentBalance from the entry before
+// it. The share of an increase that goes to the budget is rounded half away
+// from zero to whole cents, see money.Amount.MulRate.
+func calculateBudget(currentBalance *database.MoneyEntry, lastBalance *database.MoneyEntry) money.Amount {
 	// If no last balance, start from 0
-	var budget float64 = 0
+	var budget money.Amount = 0
 	if lastBalance == nil {
 		return budget
 	}
@@ -61,7 +153,181 @@ func calculateBudget(currentBalance *database.MoneyEntry, lastBalance *database.
 		return budget + diff
 	}
 	// If balance increased, add to budget based on ratio
-	return lastBalance.Budget + diff*currentBalance.Ratio
+	return lastBalance.Budget + diff.MulRate(currentBalance.Ratio)
+}
+
+// insertBalanceEntry places newEntry into the chain by its effective date and
+// recalculates its budget and the budgets of all later entries. Entries are
+// ordered newest first. Returns the new chain and the later entries whose
+// budgets changed.
+func insertBalanceEntry(entries []*database.MoneyEntry, newEntry *database.MoneyEntry) ([]*database.MoneyEntry, []*database.MoneyEntry) {
+	index := len(entries)
+	for i, entry := range entries {
+		if !entry.EffectiveAt.After(newEntry.EffectiveAt) {
+			index = i
+			break
+		}
+	}
+
+	newEntries := slices.Insert(slices.Clone(entries), index, newEntry)
+	newEntry.Budget = 0
+	if index != len(newEntries)-1 {
+		newEntry.Budget = calculateBudget(newEntry, newEntries[index+1])
+	}
+	recalculateBudgets(newEntries[:index+1])
+
+	return newEntries, newEntries[:index]
+}
+
+// insertDeltaEntry places an entry that moves the balance by amount into the
+// chain like insertBalanceEntry. The later entries were recorded without the
+// amount, so their balances are shifted by it as well.
+func insertDeltaEntry(entries []*database.MoneyEntry, newEntry *database.MoneyEntry, amount money.Amount) ([]*database.MoneyEntry, []*database.MoneyEntry) {
+	for _, entry := range entries {
+		if entry.EffectiveAt.After(newEntry.EffectiveAt) {
+			entry.Balance += amount
+		}
+	}
+	return insertBalanceEntry(entries, newEntry)
+}
+
+// insertDeltaEntries places entries that each move the balance by amount into
+// the chain, like insertDeltaEntry for every one of them but walking the chain
+// once. New entries are ordered oldest first and get their balances and
+// ratios here. Returns the new chain and the existing entries that changed.
+func insertDeltaEntries(entries []*database.MoneyEntry, newEntries []*database.MoneyEntry, amount money.Amount) ([]*database.MoneyEntry, []*database.MoneyEntry) {
+	if len(newEntries) == 0 {
+		return entries, nil
+	}
+
+	// Walk oldest first. An existing entry at the same time as a new one
+	// stays older, as in insertBalanceEntry.
+	chain := make([]*database.MoneyEntry, 0, len(entries)+len(newEntries))
+	changed := []*database.MoneyEntry{}
+	var added money.Amount
+	first := -1
+	i, j := len(entries)-1, 0
+	for i >= 0 || j < len(newEntries) {
+		if j < len(newEntries) && (i < 0 || newEntries[j].EffectiveAt.Before(entries[i].EffectiveAt)) {
+			entry := newEntries[j]
+			j++
+			entry.Balance = amount
+			entry.Ratio = defaultRatio
+			if len(chain) > 0 {
+				last := chain[len(chain)-1]
+				entry.Balance = last.Balance + amount
+				entry.Ratio = last.Ratio
+			}
+			added += amount
+			if first < 0 {
+				first = len(chain)
+			}
+			chain = append(chain, entry)
+			continue
+		}
+
+		entry := entries[i]
+		i--
+		if first >= 0 {
+			entry.Balance += added
+			changed = append(changed, entry)
+		}
+		chain = append(chain, entry)
+	}
+	slices.Reverse(chain)
+	slices.Reverse(changed)
+
+	// Recalculate from the oldest new entry, starting at the entry before it
+	oldest := len(chain) - 1 - first
+	if oldest == len(chain)-1 {
+		chain[oldest].Budget = 0
+		recalculateBudgets(chain)
+	} else {
+		recalculateBudgets(chain[:oldest+2])
+	}
+	return chain, changed
+}
+
+// removeDeltaEntry takes an entry that moved the balance by amount out of the
+// chain and shifts the later entries back by it. Returns the new chain and the
+// later entries, whose balances and budgets changed.
+func removeDeltaEntry(entries []*database.MoneyEntry, entryID *uuid.UUID, amount money.Amount) ([]*database.MoneyEntry, []*database.MoneyEntry) {
+	index := slices.IndexFunc(entries, func(entry *database.MoneyEntry) bool {
+		return entry.ID == *entryID
+	})
+	if index < 0 {
+		return entries, nil
+	}
+
+	for _, entry := range entries[:index] {
+		entry.Balance -= amount
+	}
+	chain, laterEntries := deleteBalanceEntry(entries, entryID)
+	if laterEntries == nil {
+		laterEntries = entries[:index]
+	}
+	return chain, laterEntries
+}
+
+// moveDeltaEntry replaces the amount of a delta entry and moves it to a new
+// effective date. Returns the new chain and the entries that changed,
+// including the moved entry.
+func moveDeltaEntry(entries []*database.MoneyEntry, entryID *uuid.UUID, oldAmount money.Amount, amount money.Amount, at time.Time) ([]*database.MoneyEntry, []*database.MoneyEntry) {
+	index := slices.IndexFunc(entries, func(entry *database.MoneyEntry) bool {
+		return entry.ID == *entryID
+	})
+	if index < 0 {
+		return entries, nil
+	}
+	entry := entries[index]
+	from := entry.EffectiveAt
+	if at.Before(from) {
+		from = at
+	}
+
+	chain, _ := removeDeltaEntry(entries, entryID, oldAmount)
+	entry.Balance = deltaEntry(chain, amount, at).Balance
+	entry.EffectiveAt = at
+	chain, _ = insertDeltaEntry(chain, entry, amount)
+
+	// Entries between the old and the new date moved as well, so the whole
+	// chain is recalculated
+	chain[len(chain)-1].Budget = 0
+	recalculateBudgets(chain)
+
+	changed := []*database.MoneyEntry{}
+	for _, e := range chain {
+		if !e.EffectiveAt.Before(from) {
+			changed = append(changed, e)
+		}
+	}
+	return chain, changed
+}
+
+// balanceAt returns the latest entry effective at or before the given time, or
+// nil if there is none. Entries are ordered newest first.
+func balanceAt(entries []*database.MoneyEntry, at time.Time) *database.MoneyEntry {
+	for _, entry := range entries {
+		if !entry.EffectiveAt.After(at) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// deltaEntry returns a new entry that moves the balance effective at the given
+// time by amount and inherits the ratio of the entry it follows.
+func deltaEntry(entries []*database.MoneyEntry, amount money.Amount, at time.Time) database.MoneyEntry {
+	entry := database.MoneyEntry{
+		Balance:     amount,
+		Ratio:       defaultRatio,
+		EffectiveAt: at,
+	}
+	if lastEntry := balanceAt(entries, at); lastEntry != nil {
+		entry.Balance = lastEntry.Balance + amount
+		entry.Ratio = lastEntry.Ratio
+	}
+	return entry
 }
 
 func recalculateBudgets(entries []*database.MoneyEntry) {
@@ -74,8 +340,8 @@ func recalculateBudgets(entries []*database.MoneyEntry) {
 	slices.Reverse(entries)
 }
 
-func GetLastBalance(store database.MoneyStore, userID *uuid.UUID) (*database.MoneyEntry, ErrorResponse) {
-	balances, err := store.SelectUserMoneyByCountDB(userID, 1)
+func GetLastBalance(store database.MoneyStore, accountID *uuid.UUID) (*database.MoneyEntry, ErrorResponse) {
+	balances, err := store.SelectAccountMoneyByCountDB(accountID, 1)
 	if err != nil {
 		fmt.Println("Error retrieving balance:", err)
 		return nil, ErrorResponse{
@@ -123,14 +389,34 @@ func updateBalanceEntry(entries []*database.MoneyEntry, updatedEntry *database.M
 	return append(entriesToUpdate, remainingEntries...), entriesToUpdate
 }
 
+// changeBalanceEntry updates an entry like updateBalanceEntry. The later
+// entries were recorded on top of its old balance, so they are shifted by the
+// difference like in insertDeltaEntry.
+func changeBalanceEntry(entries []*database.MoneyEntry, updatedEntry *database.MoneyEntry) ([]*database.MoneyEntry, []*database.MoneyEntry) {
+	index := slices.IndexFunc(entries, func(entry *database.MoneyEntry) bool {
+		return entry.ID == updatedEntry.ID
+	})
+	if index < 0 {
+		return entries, nil
+	}
+
+	diff := updatedEntry.Balance - entries[index].Balance
+	for _, entry := range entries[:index] {
+		entry.Balance += diff
+	}
+	return updateBalanceEntry(entries, updatedEntry)
+}
+
+// UpdateBalance sets the balance and ratio of an entry and shifts the later
+// entries by the change of the balance, in a single database transaction.
 func UpdateBalance(store database.MoneyStore, actorID *uuid.UUID, updatedEntry *EntryForUpdate) ([]*database.MoneyEntry, ErrorResponse) {
 	fmt.Println("Updating balance entry with ID:", updatedEntry.ID, "to new Balance:", updatedEntry.Balance, "and Ratio:", updatedEntry.Ratio)
 	entryToUpdate, errResp := GetBalanceByID(store, &updatedEntry.ID)
-	entryToUpdate.Balance = updatedEntry.Balance
-	entryToUpdate.Ratio = updatedEntry.Ratio
 	if errResp.Code != http.StatusOK {
 		return nil, errResp
 	}
+	entryToUpdate.Balance = updatedEntry.Balance
+	entryToUpdate.Ratio = updatedEntry.Ratio
 
 	if entryToUpdate.UserID != *actorID {
 		return nil, ErrorResponse{
@@ -139,11 +425,23 @@ func UpdateBalance(store database.MoneyStore, actorID *uuid.UUID, updatedEntry *
 		}
 	}
 
-	entries, errResp := GetAllBalances(store, &entryToUpdate.UserID)
-
-	newEntries, entriesToUpdate := updateBalanceEntry(entries, entryToUpdate)
-
-	err := store.UpdateMoneyBatchDB(entriesToUpdate)
+	var previous *database.MoneyEntry
+	var newEntries []*database.MoneyEntry
+	err := store.UpdateChainDB(&entryToUpdate.AccountID, func(entries []*database.MoneyEntry) (*database.LedgerChange, error) {
+		if !slices.ContainsFunc(entries, func(e *database.MoneyEntry) bool { return e.ID == entryToUpdate.ID }) {
+			return nil, errBalanceNotFound
+		}
+		previous = newestEntry(entries)
+		var entriesToUpdate []*database.MoneyEntry
+		newEntries, entriesToUpdate = changeBalanceEntry(entries, entryToUpdate)
+		return &database.LedgerChange{UpdatedEntries: entriesToUpdate}, nil
+	})
+	if errors.Is(err, errBalanceNotFound) {
+		return nil, ErrorResponse{
+			Message: "Balance not found",
+			Code:    http.StatusNotFound,
+		}
+	}
 	if err != nil {
 		fmt.Println("Error updating balances:", err)
 		return nil, ErrorResponse{
@@ -152,10 +450,23 @@ func UpdateBalance(store database.MoneyStore, actorID *uuid.UUID, updatedEntry *
 		}
 	}
 
-	fmt.Println("Returning",len(newEntries) ,"updated entries:")
+	// The entry in the chain holds the recalculated budget
 	for _, e := range newEntries {
-		fmt.Printf("ID: %s, Balance: %.2f, Budget: %.2f, Ratio: %.2f\n", e.ID, e.Balance, e.Budget, e.Ratio)
+		if e.ID == entryToUpdate.ID {
+			entryToUpdate = e
+		}
 	}
+	publishBalanceEvent(&BalanceEvent{
+		Type:      BalanceUpdated,
+		UserID:    *actorID,
+		AccountID: entryToUpdate.AccountID,
+		Entry:     entryToUpdate,
+		Previous:  previous,
+		Latest:    newestEntry(newEntries),
+		Entries:   newEntries,
+		At:        time.Now(),
+	})
+
 	return newEntries, ErrorResponse{
 		Message: "",
 		Code:    http.StatusOK,
@@ -189,45 +500,92 @@ func deleteBalanceEntry(entries []*database.MoneyEntry, balanceID *uuid.UUID) ([
 	return append(entriesToUpdate, remainingEntries...), entriesToUpdate
 }
 
-func DeleteBalance(store database.MoneyStore, balanceID *uuid.UUID) ([]*database.MoneyEntry, ErrorResponse) {
-	errResp := ErrorResponse{}
-	entryToDelete, errResp := GetBalanceByID(store, balanceID)
-	entries, errResp := GetAllBalances(store, &entryToDelete.UserID)
+// removeBalanceEntry takes an entry out of the chain like removeDeltaEntry.
+// What the entry added to the balance before it is taken off the later
+// entries.
+func removeBalanceEntry(entries []*database.MoneyEntry, balanceID *uuid.UUID) ([]*database.MoneyEntry, []*database.MoneyEntry) {
+	index := slices.IndexFunc(entries, func(entry *database.MoneyEntry) bool {
+		return entry.ID == *balanceID
+	})
+	if index < 0 {
+		return entries, nil
+	}
 
-	newEntries, entriesToUpdate := deleteBalanceEntry(entries, balanceID)
+	amount := entries[index].Balance
+	if index != len(entries)-1 {
+		amount -= entries[index+1].Balance
+	}
+	return removeDeltaEntry(entries, balanceID, amount)
+}
 
-	err := store.DeleteMoneyDB(balanceID)
-	if err != nil {
-		fmt.Println("Error deleting balance:", err)
+// DeleteBalance deletes a balance entry of the actor and shifts the later
+// entries back by what it added to the balance, in a single database
+// transaction. Entries of transactions are deleted with their transaction, see
+// DeleteTransaction.
+func DeleteBalance(store database.MoneyStore, actorID *uuid.UUID, balanceID *uuid.UUID) ([]*database.MoneyEntry, ErrorResponse) {
+	entryToDelete, errResp := GetBalanceByID(store, balanceID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	if entryToDelete.UserID != *actorID {
 		return nil, ErrorResponse{
-			Message: "Failed to delete balance",
-			Code:    http.StatusInternalServerError,
+			Message: "Forbidden: cannot delete another user's balance",
+			Code:    http.StatusForbidden,
 		}
 	}
-
-	if entriesToUpdate == nil {
-		return newEntries, errResp
+	if entryToDelete.TransactionID != nil {
+		return nil, ErrorResponse{
+			Message: "Balance belongs to a transaction, delete the transaction instead",
+			Code:    http.StatusConflict,
+		}
+	}
+	var previous *database.MoneyEntry
+	var newEntries []*database.MoneyEntry
+	err := store.UpdateChainDB(&entryToDelete.AccountID, func(entries []*database.MoneyEntry) (*database.LedgerChange, error) {
+		if !slices.ContainsFunc(entries, func(e *database.MoneyEntry) bool { return e.ID == *balanceID }) {
+			return nil, errBalanceNotFound
+		}
+		previous = newestEntry(entries)
+		var entriesToUpdate []*database.MoneyEntry
+		newEntries, entriesToUpdate = removeBalanceEntry(entries, balanceID)
+		return &database.LedgerChange{DeletedEntryID: balanceID, UpdatedEntries: entriesToUpdate}, nil
+	})
+	if errors.Is(err, errBalanceNotFound) {
+		return nil, ErrorResponse{
+			Message: "Balance not found",
+			Code:    http.StatusNotFound,
+		}
 	}
-
-	err = store.UpdateMoneyBatchDB(entriesToUpdate)
 	if err != nil {
-		fmt.Println("Error updating balances after deletion:", err)
+		fmt.Println("Error deleting balance:", err)
 		return nil, ErrorResponse{
-			Message: "Failed to update balances after deletion",
+			Message: "Failed to delete balance",
 			Code:    http.StatusInternalServerError,
 		}
 	}
 
-	fmt.Println("Returning",len(newEntries) ,"updated entries:")
-	for _, e := range newEntries {
-		fmt.Printf("ID: %s, Balance: %.2f, Budget: %.2f, Ratio: %.2f\n", e.ID, e.Balance, e.Budget, e.Ratio)
-	}
+	publishBalanceEvent(&BalanceEvent{
+		Type:      BalanceDeleted,
+		UserID:    entryToDelete.UserID,
+		AccountID: entryToDelete.AccountID,
+		Entry:     entryToDelete,
+		Previous:  previous,
+		Latest:    newestEntry(newEntries),
+		Entries:   newEntries,
+		At:        time.Now(),
+	})
 
 	return newEntries, errResp
 }
 
 func GetBalanceByID(store database.MoneyStore, balanceID *uuid.UUID) (*database.MoneyEntry, ErrorResponse) {
 	balance, err := store.SelectMoneyByIDDB(balanceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorResponse{
+			Message: "Balance not found",
+			Code:    http.StatusNotFound,
+		}
+	}
 	if err != nil {
 		fmt.Println("Error retrieving balance:", err)
 		return nil, ErrorResponse{
@@ -273,3 +631,123 @@ func GetAllBalances(store database.MoneyStore, userID *uuid.UUID) ([]*database.M
 		Code:    http.StatusOK,
 	}
 }
+
+func GetAccountBalances(store database.MoneyStore, accountID *uuid.UUID) ([]*database.MoneyEntry, ErrorResponse) {
+	balances, err := store.SelectAccountMoneyDB(accountID)
+	if err != nil {
+		fmt.Println("Error retrieving balance:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve balances",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return balances, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// GetBalancesByAccount returns the budget chain of a single account after
+// checking that it belongs to the actor.
+func GetBalancesByAccount(store database.LedgerStore, actorID *uuid.UUID, accountID *uuid.UUID) ([]*database.MoneyEntry, ErrorResponse) {
+	_, errResp := GetAccountByID(store, actorID, accountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	return GetAccountBalances(store, accountID)
+}
+
+// GetBalancePage returns a page of the user's balances, optionally limited to
+// one account and a range of effective dates.
+func GetBalancePage(store database.LedgerStore, userID *uuid.UUID, query *BalanceQuery) (*BalancePage, ErrorResponse) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 1 || limit > maxPageSize {
+		return nil, ErrorResponse{
+			Message: fmt.Sprintf("Limit must be between 1 and %d", maxPageSize),
+			Code:    http.StatusBadRequest,
+		}
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, ErrorResponse{
+			Message: "From must be before to",
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	if query.AccountID != nil {
+		_, errResp := GetAccountByID(store, userID, query.AccountID)
+		if errResp.Code != http.StatusOK {
+			return nil, errResp
+		}
+	}
+
+	moneyQuery := &database.MoneyQuery{
+		AccountID: query.AccountID,
+		From:      query.From,
+		To:        query.To,
+		// One more than requested tells us whether there is another page
+		Limit: limit + 1,
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, ErrorResponse{
+				Message: "Invalid cursor",
+				Code:    http.StatusBadRequest,
+			}
+		}
+		moneyQuery.After = cursor
+	}
+
+	entries, err := store.SelectUserMoneyPageDB(userID, moneyQuery)
+	if err != nil {
+		fmt.Println("Error retrieving balances:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve balances",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	page := &BalancePage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = encodeCursor(entries[limit-1])
+	}
+
+	return page, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// encodeCursor turns the position of an entry into an opaque string clients
+// pass back to get the following page.
+func encodeCursor(entry *database.MoneyEntry) string {
+	cursor, _ := json.Marshal(database.MoneyCursor{
+		EffectiveAt: entry.EffectiveAt,
+		CreatedAt:   entry.CreatedAt,
+		ID:          entry.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(cursor)
+}
+
+func decodeCursor(value string) (*database.MoneyCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := &database.MoneyCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+	if cursor.ID == uuid.Nil || cursor.CreatedAt == "" {
+		return nil, errors.New("incomplete cursor")
+	}
+	return cursor, nil
+}
@@ -2,8 +2,10 @@ package logic
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
 	"github.com/google/uuid"
 )
 
@@ -16,43 +18,43 @@ func TestRecalculateBudget_UpdatedLastEntry(t *testing.T) {
 	balanceEntries := []*database.MoneyEntry{
 		{
 			ID:      ID3,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  -200.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(-200),
 		},
 		{
 			ID:      ID2,
-			Balance: 1200.0,
-			Ratio:   0.5,
-			Budget:  200.0,
+			Balance: money.FromInt(1200),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(200),
 		},
 		{
 			ID:      ID1,
-			Balance: 1000.0,
-			Ratio:   0.5,
-			Budget:  100.0,
+			Balance: money.FromInt(1000),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(100),
 		},
 		{
 			ID:      ID0,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  0.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(0),
 		},
 	}
 
 	updatedEntry := &database.MoneyEntry{
 		ID:      ID3,
-		Balance: 1300.0,
-		Ratio:   0.5,
+		Balance: money.FromInt(1300),
+		Ratio:   money.MustParseRate("0.5"),
 	}
 
-	expectedBudgets := []float64{250.0, 200.0, 100.0, 0.0}
+	expectedBudgets := []money.Amount{money.FromInt(250), money.FromInt(200), money.FromInt(100), money.FromInt(0)}
 
 	newBalances, _ := updateBalanceEntry(balanceEntries, updatedEntry)
 
 	for i, entry := range newBalances {
 		if entry.Budget != expectedBudgets[i] {
-			t.Errorf("Expected budget %.2f, but got %.2f", expectedBudgets[i], entry.Budget)
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
 		}
 	}
 }
@@ -66,43 +68,43 @@ func TestRecalculateBudget_UpdatedMidEntry(t *testing.T) {
 	balanceEntries := []*database.MoneyEntry{
 		{
 			ID:      ID3,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  -200.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(-200),
 		},
 		{
 			ID:      ID2,
-			Balance: 1200.0,
-			Ratio:   0.5,
-			Budget:  200.0,
+			Balance: money.FromInt(1200),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(200),
 		},
 		{
 			ID:      ID1,
-			Balance: 1000.0,
-			Ratio:   0.5,
-			Budget:  100.0,
+			Balance: money.FromInt(1000),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(100),
 		},
 		{
 			ID:      ID0,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  0.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(0),
 		},
 	}
 
 	updatedEntry := &database.MoneyEntry{
 		ID:      ID1,
-		Balance: 1300.0,
-		Ratio:   0.5,
+		Balance: money.FromInt(1300),
+		Ratio:   money.MustParseRate("0.5"),
 	}
 
-	expectedBudgets := []float64{-250.0, 150.0, 250.0, 0.0}
+	expectedBudgets := []money.Amount{money.FromInt(-250), money.FromInt(150), money.FromInt(250), money.FromInt(0)}
 
 	newBalances, _ := updateBalanceEntry(balanceEntries, updatedEntry)
 
 	for i, entry := range newBalances {
 		if entry.Budget != expectedBudgets[i] {
-			t.Errorf("Expected budget %.2f, but got %.2f", expectedBudgets[i], entry.Budget)
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
 		}
 	}
 }
@@ -116,43 +118,43 @@ func TestRecalculateBudget_UpdatedFirstEntry(t *testing.T) {
 	balanceEntries := []*database.MoneyEntry{
 		{
 			ID:      ID3,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  -200.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(-200),
 		},
 		{
 			ID:      ID2,
-			Balance: 1200.0,
-			Ratio:   0.5,
-			Budget:  200.0,
+			Balance: money.FromInt(1200),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(200),
 		},
 		{
 			ID:      ID1,
-			Balance: 1000.0,
-			Ratio:   0.5,
-			Budget:  100.0,
+			Balance: money.FromInt(1000),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(100),
 		},
 		{
 			ID:      ID0,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  0.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(0),
 		},
 	}
 
 	updatedEntry := &database.MoneyEntry{
 		ID:      ID0,
-		Balance: 600.0,
-		Ratio:   0.5,
+		Balance: money.FromInt(600),
+		Ratio:   money.MustParseRate("0.5"),
 	}
 
-	expectedBudgets := []float64{-100.0, 300.0, 200.0, 0.0}
+	expectedBudgets := []money.Amount{money.FromInt(-100), money.FromInt(300), money.FromInt(200), money.FromInt(0)}
 
 	newBalances, _ := updateBalanceEntry(balanceEntries, updatedEntry)
 
 	for i, entry := range newBalances {
 		if entry.Budget != expectedBudgets[i] {
-			t.Errorf("Expected budget %.2f, but got %.2f", expectedBudgets[i], entry.Budget)
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
 		}
 	}
 }
@@ -166,37 +168,37 @@ func TestRecalulateBudget_DeletedLastEntry(t *testing.T) {
 	balanceEntries := []*database.MoneyEntry{
 		{
 			ID:      ID3,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  0.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(0),
 		},
 		{
 			ID:      ID2,
-			Balance: 1200.0,
-			Ratio:   0.5,
-			Budget:  200.0,
+			Balance: money.FromInt(1200),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(200),
 		},
 		{
 			ID:      ID1,
-			Balance: 1000.0,
-			Ratio:   0.5,
-			Budget:  100.0,
+			Balance: money.FromInt(1000),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(100),
 		},
 		{
 			ID:      ID0,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  0.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(0),
 		},
 	}
 
-	expectedBudgets := []float64{200.0, 100.0, 0.0}
+	expectedBudgets := []money.Amount{money.FromInt(200), money.FromInt(100), money.FromInt(0)}
 
 	newBalances, _ := deleteBalanceEntry(balanceEntries, &ID3)
 
 	for i, entry := range newBalances {
 		if entry.Budget != expectedBudgets[i] {
-			t.Errorf("Expected budget %.2f, but got %.2f", expectedBudgets[i], entry.Budget)
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
 		}
 	}
 }
@@ -210,37 +212,37 @@ func TestRecalulateBudget_DeletedMidEntry(t *testing.T) {
 	balanceEntries := []*database.MoneyEntry{
 		{
 			ID:      ID3,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  0.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(0),
 		},
 		{
 			ID:      ID2,
-			Balance: 1200.0,
-			Ratio:   0.5,
-			Budget:  200.0,
+			Balance: money.FromInt(1200),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(200),
 		},
 		{
 			ID:      ID1,
-			Balance: 1000.0,
-			Ratio:   0.5,
-			Budget:  100.0,
+			Balance: money.FromInt(1000),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(100),
 		},
 		{
 			ID:      ID0,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  0.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(0),
 		},
 	}
 
-	expectedBudgets := []float64{-100.0, 100.0, 0.0}
+	expectedBudgets := []money.Amount{money.FromInt(-100), money.FromInt(100), money.FromInt(0)}
 
 	newBalances, _ := deleteBalanceEntry(balanceEntries, &ID2)
 
 	for i, entry := range newBalances {
 		if entry.Budget != expectedBudgets[i] {
-			t.Errorf("Expected budget %.2f, but got %.2f", expectedBudgets[i], entry.Budget)
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
 		}
 	}
 }
@@ -254,79 +256,360 @@ func TestRecalulateBudget_DeletedFirstEntry(t *testing.T) {
 	balanceEntries := []*database.MoneyEntry{
 		{
 			ID:      ID3,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  0.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(0),
 		},
 		{
 			ID:      ID2,
-			Balance: 1200.0,
-			Ratio:   0.5,
-			Budget:  200.0,
+			Balance: money.FromInt(1200),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(200),
 		},
 		{
 			ID:      ID1,
-			Balance: 1000.0,
-			Ratio:   0.5,
-			Budget:  100.0,
+			Balance: money.FromInt(1000),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(100),
 		},
 		{
 			ID:      ID0,
-			Balance: 800.0,
-			Ratio:   0.5,
-			Budget:  0.0,
+			Balance: money.FromInt(800),
+			Ratio:   money.MustParseRate("0.5"),
+			Budget:  money.FromInt(0),
 		},
 	}
 
-	expectedBudgets := []float64{-300.0, 100.0, 0.0}
+	expectedBudgets := []money.Amount{money.FromInt(-300), money.FromInt(100), money.FromInt(0)}
 
 	newBalances, _ := deleteBalanceEntry(balanceEntries, &ID0)
 
 	for i, entry := range newBalances {
 		if entry.Budget != expectedBudgets[i] {
-			t.Errorf("Expected budget %.2f, but got %.2f", expectedBudgets[i], entry.Budget)
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
 		}
 	}
 }
 
 func TestCalculateBudgetIncrease(t *testing.T) {
 	testBalance := &database.MoneyEntry{
-		Balance: 1000.0,
-		Ratio: 0.2,
+		Balance: money.FromInt(1000),
+		Ratio: money.MustParseRate("0.2"),
 	}
 	
 	testLastBalance := &database.MoneyEntry{
-		Balance: 800.0,
-		Budget: 400.0,
-		Ratio: 0.5,
+		Balance: money.FromInt(800),
+		Budget: money.FromInt(400),
+		Ratio: money.MustParseRate("0.5"),
 	}
 
-	expectedBudget := 440.0
+	expectedBudget := money.FromInt(440)
 
 	calculatedBudget := calculateBudget(testBalance, testLastBalance)
 
 	if calculatedBudget != expectedBudget {
-		t.Errorf("Expected budget %.2f, but got %.2f", expectedBudget, calculatedBudget)
+		t.Errorf("Expected budget %s, but got %s", expectedBudget, calculatedBudget)
 	}
 }
 
 func TestCalculateBudgetDecrease(t *testing.T) {
 	testBalance := &database.MoneyEntry{
-		Balance: 600.0,
-		Ratio: 0.9,
+		Balance: money.FromInt(600),
+		Ratio: money.MustParseRate("0.9"),
 	}
 	
 	testLastBalance := &database.MoneyEntry{
-		Balance: 800.0,
-		Budget: 400.0,
-		Ratio: 0.5,
+		Balance: money.FromInt(800),
+		Budget: money.FromInt(400),
+		Ratio: money.MustParseRate("0.5"),
 	}
 
-	expectedBudget := 200.0
+	expectedBudget := money.FromInt(200)
 
 	calculatedBudget := calculateBudget(testBalance, testLastBalance)
 
 	if calculatedBudget != expectedBudget {
-		t.Errorf("Expected budget %.2f, but got %.2f", expectedBudget, calculatedBudget)
+		t.Errorf("Expected budget %s, but got %s", expectedBudget, calculatedBudget)
+	}
+}
+
+func TestCalculateBudgetRounding(t *testing.T) {
+	testBalance := &database.MoneyEntry{
+		Balance: money.MustParseAmount("800.03"),
+		Ratio:   money.MustParseRate("0.5"),
+	}
+
+	testLastBalance := &database.MoneyEntry{
+		Balance: money.FromInt(800),
+		Budget:  money.MustParseAmount("0.10"),
+		Ratio:   money.MustParseRate("0.5"),
+	}
+
+	expectedBudget := money.MustParseAmount("0.12")
+
+	calculatedBudget := calculateBudget(testBalance, testLastBalance)
+
+	if calculatedBudget != expectedBudget {
+		t.Errorf("Expected budget %s, but got %s", expectedBudget, calculatedBudget)
+	}
+}
+
+func TestRecalculateBudget_InsertedMidEntry(t *testing.T) {
+	ID0 := uuid.New()
+	ID1 := uuid.New()
+	ID2 := uuid.New()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	balanceEntries := []*database.MoneyEntry{
+		{
+			ID:          ID2,
+			Balance:     money.FromInt(1200),
+			Ratio:       money.MustParseRate("0.5"),
+			Budget:      money.FromInt(200),
+			EffectiveAt: start.AddDate(0, 0, 14),
+		},
+		{
+			ID:          ID1,
+			Balance:     money.FromInt(1000),
+			Ratio:       money.MustParseRate("0.5"),
+			Budget:      money.FromInt(100),
+			EffectiveAt: start.AddDate(0, 0, 7),
+		},
+		{
+			ID:          ID0,
+			Balance:     money.FromInt(800),
+			Ratio:       money.MustParseRate("0.5"),
+			Budget:      money.FromInt(0),
+			EffectiveAt: start,
+		},
+	}
+
+	newEntry := &database.MoneyEntry{
+		Balance:     money.FromInt(600),
+		Ratio:       money.MustParseRate("0.5"),
+		EffectiveAt: start.AddDate(0, 0, 10),
+	}
+
+	expectedBudgets := []money.Amount{money.FromInt(0), money.FromInt(-300), money.FromInt(100), money.FromInt(0)}
+
+	newBalances, updatedBalances := insertBalanceEntry(balanceEntries, newEntry)
+
+	if len(newBalances) != len(expectedBudgets) {
+		t.Fatalf("Expected %d entries, but got %d", len(expectedBudgets), len(newBalances))
+	}
+	if newBalances[1] != newEntry {
+		t.Errorf("Expected new entry at index 1")
+	}
+	for i, entry := range newBalances {
+		if entry.Budget != expectedBudgets[i] {
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
+		}
+	}
+	if len(updatedBalances) != 1 || updatedBalances[0].ID != ID2 {
+		t.Errorf("Expected only the later entry to be updated, but got %d entries", len(updatedBalances))
+	}
+}
+
+func TestRecalculateBudget_InsertedFirstEntry(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	balanceEntries := []*database.MoneyEntry{
+		{
+			ID:          uuid.New(),
+			Balance:     money.FromInt(1000),
+			Ratio:       money.MustParseRate("0.5"),
+			Budget:      money.FromInt(0),
+			EffectiveAt: start,
+		},
+	}
+
+	newEntry := &database.MoneyEntry{
+		Balance:     money.FromInt(800),
+		Ratio:       money.MustParseRate("0.5"),
+		EffectiveAt: start.AddDate(0, 0, -1),
+	}
+
+	expectedBudgets := []money.Amount{money.FromInt(100), money.FromInt(0)}
+
+	newBalances, updatedBalances := insertBalanceEntry(balanceEntries, newEntry)
+
+	for i, entry := range newBalances {
+		if entry.Budget != expectedBudgets[i] {
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
+		}
+	}
+	if len(updatedBalances) != 1 {
+		t.Errorf("Expected 1 updated entry, but got %d", len(updatedBalances))
+	}
+}
+
+func TestInsertDeltaEntry_BackdatedBetweenSnapshots(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	laterID := uuid.New()
+
+	balanceEntries := []*database.MoneyEntry{
+		{
+			ID:          laterID,
+			Balance:     money.FromInt(900),
+			Ratio:       money.MustParseRate("0.5"),
+			Budget:      money.FromInt(-100),
+			EffectiveAt: start.AddDate(0, 0, 2),
+		},
+		{
+			ID:          uuid.New(),
+			Balance:     money.FromInt(1000),
+			Ratio:       money.MustParseRate("0.5"),
+			Budget:      money.FromInt(0),
+			EffectiveAt: start,
+		},
+	}
+
+	newEntry := deltaEntry(balanceEntries, money.FromInt(-50), start.AddDate(0, 0, 1))
+	newBalances, updatedBalances := insertDeltaEntry(balanceEntries, &newEntry, money.FromInt(-50))
+
+	expectedBalances := []money.Amount{money.FromInt(850), money.FromInt(950), money.FromInt(1000)}
+	expectedBudgets := []money.Amount{money.FromInt(-150), money.FromInt(-50), money.FromInt(0)}
+	if len(newBalances) != len(expectedBalances) {
+		t.Fatalf("Expected %d entries, but got %d", len(expectedBalances), len(newBalances))
+	}
+	for i, entry := range newBalances {
+		if entry.Balance != expectedBalances[i] {
+			t.Errorf("Expected balance %s, but got %s", expectedBalances[i], entry.Balance)
+		}
+		if entry.Budget != expectedBudgets[i] {
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
+		}
+	}
+	if len(updatedBalances) != 1 || updatedBalances[0].ID != laterID {
+		t.Errorf("Expected only the later entry to be updated, but got %d entries", len(updatedBalances))
+	}
+}
+
+func TestInsertDeltaEntries_MatchesOneByOne(t *testing.T) {
+	amount := money.FromInt(-40)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	occurrences := []time.Time{start.AddDate(0, 0, 5), start.AddDate(0, 0, 9), start.AddDate(0, 0, 25)}
+
+	// Insert one by one as the scheduler did before
+	expected := backdatedSnapshotChain()
+	for _, occurrence := range occurrences {
+		newEntry := deltaEntry(expected, amount, occurrence)
+		expected, _ = insertDeltaEntry(expected, &newEntry, amount)
+	}
+
+	balanceEntries := backdatedSnapshotChain()
+	newEntries := []*database.MoneyEntry{}
+	for _, occurrence := range occurrences {
+		newEntries = append(newEntries, &database.MoneyEntry{EffectiveAt: occurrence})
+	}
+	newBalances, updatedBalances := insertDeltaEntries(balanceEntries, newEntries, amount)
+
+	if len(newBalances) != len(expected) {
+		t.Fatalf("Expected %d entries, but got %d", len(expected), len(newBalances))
+	}
+	for i, entry := range newBalances {
+		if !entry.EffectiveAt.Equal(expected[i].EffectiveAt) {
+			t.Errorf("Expected entry %d at %s, but got %s", i, expected[i].EffectiveAt, entry.EffectiveAt)
+		}
+		if entry.Balance != expected[i].Balance {
+			t.Errorf("Expected balance %s, but got %s", expected[i].Balance, entry.Balance)
+		}
+		if entry.Budget != expected[i].Budget {
+			t.Errorf("Expected budget %s, but got %s", expected[i].Budget, entry.Budget)
+		}
+	}
+	// The snapshot on day 9 stays older than the occurrence at the same time
+	if len(updatedBalances) != 2 || updatedBalances[0].ID != balanceEntries[0].ID || updatedBalances[1].ID != balanceEntries[1].ID {
+		t.Errorf("Expected the two later entries to be updated, but got %d entries", len(updatedBalances))
+	}
+}
+
+func TestInsertDeltaEntries_EmptyChain(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newEntries := []*database.MoneyEntry{{EffectiveAt: start}, {EffectiveAt: start.AddDate(0, 1, 0)}}
+
+	newBalances, updatedBalances := insertDeltaEntries(nil, newEntries, money.FromInt(100))
+
+	if len(newBalances) != 2 || len(updatedBalances) != 0 {
+		t.Fatalf("Expected 2 entries and no updates, but got %d and %d", len(newBalances), len(updatedBalances))
+	}
+	if newBalances[0].Balance != money.FromInt(200) || newBalances[1].Balance != money.FromInt(100) {
+		t.Errorf("Expected balances 200 and 100, but got %s and %s", newBalances[0].Balance, newBalances[1].Balance)
+	}
+	if newBalances[1].Budget != 0 || newBalances[0].Budget != money.FromInt(100).MulRate(defaultRatio) {
+		t.Errorf("Expected budgets %s and 0, but got %s and %s", money.FromInt(100).MulRate(defaultRatio), newBalances[0].Budget, newBalances[1].Budget)
+	}
+}
+
+func backdatedSnapshotChain() []*database.MoneyEntry {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ratio := money.MustParseRate("0.5")
+	transactionID := uuid.New()
+	return []*database.MoneyEntry{
+		{ID: uuid.New(), Balance: money.FromInt(950), Budget: money.FromInt(50), Ratio: ratio, EffectiveAt: start.AddDate(0, 0, 19), TransactionID: &transactionID},
+		{ID: uuid.New(), Balance: money.FromInt(1000), Budget: money.FromInt(100), Ratio: ratio, EffectiveAt: start.AddDate(0, 0, 9)},
+		{ID: uuid.New(), Balance: money.FromInt(800), Budget: money.FromInt(0), Ratio: ratio, EffectiveAt: start},
+	}
+}
+
+func TestChangeBalanceEntry_ShiftsLaterEntries(t *testing.T) {
+	balanceEntries := backdatedSnapshotChain()
+	updatedEntry := &database.MoneyEntry{ID: balanceEntries[1].ID, Balance: money.FromInt(1100), Ratio: money.MustParseRate("0.5")}
+
+	newBalances, updatedBalances := changeBalanceEntry(balanceEntries, updatedEntry)
+
+	// The transaction after the snapshot still takes 50 off it
+	expectedBalances := []money.Amount{money.FromInt(1050), money.FromInt(1100), money.FromInt(800)}
+	expectedBudgets := []money.Amount{money.FromInt(100), money.FromInt(150), money.FromInt(0)}
+	for i, entry := range newBalances {
+		if entry.Balance != expectedBalances[i] {
+			t.Errorf("Expected balance %s, but got %s", expectedBalances[i], entry.Balance)
+		}
+		if entry.Budget != expectedBudgets[i] {
+			t.Errorf("Expected budget %s, but got %s", expectedBudgets[i], entry.Budget)
+		}
+	}
+	if len(updatedBalances) != 2 {
+		t.Errorf("Expected 2 updated entries, but got %d", len(updatedBalances))
+	}
+}
+
+func TestRemoveBalanceEntry_ShiftsLaterEntries(t *testing.T) {
+	balanceEntries := backdatedSnapshotChain()
+	transactionEntry := balanceEntries[0]
+
+	newBalances, updatedBalances := removeBalanceEntry(balanceEntries, &balanceEntries[1].ID)
+
+	if len(newBalances) != 2 {
+		t.Fatalf("Expected 2 entries, but got %d", len(newBalances))
+	}
+	// The snapshot had added 200, the transaction now follows the first entry
+	if transactionEntry.Balance != money.FromInt(750) || transactionEntry.Budget != money.FromInt(-50) {
+		t.Errorf("Expected balance 750 and budget -50, but got %s and %s", transactionEntry.Balance, transactionEntry.Budget)
+	}
+	if len(updatedBalances) != 1 || updatedBalances[0].ID != transactionEntry.ID {
+		t.Errorf("Expected only the transaction entry to be updated, but got %d entries", len(updatedBalances))
+	}
+}
+
+func TestBalanceCursor_RoundTrip(t *testing.T) {
+	entry := &database.MoneyEntry{
+		ID:          uuid.New(),
+		EffectiveAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		CreatedAt:   "2024-03-01T12:00:00.123456Z",
+	}
+
+	cursor, err := decodeCursor(encodeCursor(entry))
+	if err != nil {
+		t.Fatalf("Expected cursor to decode, but got %s", err)
+	}
+	if cursor.ID != entry.ID || !cursor.EffectiveAt.Equal(entry.EffectiveAt) || cursor.CreatedAt != entry.CreatedAt {
+		t.Errorf("Expected cursor of entry %s, but got %+v", entry.ID, cursor)
+	}
+
+	for _, value := range []string{"not a cursor", "e30"} {
+		if _, err := decodeCursor(value); err == nil {
+			t.Errorf("Expected cursor %q to be rejected", value)
+		}
 	}
 }
@@ -0,0 +1,362 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Supported recurrence frequencies
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyYearly  = "yearly"
+)
+
+// Most occurrences a schedule books per scheduler run. A schedule started far
+// in the past catches up over several runs instead of one long lock of the
+// account.
+const recurringCatchUpLimit = 100
+
+// RecurringForUpdate holds the fields of a schedule that can be changed. To
+// change when a schedule occurs, delete it and create a new one.
+type RecurringForUpdate struct {
+	Description string       `json:"description"`
+	Amount      money.Amount `json:"amount"`
+	EndDate     *time.Time   `json:"end_date"`
+}
+
+func CreateRecurring(store database.SchedulingStore, userID *uuid.UUID, recurring *database.Recurring) (*database.Recurring, ErrorResponse) {
+	account, errResp := resolveAccount(store, userID, &recurring.AccountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	recurring.AccountID = account.ID
+	recurring.UserID = *userID
+	recurring.Description = strings.TrimSpace(recurring.Description)
+	recurring.Frequency = strings.ToLower(strings.TrimSpace(recurring.Frequency))
+	if recurring.Interval == 0 {
+		recurring.Interval = 1
+	}
+	if recurring.StartDate.IsZero() {
+		recurring.StartDate = time.Now()
+	}
+	// Occurrences are matched against stored entries, so they must survive
+	// the round trip through the database unchanged
+	recurring.StartDate = recurring.StartDate.Truncate(time.Second)
+
+	if err := validateRecurring(recurring); err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	recurring.Occurrence = firstOccurrence(recurring)
+	recurring.NextRun = occurrenceAt(recurring, recurring.Occurrence)
+
+	recurringID, err := store.InsertRecurringDB(recurring)
+	if err != nil {
+		fmt.Println("Error inserting recurring schedule:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to insert recurring schedule",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return GetRecurringByID(store, userID, &recurringID)
+}
+
+func GetRecurring(store database.RecurringStore, userID *uuid.UUID) ([]*database.Recurring, ErrorResponse) {
+	schedules, err := store.SelectUserRecurringDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving recurring schedules:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve recurring schedules",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return schedules, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func GetRecurringByID(store database.RecurringStore, actorID *uuid.UUID, recurringID *uuid.UUID) (*database.Recurring, ErrorResponse) {
+	recurring, err := store.SelectRecurringByIDDB(recurringID)
+	if err != nil {
+		fmt.Println("Error retrieving recurring schedule:", err)
+		return nil, ErrorResponse{
+			Message: "Recurring schedule not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if recurring.UserID != *actorID {
+		return nil, ErrorResponse{
+			Message: "Forbidden: cannot access another user's recurring schedule",
+			Code:    http.StatusForbidden,
+		}
+	}
+
+	return recurring, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func UpdateRecurring(store database.RecurringStore, actorID *uuid.UUID, recurringID *uuid.UUID, recurringForUpdate *RecurringForUpdate) (*database.Recurring, ErrorResponse) {
+	recurring, errResp := GetRecurringByID(store, actorID, recurringID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	recurring.Description = strings.TrimSpace(recurringForUpdate.Description)
+	recurring.Amount = recurringForUpdate.Amount
+	recurring.EndDate = recurringForUpdate.EndDate
+
+	if err := validateRecurring(recurring); err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	if err := store.UpdateRecurringDB(recurring); err != nil {
+		fmt.Println("Error updating recurring schedule:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to update recurring schedule",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return recurring, errResp
+}
+
+// DeleteRecurring removes the schedule. Entries it already created are kept.
+func DeleteRecurring(store database.RecurringStore, actorID *uuid.UUID, recurringID *uuid.UUID) ErrorResponse {
+	_, errResp := GetRecurringByID(store, actorID, recurringID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+
+	if err := store.DeleteRecurringDB(recurringID); err != nil {
+		fmt.Println("Error deleting recurring schedule:", err)
+		return ErrorResponse{
+			Message: "Failed to delete recurring schedule",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return errResp
+}
+
+// MaterializeRecurring books the occurrences that are due at the given time,
+// at most recurringCatchUpLimit per schedule, and returns the number of
+// entries created. An occurrence that already has
+// an entry is skipped, so running this repeatedly or from several instances
+// never books an occurrence twice.
+func MaterializeRecurring(store database.SchedulingStore, now time.Time) int {
+	schedules, err := store.SelectDueRecurringDB(now)
+	if err != nil {
+		fmt.Println("Error retrieving due recurring schedules:", err)
+		return 0
+	}
+
+	count := 0
+	for _, recurring := range schedules {
+		count += materializeSchedule(store, recurring, now)
+	}
+	return count
+}
+
+// materializeSchedule books the due occurrences of the schedule, at most
+// recurringCatchUpLimit per run, in one change of the chain. Occurrences that
+// already have an entry are skipped. The schedule is advanced past the
+// occurrences looked at, so one far behind catches up over several runs.
+func materializeSchedule(store database.SchedulingStore, recurring *database.Recurring, now time.Time) int {
+	occurrences := []time.Time{}
+	next := *recurring
+	for isDue(&next, now) && len(occurrences) < recurringCatchUpLimit {
+		occurrences = append(occurrences, next.NextRun)
+		next.Occurrence++
+		next.NextRun = occurrenceAt(&next, next.Occurrence)
+	}
+	if len(occurrences) == 0 {
+		return 0
+	}
+
+	count, errResp := insertOccurrences(store, recurring, occurrences)
+	if errResp.Code != http.StatusOK {
+		fmt.Println("Error materializing recurring schedule", recurring.ID, ":", errResp.Message)
+		return 0
+	}
+
+	recurring.Occurrence = next.Occurrence
+	recurring.NextRun = next.NextRun
+	if err := store.UpdateRecurringDB(recurring); err != nil {
+		fmt.Println("Error advancing recurring schedule:", err)
+	}
+	return count
+}
+
+// insertOccurrences inserts an entry for every occurrence, ordered oldest
+// first, that does not have one yet and recalculates the chain once. Returns
+// the number of entries inserted.
+func insertOccurrences(store database.LedgerStore, recurring *database.Recurring, occurrences []time.Time) (int, ErrorResponse) {
+	account, errResp := resolveAccount(store, &recurring.UserID, &recurring.AccountID)
+	if errResp.Code != http.StatusOK {
+		return 0, errResp
+	}
+
+	var previous *database.MoneyEntry
+	var chain []*database.MoneyEntry
+	var newEntries []*database.MoneyEntry
+	err := store.UpdateChainDB(&account.ID, func(entries []*database.MoneyEntry) (*database.LedgerChange, error) {
+		previous = newestEntry(entries)
+
+		// Another instance may have booked some of them in the meantime
+		booked := map[int64]bool{}
+		for _, entry := range entries {
+			if entry.RecurringID != nil && *entry.RecurringID == recurring.ID {
+				booked[entry.EffectiveAt.UnixNano()] = true
+			}
+		}
+
+		newEntries = nil
+		for _, occurrence := range occurrences {
+			if booked[occurrence.UnixNano()] {
+				continue
+			}
+			newEntries = append(newEntries, &database.MoneyEntry{
+				Currency:    account.Currency,
+				EffectiveAt: occurrence,
+				UserID:      recurring.UserID,
+				AccountID:   account.ID,
+				RecurringID: &recurring.ID,
+			})
+		}
+
+		var entriesToUpdate []*database.MoneyEntry
+		chain, entriesToUpdate = insertDeltaEntries(entries, newEntries, recurring.Amount)
+		return &database.LedgerChange{NewEntries: newEntries, UpdatedEntries: entriesToUpdate}, nil
+	})
+	if err != nil {
+		fmt.Println("Error inserting recurring balances:", err)
+		return 0, ErrorResponse{
+			Message: "Failed to insert recurring balances",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	if len(newEntries) == 0 {
+		return 0, errResp
+	}
+
+	// One event for the batch, like an import. The stored entry holds the
+	// fields set by the database.
+	newest, err := store.SelectMoneyByIDDB(&newEntries[len(newEntries)-1].ID)
+	if err != nil {
+		fmt.Println("Error retrieving new recurring balance:", err)
+		return len(newEntries), errResp
+	}
+	for i, e := range chain {
+		if e.ID == newest.ID {
+			chain[i] = newest
+		}
+	}
+	publishBalanceEvent(&BalanceEvent{
+		Type:      BalanceCreated,
+		UserID:    recurring.UserID,
+		AccountID: account.ID,
+		Entry:     newest,
+		Previous:  previous,
+		Latest:    newestEntry(chain),
+		Entries:   chain,
+		At:        time.Now(),
+	})
+
+	return len(newEntries), ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func isDue(recurring *database.Recurring, now time.Time) bool {
+	if recurring.NextRun.After(now) {
+		return false
+	}
+	return recurring.EndDate == nil || !recurring.NextRun.After(*recurring.EndDate)
+}
+
+func validateRecurring(recurring *database.Recurring) error {
+	switch recurring.Frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+	default:
+		return fmt.Errorf("invalid frequency %q", recurring.Frequency)
+	}
+	if recurring.Interval < 1 {
+		return errors.New("interval must be at least 1")
+	}
+	if recurring.DayOfMonth != nil && (*recurring.DayOfMonth < 1 || *recurring.DayOfMonth > 31) {
+		return errors.New("day of month must be between 1 and 31")
+	}
+	if recurring.Amount == 0 {
+		return errors.New("amount must not be zero")
+	}
+	if recurring.EndDate != nil && recurring.EndDate.Before(recurring.StartDate) {
+		return errors.New("end date must not be before start date")
+	}
+	return nil
+}
+
+// firstOccurrence returns the index of the first occurrence that is not
+// before the start date. With a day of month earlier than the start date's
+// day, the first month is skipped.
+func firstOccurrence(recurring *database.Recurring) int {
+	n := 0
+	for occurrenceAt(recurring, n).Before(recurring.StartDate) {
+		n++
+	}
+	return n
+}
+
+// occurrenceAt returns the n-th occurrence of the schedule counted from its
+// start date. Monthly and yearly occurrences fall on the configured day of
+// month, or the start date's day, moved back to the last day of shorter
+// months.
+func occurrenceAt(recurring *database.Recurring, n int) time.Time {
+	start := recurring.StartDate
+	step := n * recurring.Interval
+
+	day := start.Day()
+	if recurring.DayOfMonth != nil {
+		day = *recurring.DayOfMonth
+	}
+
+	switch recurring.Frequency {
+	case FrequencyDaily:
+		return start.AddDate(0, 0, step)
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*step)
+	case FrequencyMonthly:
+		return dayInMonth(start, 0, step, day)
+	default:
+		return dayInMonth(start, step, 0, day)
+	}
+}
+
+func dayInMonth(start time.Time, years int, months int, day int) time.Time {
+	first := time.Date(start.Year()+years, start.Month()+time.Month(months), 1,
+		start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, lastDay)-1)
+}
@@ -0,0 +1,61 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+)
+
+func TestOccurrenceAt_MonthlyEndOfMonth(t *testing.T) {
+	day := 31
+	recurring := &database.Recurring{
+		Frequency:  FrequencyMonthly,
+		Interval:   1,
+		DayOfMonth: &day,
+		StartDate:  time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC),
+	}
+
+	expected := []time.Time{
+		time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC),
+	}
+
+	for i, occurrence := range expected {
+		if got := occurrenceAt(recurring, i); !got.Equal(occurrence) {
+			t.Errorf("Expected occurrence %d at %s, but got %s", i, occurrence, got)
+		}
+	}
+}
+
+func TestFirstOccurrence_DayBeforeStart(t *testing.T) {
+	day := 1
+	recurring := &database.Recurring{
+		Frequency:  FrequencyMonthly,
+		Interval:   1,
+		DayOfMonth: &day,
+		StartDate:  time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	n := firstOccurrence(recurring)
+	expected := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	if got := occurrenceAt(recurring, n); !got.Equal(expected) {
+		t.Errorf("Expected first occurrence at %s, but got %s", expected, got)
+	}
+}
+
+func TestOccurrenceAt_WeeklyAndYearly(t *testing.T) {
+	start := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+
+	weekly := &database.Recurring{Frequency: FrequencyWeekly, Interval: 2, StartDate: start}
+	if got := occurrenceAt(weekly, 2); !got.Equal(time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected weekly occurrence %s", got)
+	}
+
+	yearly := &database.Recurring{Frequency: FrequencyYearly, Interval: 1, StartDate: start}
+	if got := occurrenceAt(yearly, 1); !got.Equal(time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected yearly occurrence %s", got)
+	}
+}
@@ -0,0 +1,189 @@
+package logic
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Supported report periods. Periods are calendar months and years in UTC.
+const (
+	ReportPeriodMonthly = BudgetPeriodMonthly
+	ReportPeriodYearly  = "yearly"
+)
+
+// Most periods a single report may have
+const maxReportPeriods = 1200
+
+// ReportPeriod sums up the entries of an account effective from Start up to
+// but not including End. Inflow and Outflow are the increases and decreases
+// of the balance, BudgetAccrued is the share of the inflow that went to the
+// budget by the ratio and Savings the rest of the inflow, which was retained.
+type ReportPeriod struct {
+	Start           time.Time    `json:"start"`
+	End             time.Time    `json:"end"`
+	StartingBalance money.Amount `json:"starting_balance"`
+	EndingBalance   money.Amount `json:"ending_balance"`
+	StartingBudget  money.Amount `json:"starting_budget"`
+	EndingBudget    money.Amount `json:"ending_budget"`
+	Inflow          money.Amount `json:"inflow"`
+	Outflow         money.Amount `json:"outflow"`
+	BudgetAccrued   money.Amount `json:"budget_accrued"`
+	Savings         money.Amount `json:"savings"`
+	Entries         int          `json:"entries"`
+}
+
+// Report holds the periods of an account oldest first and their total.
+type Report struct {
+	AccountID uuid.UUID      `json:"account_id"`
+	Currency  string         `json:"currency"`
+	Period    string         `json:"period"`
+	Total     ReportPeriod   `json:"total"`
+	Periods   []ReportPeriod `json:"periods"`
+}
+
+// GetReport aggregates the entries of the account per month or year. Without
+// from the report starts with the account's first entry, without to it ends
+// with the current period. Without an account the user's default account is
+// used.
+func GetReport(store database.LedgerStore, userID *uuid.UUID, accountID *uuid.UUID, period string, from *time.Time, to *time.Time) (*Report, ErrorResponse) {
+	if period == "" {
+		period = ReportPeriodMonthly
+	}
+	if period != ReportPeriodMonthly && period != ReportPeriodYearly {
+		return nil, ErrorResponse{
+			Message: fmt.Sprintf("Invalid period %q", period),
+			Code:    http.StatusBadRequest,
+		}
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, ErrorResponse{
+			Message: "From must be before to",
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	account, errResp := resolveAccount(store, userID, accountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	entries, errResp := GetAccountBalances(store, &account.ID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	var start time.Time
+	if from != nil {
+		start = *from
+	} else if len(entries) > 0 {
+		start = entries[len(entries)-1].EffectiveAt
+	} else {
+		start = end
+	}
+
+	periods := calculateReport(entries, period, start.UTC(), end.UTC())
+	if len(periods) > maxReportPeriods {
+		return nil, ErrorResponse{
+			Message: fmt.Sprintf("Report has more than %d periods", maxReportPeriods),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	return &Report{
+		AccountID: account.ID,
+		Currency:  account.Currency,
+		Period:    period,
+		Total:     reportTotal(periods),
+		Periods:   periods,
+	}, errResp
+}
+
+// calculateReport walks the periods containing start up to the one containing
+// end and sums up the changes of the balance between consecutive entries. The
+// first entry of an account opens it and is not counted as inflow. Entries
+// are ordered newest first.
+func calculateReport(entries []*database.MoneyEntry, period string, start time.Time, end time.Time) []ReportPeriod {
+	chain := slices.Clone(entries)
+	slices.Reverse(chain)
+
+	periods := []ReportPeriod{}
+	var last *database.MoneyEntry
+	i := 0
+	for from := periodStart(period, start); from.Before(end) || len(periods) == 0; from = nextPeriod(period, from) {
+		// One more than allowed tells the caller the range is too long
+		if len(periods) > maxReportPeriods {
+			break
+		}
+		report := ReportPeriod{Start: from, End: nextPeriod(period, from)}
+
+		for i < len(chain) && chain[i].EffectiveAt.Before(report.Start) {
+			last = chain[i]
+			i++
+		}
+		if last != nil {
+			report.StartingBalance = last.Balance
+			report.StartingBudget = last.Budget
+		}
+
+		for i < len(chain) && chain[i].EffectiveAt.Before(report.End) {
+			entry := chain[i]
+			if last != nil {
+				diff := entry.Balance - last.Balance
+				if diff > 0 {
+					report.Inflow += diff
+					report.BudgetAccrued += entry.Budget - last.Budget
+				} else {
+					report.Outflow -= diff
+				}
+			}
+			last = entry
+			report.Entries++
+			i++
+		}
+		if last != nil {
+			report.EndingBalance = last.Balance
+			report.EndingBudget = last.Budget
+		}
+
+		report.Savings = report.Inflow - report.BudgetAccrued
+		periods = append(periods, report)
+	}
+
+	return periods
+}
+
+// reportTotal sums up the periods into one from the first period's start to
+// the last period's end.
+func reportTotal(periods []ReportPeriod) ReportPeriod {
+	if len(periods) == 0 {
+		return ReportPeriod{}
+	}
+
+	first, last := periods[0], periods[len(periods)-1]
+	total := ReportPeriod{
+		Start:           first.Start,
+		End:             last.End,
+		StartingBalance: first.StartingBalance,
+		StartingBudget:  first.StartingBudget,
+		EndingBalance:   last.EndingBalance,
+		EndingBudget:    last.EndingBudget,
+	}
+	for _, period := range periods {
+		total.Inflow += period.Inflow
+		total.Outflow += period.Outflow
+		total.BudgetAccrued += period.BudgetAccrued
+		total.Savings += period.Savings
+		total.Entries += period.Entries
+	}
+	return total
+}
@@ -0,0 +1,60 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+)
+
+func TestCalculateReport_Monthly(t *testing.T) {
+	ratio := money.MustParseRate("0.5")
+	chain := []*database.MoneyEntry{}
+	for _, entry := range []struct {
+		day     time.Time
+		balance int64
+	}{
+		{time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), 1000},
+		{time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC), 3000},
+		{time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC), 2400},
+		{time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC), 2200},
+		{time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), 4200},
+	} {
+		chain, _ = insertBalanceEntry(chain, &database.MoneyEntry{Balance: money.FromInt(entry.balance), Ratio: ratio, EffectiveAt: entry.day})
+	}
+
+	periods := calculateReport(chain, ReportPeriodMonthly, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC))
+	if len(periods) != 4 {
+		t.Fatalf("Expected 4 months, but got %d", len(periods))
+	}
+
+	expected := []ReportPeriod{
+		// The first entry opens the account
+		{StartingBalance: 0, EndingBalance: money.FromInt(3000), Inflow: money.FromInt(2000), BudgetAccrued: money.FromInt(1000), Savings: money.FromInt(1000), EndingBudget: money.FromInt(1000), Entries: 2},
+		{StartingBalance: money.FromInt(3000), EndingBalance: money.FromInt(2200), Outflow: money.FromInt(800), StartingBudget: money.FromInt(1000), EndingBudget: money.FromInt(200), Entries: 2},
+		// A month without entries keeps the balance
+		{StartingBalance: money.FromInt(2200), EndingBalance: money.FromInt(2200), StartingBudget: money.FromInt(200), EndingBudget: money.FromInt(200)},
+		{StartingBalance: money.FromInt(2200), EndingBalance: money.FromInt(4200), Inflow: money.FromInt(2000), BudgetAccrued: money.FromInt(1000), Savings: money.FromInt(1000), StartingBudget: money.FromInt(200), EndingBudget: money.FromInt(1200), Entries: 1},
+	}
+	for i, e := range expected {
+		period := periods[i]
+		e.Start, e.End = period.Start, period.End
+		if period != e {
+			t.Errorf("Expected month %d %+v, but got %+v", i+1, e, period)
+		}
+	}
+	if !periods[1].Start.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) || !periods[1].End.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected February, but got %s to %s", periods[1].Start, periods[1].End)
+	}
+
+	total := reportTotal(periods)
+	if total.Inflow != money.FromInt(4000) || total.Outflow != money.FromInt(800) || total.EndingBalance != money.FromInt(4200) || total.Entries != 5 {
+		t.Errorf("Unexpected total %+v", total)
+	}
+
+	yearly := calculateReport(chain, ReportPeriodYearly, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC))
+	if len(yearly) != 1 || yearly[0].Inflow != total.Inflow || !yearly[0].End.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected one year matching the total, but got %+v", yearly)
+	}
+}
@@ -0,0 +1,213 @@
+package logic
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+// RefreshRequest renews the tokens of a session.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshSession exchanges a refresh token for a new access token and a new
+// refresh token of the same session. Each refresh token can be used once.
+// Using one again means it was stolen, so the whole session is revoked and
+// both the thief and the user have to log in again.
+func RefreshSession(store database.SessionAuditStore, signer *AccessTokenSigner, request *RefreshRequest) (AuthTokens, ErrorResponse) {
+	var tokens AuthTokens
+	unauthorized := ErrorResponse{
+		Message: "Invalid or expired refresh token",
+		Code:    http.StatusUnauthorized,
+	}
+	reused := ErrorResponse{
+		Message: "Refresh token reused, session revoked",
+		Code:    http.StatusUnauthorized,
+	}
+
+	refreshToken, err := validateToken(store, request.RefreshToken, TokenPurposeRefresh)
+	if err != nil {
+		fmt.Println("Refresh token validation failed:", err.Error())
+		return tokens, unauthorized
+	}
+	if refreshToken.SessionID == nil {
+		return tokens, unauthorized
+	}
+
+	if refreshToken.UsedAt != nil {
+		revokeReusedSession(store, signer, refreshToken)
+		return tokens, reused
+	}
+
+	now := time.Now()
+	var token database.Token
+	nextRefreshToken := GenerateRefreshToken(&refreshToken.UserID)
+	sessionTokens := []*database.Token{&nextRefreshToken}
+	if signer == nil {
+		token = GenerateSessionToken(&refreshToken.UserID)
+		sessionTokens = append(sessionTokens, &token)
+	} else {
+		token, err = signer.Sign(refreshToken.UserID, *refreshToken.SessionID, now)
+		if err != nil {
+			fmt.Println("Failed to sign access token:", err.Error())
+			return tokens, ErrorResponse{
+				Message: "Internal server error",
+				Code:    http.StatusInternalServerError,
+			}
+		}
+	}
+
+	rotated, err := store.RotateRefreshTokenDB(refreshToken.Prefix, now, refreshToken.SessionID, sessionTokens...)
+	if err != nil {
+		fmt.Println("Failed to rotate refresh token:", err.Error())
+		return tokens, ErrorResponse{
+			Message: "Internal server error",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	// Another request used the token since it was read
+	if !rotated {
+		revokeReusedSession(store, signer, refreshToken)
+		return tokens, reused
+	}
+	if err := store.TouchSessionDB(refreshToken.SessionID, now, now.Add(-sessionTouchInterval)); err != nil {
+		fmt.Println("Failed to update session:", err)
+	}
+
+	return newAuthTokens(token, nextRefreshToken), ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func revokeReusedSession(store database.SessionAuditStore, signer *AccessTokenSigner, refreshToken *database.Token) {
+	fmt.Println("Refresh token reused, revoking session", refreshToken.SessionID)
+	if err := store.DeleteSessionDB(refreshToken.SessionID); err != nil {
+		fmt.Println("Failed to revoke session:", err.Error())
+	}
+	signer.RevokeSessions([]uuid.UUID{*refreshToken.SessionID}, time.Now())
+	recordAudit(store, &refreshToken.UserID, AuditRefreshReused, refreshToken.SessionID.String())
+}
+
+func newAuthTokens(token database.Token, refreshToken database.Token) AuthTokens {
+	return AuthTokens{
+		Token:         token,
+		RefreshToken:  refreshToken.Token,
+		RefreshExpiry: refreshToken.Expiry,
+	}
+}
+
+// GetSessions returns the sessions of the user and marks the current one.
+func GetSessions(store database.SessionStore, userID *uuid.UUID, currentID *uuid.UUID) ([]*database.Session, ErrorResponse) {
+	sessions, err := store.SelectUserSessionsDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving sessions:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve sessions",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	for _, session := range sessions {
+		session.Current = currentID != nil && session.ID == *currentID
+	}
+	return sessions, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// RevokeSession logs the device of the session out.
+func RevokeSession(store database.AuthStore, signer *AccessTokenSigner, actorID *uuid.UUID, sessionID *uuid.UUID) ErrorResponse {
+	session, err := store.SelectSessionByIDDB(sessionID)
+	if err != nil {
+		fmt.Println("Error retrieving session:", err)
+		return ErrorResponse{
+			Message: "Session not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if session.UserID != *actorID {
+		return ErrorResponse{
+			Message: "Forbidden: cannot access another user's session",
+			Code:    http.StatusForbidden,
+		}
+	}
+
+	if err := store.DeleteSessionDB(sessionID); err != nil {
+		fmt.Println("Error deleting session:", err)
+		return ErrorResponse{
+			Message: "Failed to revoke session",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	signer.RevokeSessions([]uuid.UUID{*sessionID}, time.Now())
+	recordAudit(store, actorID, AuditSessionRevoked, session.DeviceName)
+
+	return ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// RevokeAllSessions logs the user out everywhere. With keepID that session
+// stays logged in.
+func RevokeAllSessions(store database.AuthStore, signer *AccessTokenSigner, userID *uuid.UUID, keepID *uuid.UUID) ErrorResponse {
+	sessionIDs, err := store.DeleteUserSessionsDB(userID, keepID)
+	if err != nil {
+		fmt.Println("Error deleting sessions:", err)
+		return ErrorResponse{
+			Message: "Failed to revoke sessions",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	signer.RevokeSessions(sessionIDs, time.Now())
+	recordAudit(store, userID, AuditSessionsRevoked, "")
+
+	return ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// SessionActive reports whether the session still exists. It is gone once
+// it was revoked or its user was erased. Errors other than a missing session
+// are logged and treated as active, so a database hiccup does not end
+// long-lived streams.
+func SessionActive(store database.SessionStore, sessionID *uuid.UUID) bool {
+	_, err := store.SelectSessionByIDDB(sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		fmt.Println("Error retrieving session:", err)
+	}
+	return true
+}
+
+// deviceName is the name the device logged in with, or its user agent.
+func deviceName(loginReq *LoginRequest) string {
+	name := strings.TrimSpace(loginReq.DeviceName)
+	if name == "" {
+		name = strings.TrimSpace(loginReq.UserAgent)
+	}
+	if name == "" {
+		name = "Unknown device"
+	}
+	return truncate(name, maxDeviceNameLength)
+}
+
+func truncate(s string, length int) string {
+	if runes := []rune(s); len(runes) > length {
+		return string(runes[:length])
+	}
+	return s
+}
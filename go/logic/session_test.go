@@ -0,0 +1,314 @@
+package logic
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+func (store *memoryTokenStore) InsertSessionDB(session *database.Session, tokens ...*database.Token) error {
+	session.ID = uuid.New()
+	session.LastUsedAt = time.Now()
+	store.sessions[session.ID] = session
+	return store.InsertSessionTokensDB(&session.ID, tokens...)
+}
+
+func (store *memoryTokenStore) InsertSessionTokensDB(sessionID *uuid.UUID, tokens ...*database.Token) error {
+	if _, ok := store.sessions[*sessionID]; !ok {
+		return errors.New("session does not exist")
+	}
+	for _, token := range tokens {
+		token.SessionID = sessionID
+		if err := store.InsertToken(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *memoryTokenStore) RotateRefreshTokenDB(prefix string, at time.Time, sessionID *uuid.UUID, tokens ...*database.Token) (bool, error) {
+	token, ok := store.tokens[prefix]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+	if store.failInserts {
+		return false, errors.New("insert failed")
+	}
+	if err := store.InsertSessionTokensDB(sessionID, tokens...); err != nil {
+		return false, err
+	}
+	token.UsedAt = &at
+	store.tokens[prefix] = token
+	return true, nil
+}
+
+func (store *memoryTokenStore) SelectSessionByIDDB(id *uuid.UUID) (*database.Session, error) {
+	session, ok := store.sessions[*id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return session, nil
+}
+
+func (store *memoryTokenStore) SelectUserSessionsDB(userID *uuid.UUID) ([]*database.Session, error) {
+	sessions := []*database.Session{}
+	for _, session := range store.sessions {
+		if session.UserID == *userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (store *memoryTokenStore) TouchSessionDB(id *uuid.UUID, at time.Time, notBefore time.Time) error {
+	if session, ok := store.sessions[*id]; ok && session.LastUsedAt.Before(notBefore) {
+		session.LastUsedAt = at
+	}
+	return nil
+}
+
+func (store *memoryTokenStore) DeleteSessionDB(id *uuid.UUID) error {
+	delete(store.sessions, *id)
+	for prefix, token := range store.tokens {
+		if token.SessionID != nil && *token.SessionID == *id {
+			delete(store.tokens, prefix)
+		}
+	}
+	return nil
+}
+
+func (store *memoryTokenStore) DeleteUserSessionsDB(userID *uuid.UUID, keep *uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id, session := range store.sessions {
+		if session.UserID == *userID && (keep == nil || id != *keep) {
+			store.DeleteSessionDB(&id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func TestValidateSession_ConcurrentSessions(t *testing.T) {
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+
+	phoneToken := GenerateSessionToken(&userID)
+	phone := &database.Session{DeviceName: "Phone", UserID: userID}
+	store.InsertSessionDB(phone, &phoneToken)
+	browserToken := GenerateSessionToken(&userID)
+	browser := &database.Session{DeviceName: "Browser", UserID: userID}
+	store.InsertSessionDB(browser, &browserToken)
+
+	for _, token := range []database.Token{phoneToken, browserToken} {
+		validated, err := ValidateSession(store, token.Token)
+		if err != nil {
+			t.Fatalf("Expected both sessions to be valid, but got %v", err)
+		}
+		if validated.SessionID == nil || *validated.SessionID != *token.SessionID {
+			t.Errorf("Expected session %s, but got %v", token.SessionID, validated.SessionID)
+		}
+	}
+
+	// Revoking one session logs out only that device
+	store.DeleteSessionDB(&phone.ID)
+	if _, err := ValidateSession(store, phoneToken.Token); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("Expected error %v for a revoked session, but got %v", ErrTokenInvalid, err)
+	}
+	if _, err := ValidateSession(store, browserToken.Token); err != nil {
+		t.Errorf("Expected other session to stay valid, but got %v", err)
+	}
+}
+
+func TestValidateSession_TouchesSession(t *testing.T) {
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+	token := GenerateSessionToken(&userID)
+	session := &database.Session{UserID: userID}
+	store.InsertSessionDB(session, &token)
+
+	stale := time.Now().Add(-time.Hour)
+	session.LastUsedAt = stale
+	if _, err := ValidateSession(store, token.Token); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if !session.LastUsedAt.After(stale) {
+		t.Errorf("Expected last use to be updated, but got %s", session.LastUsedAt)
+	}
+
+	recent := time.Now().Add(-time.Second)
+	session.LastUsedAt = recent
+	ValidateSession(store, token.Token)
+	if !session.LastUsedAt.Equal(recent) {
+		t.Errorf("Expected last use within %s not to be updated, but got %s", sessionTouchInterval, session.LastUsedAt)
+	}
+}
+
+func TestGetSessions_MarksCurrent(t *testing.T) {
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+	var current uuid.UUID
+	for i := range 3 {
+		token := GenerateSessionToken(&userID)
+		session := &database.Session{UserID: userID}
+		store.InsertSessionDB(session, &token)
+		if i == 1 {
+			current = session.ID
+		}
+	}
+
+	sessions, errResp := GetSessions(store, &userID, &current)
+	if errResp.Message != "" {
+		t.Fatalf("Expected no error, but got %s", errResp.Message)
+	}
+	marked := 0
+	for _, session := range sessions {
+		if session.Current {
+			marked++
+			if session.ID != current {
+				t.Errorf("Expected session %s to be current, but got %s", current, session.ID)
+			}
+		}
+	}
+	if marked != 1 {
+		t.Errorf("Expected one current session, but got %d", marked)
+	}
+}
+
+func TestDeviceName(t *testing.T) {
+	tests := []struct {
+		request  LoginRequest
+		expected string
+	}{
+		{LoginRequest{DeviceName: "  Pixel 8 "}, "Pixel 8"},
+		{LoginRequest{UserAgent: "Mozilla/5.0"}, "Mozilla/5.0"},
+		{LoginRequest{}, "Unknown device"},
+		{LoginRequest{DeviceName: strings.Repeat("ä", 150)}, strings.Repeat("ä", maxDeviceNameLength)},
+	}
+
+	for _, test := range tests {
+		if got := deviceName(&test.request); got != test.expected {
+			t.Errorf("Expected device name %s, but got %s", test.expected, got)
+		}
+	}
+}
+
+func TestRefreshSession_Rotates(t *testing.T) {
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+	access := GenerateSessionToken(&userID)
+	refresh := GenerateRefreshToken(&userID)
+	session := &database.Session{UserID: userID}
+	store.InsertSessionDB(session, &access, &refresh)
+
+	if time.Until(access.Expiry) > time.Hour {
+		t.Errorf("Expected a short-lived access token, but it expires at %s", access.Expiry)
+	}
+	if _, err := ValidateSession(store, refresh.Token); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("Expected a refresh token not to be accepted as access token, but got %v", err)
+	}
+
+	tokens, errResp := RefreshSession(store, nil, &RefreshRequest{RefreshToken: refresh.Token})
+	if errResp.Message != "" {
+		t.Fatalf("Expected no error, but got %s", errResp.Message)
+	}
+	if tokens.RefreshToken == refresh.Token || tokens.Token.Token == access.Token {
+		t.Errorf("Expected new tokens, but got the old ones")
+	}
+	if tokens.SessionID == nil || *tokens.SessionID != session.ID {
+		t.Errorf("Expected tokens of session %s, but got %v", session.ID, tokens.SessionID)
+	}
+	if _, err := ValidateSession(store, tokens.Token.Token); err != nil {
+		t.Errorf("Expected new access token to be valid, but got %v", err)
+	}
+
+	again, errResp := RefreshSession(store, nil, &RefreshRequest{RefreshToken: tokens.RefreshToken})
+	if errResp.Message != "" {
+		t.Fatalf("Expected the new refresh token to work, but got %s", errResp.Message)
+	}
+	if again.RefreshToken == tokens.RefreshToken {
+		t.Errorf("Expected the refresh token to rotate again")
+	}
+}
+
+func TestRefreshSession_ReuseRevokesFamily(t *testing.T) {
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+	access := GenerateSessionToken(&userID)
+	refresh := GenerateRefreshToken(&userID)
+	session := &database.Session{UserID: userID}
+	store.InsertSessionDB(session, &access, &refresh)
+	other := GenerateSessionToken(&userID)
+	store.InsertSessionDB(&database.Session{UserID: userID}, &other)
+
+	tokens, errResp := RefreshSession(store, nil, &RefreshRequest{RefreshToken: refresh.Token})
+	if errResp.Message != "" {
+		t.Fatalf("Expected no error, but got %s", errResp.Message)
+	}
+
+	// Replaying the used refresh token revokes the session and all its tokens
+	_, errResp = RefreshSession(store, nil, &RefreshRequest{RefreshToken: refresh.Token})
+	if errResp.Code != 401 {
+		t.Errorf("Expected status 401 on reuse, but got %d", errResp.Code)
+	}
+	if _, ok := store.sessions[session.ID]; ok {
+		t.Errorf("Expected session to be revoked on reuse")
+	}
+	for _, token := range []string{access.Token, tokens.Token.Token} {
+		if _, err := ValidateSession(store, token); err == nil {
+			t.Errorf("Expected access tokens of the revoked session to be invalid")
+		}
+	}
+	if _, errResp := RefreshSession(store, nil, &RefreshRequest{RefreshToken: tokens.RefreshToken}); errResp.Code != 401 {
+		t.Errorf("Expected the rotated refresh token to be revoked, but got status %d", errResp.Code)
+	}
+	if len(store.audit) != 1 || store.audit[0].Action != AuditRefreshReused {
+		t.Errorf("Expected reuse to be audited, but got %v", store.audit)
+	}
+
+	if _, err := ValidateSession(store, other.Token); err != nil {
+		t.Errorf("Expected other sessions to stay valid, but got %v", err)
+	}
+}
+
+func TestSessionActive(t *testing.T) {
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+	token := GenerateSessionToken(&userID)
+	session := &database.Session{UserID: userID}
+	store.InsertSessionDB(session, &token)
+
+	if !SessionActive(store, &session.ID) {
+		t.Errorf("Expected session to be active")
+	}
+	store.DeleteSessionDB(&session.ID)
+	if SessionActive(store, &session.ID) {
+		t.Errorf("Expected revoked session not to be active")
+	}
+}
+
+func TestRefreshSession_FailedRotationKeepsToken(t *testing.T) {
+	store := newMemoryTokenStore()
+	userID := uuid.New()
+	refresh := GenerateRefreshToken(&userID)
+	session := &database.Session{UserID: userID}
+	store.InsertSessionDB(session, &refresh)
+
+	store.failInserts = true
+	if _, errResp := RefreshSession(store, nil, &RefreshRequest{RefreshToken: refresh.Token}); errResp.Code != 500 {
+		t.Fatalf("Expected status 500, but got %d", errResp.Code)
+	}
+
+	// The retry is not mistaken for reuse
+	store.failInserts = false
+	if _, errResp := RefreshSession(store, nil, &RefreshRequest{RefreshToken: refresh.Token}); errResp.Message != "" {
+		t.Errorf("Expected the retry to succeed, but got %s", errResp.Message)
+	}
+	if _, ok := store.sessions[session.ID]; !ok {
+		t.Errorf("Expected the session to stay active")
+	}
+}
@@ -0,0 +1,101 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+// Events buffered per subscriber before the oldest is dropped. Every event
+// carries the whole chain, so a slow client only misses intermediate states.
+const streamBufferSize = 16
+
+// StreamEvent is the data of a balance event sent to the user's clients.
+// Entries is the recomputed chain of the account, newest first.
+type StreamEvent struct {
+	Type      string                 `json:"type"`
+	AccountID uuid.UUID              `json:"account_id"`
+	Entry     *database.MoneyEntry   `json:"entry"`
+	Entries   []*database.MoneyEntry `json:"entries"`
+	At        time.Time              `json:"at"`
+}
+
+// BalanceHub fans balance events out to the open streams of each user.
+type BalanceHub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan *BalanceEvent]struct{}
+}
+
+func NewBalanceHub() *BalanceHub {
+	return &BalanceHub{
+		subscribers: map[uuid.UUID]map[chan *BalanceEvent]struct{}{},
+	}
+}
+
+// Subscribe opens a stream of the user's balance events. The returned
+// function closes it and has to be called once the stream is no longer read.
+func (hub *BalanceHub) Subscribe(userID uuid.UUID) (<-chan *BalanceEvent, func()) {
+	events := make(chan *BalanceEvent, streamBufferSize)
+
+	hub.mu.Lock()
+	if hub.subscribers[userID] == nil {
+		hub.subscribers[userID] = map[chan *BalanceEvent]struct{}{}
+	}
+	hub.subscribers[userID][events] = struct{}{}
+	hub.mu.Unlock()
+
+	return events, func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		delete(hub.subscribers[userID], events)
+		if len(hub.subscribers[userID]) == 0 {
+			delete(hub.subscribers, userID)
+		}
+	}
+}
+
+// Publish sends the event to every stream of its user without blocking. It
+// is a BalanceListener.
+func (hub *BalanceHub) Publish(event *BalanceEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for events := range hub.subscribers[event.UserID] {
+		select {
+		case events <- event:
+		default:
+			// Drop the oldest event to make room for the newest
+			select {
+			case <-events:
+			default:
+			}
+			events <- event
+		}
+	}
+}
+
+// WriteStreamEvent writes the event in the Server-Sent Events format, named
+// after the event type.
+func WriteStreamEvent(w io.Writer, event *BalanceEvent) error {
+	entries := event.Entries
+	if entries == nil {
+		entries = []*database.MoneyEntry{}
+	}
+	data, err := json.Marshal(StreamEvent{
+		Type:      event.Type,
+		AccountID: event.AccountID,
+		Entry:     event.Entry,
+		Entries:   entries,
+		At:        event.At,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
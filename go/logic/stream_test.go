@@ -0,0 +1,120 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+func TestBalanceHub_PublishesToUser(t *testing.T) {
+	hub := NewBalanceHub()
+	userID, otherID := uuid.New(), uuid.New()
+
+	events, unsubscribe := hub.Subscribe(userID)
+	otherEvents, unsubscribeOther := hub.Subscribe(otherID)
+	defer unsubscribeOther()
+
+	event := &BalanceEvent{Type: BalanceCreated, UserID: userID}
+	hub.Publish(event)
+
+	select {
+	case received := <-events:
+		if received != event {
+			t.Errorf("Expected event %v, but got %v", event, received)
+		}
+	default:
+		t.Errorf("Expected an event for the user, but got none")
+	}
+	select {
+	case received := <-otherEvents:
+		t.Errorf("Expected no event for another user, but got %v", received)
+	default:
+	}
+
+	unsubscribe()
+	hub.Publish(event)
+	select {
+	case received := <-events:
+		t.Errorf("Expected no event after unsubscribing, but got %v", received)
+	default:
+	}
+	if _, ok := hub.subscribers[userID]; ok {
+		t.Errorf("Expected user to be removed after the last stream closed")
+	}
+}
+
+func TestBalanceHub_DropsOldest(t *testing.T) {
+	hub := NewBalanceHub()
+	userID := uuid.New()
+	events, unsubscribe := hub.Subscribe(userID)
+	defer unsubscribe()
+
+	var last *BalanceEvent
+	for range streamBufferSize + 3 {
+		last = &BalanceEvent{Type: BalanceUpdated, UserID: userID}
+		hub.Publish(last)
+	}
+
+	if len(events) != streamBufferSize {
+		t.Fatalf("Expected %d buffered events, but got %d", streamBufferSize, len(events))
+	}
+	var received *BalanceEvent
+	for len(events) > 0 {
+		received = <-events
+	}
+	if received != last {
+		t.Errorf("Expected the newest event to be kept")
+	}
+}
+
+func TestWriteStreamEvent(t *testing.T) {
+	accountID := uuid.New()
+	entries := []*database.MoneyEntry{
+		{ID: uuid.New(), Balance: money.FromInt(900), Budget: money.FromInt(50), AccountID: accountID},
+		{ID: uuid.New(), Balance: money.FromInt(1000), Budget: money.FromInt(100), AccountID: accountID},
+	}
+	event := &BalanceEvent{
+		Type:      BalanceDeleted,
+		AccountID: accountID,
+		Entry:     entries[0],
+		Entries:   entries,
+		At:        time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteStreamEvent(&buffer, event); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	output := buffer.String()
+	if !strings.HasPrefix(output, "event: balance.deleted\ndata: ") || !strings.HasSuffix(output, "\n\n") {
+		t.Fatalf("Expected a balance.deleted event, but got %q", output)
+	}
+	data := strings.TrimSuffix(strings.TrimPrefix(output, "event: balance.deleted\ndata: "), "\n\n")
+	if strings.Contains(data, "\n") {
+		t.Errorf("Expected data on a single line, but got %q", data)
+	}
+
+	var decoded StreamEvent
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, but got %v", err)
+	}
+	if decoded.AccountID != accountID {
+		t.Errorf("Expected account %s, but got %s", accountID, decoded.AccountID)
+	}
+	if len(decoded.Entries) != 2 || decoded.Entries[0].Balance != money.FromInt(900) {
+		t.Errorf("Expected the recomputed entries newest first, but got %v", decoded.Entries)
+	}
+
+	buffer.Reset()
+	WriteStreamEvent(&buffer, &BalanceEvent{Type: BalanceDeleted})
+	if !strings.Contains(buffer.String(), `"entries":[]`) {
+		t.Errorf("Expected an empty entry list, but got %q", buffer.String())
+	}
+}
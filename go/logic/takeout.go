@@ -0,0 +1,178 @@
+package logic
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+// TakeoutProfile is the user's profile as included in a takeout, without the
+// password hash.
+type TakeoutProfile struct {
+	ID                 uuid.UUID  `json:"id"`
+	Username           string     `json:"username"`
+	Email              string     `json:"email"`
+	EmailVerified      bool       `json:"email_verified"`
+	CreatedAt          string     `json:"created_at"`
+	ErasureScheduledAt *time.Time `json:"erasure_scheduled_at"`
+}
+
+// Takeout is a copy of everything stored about a user, written as a zip
+// archive with one JSON file per kind of record. Entries are streamed from
+// the database while writing, like in an export.
+type Takeout struct {
+	Profile   TakeoutProfile
+	Roles     []database.Role
+	Sessions  []*database.Session
+	AuditLog  []*database.AuditEvent
+	Recurring []*database.Recurring
+	Goals     []*database.Goal
+	Budgets   []*database.Budget
+	Alerts    []*database.AlertRule
+	Webhooks  []*database.Webhook
+	Ledger    *Export
+}
+
+func NewTakeout(store database.TakeoutStore, userID *uuid.UUID) (*Takeout, ErrorResponse) {
+	user, err := store.SelectUserByIDDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving user:", err)
+		return nil, ErrorResponse{
+			Message: "User not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+	erasureAt, err := store.SelectUserErasureDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving erasure:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve erasure",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	roles, err := store.GetUserRolesDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving roles:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve roles",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	sessions, errResp := GetSessions(store, userID, nil)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	// Recorded first so the takeout includes itself
+	recordAudit(store, userID, AuditTakeout, "")
+
+	auditLog, errResp := GetAuditLog(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	recurring, errResp := GetRecurring(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	goals, errResp := GetGoals(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	budgets, errResp := GetBudgets(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	alerts, errResp := GetAlertRules(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	webhooks, errResp := GetWebhooks(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	ledger, errResp := NewExport(store, userID, nil, ExportFormatJSON)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	return &Takeout{
+		Profile: TakeoutProfile{
+			ID:                 user.ID,
+			Username:           user.Username,
+			Email:              user.Email,
+			EmailVerified:      user.EmailVerified,
+			CreatedAt:          user.CreatedAt,
+			ErasureScheduledAt: erasureAt,
+		},
+		Roles:     roles,
+		Sessions:  sessions,
+		AuditLog:  auditLog,
+		Recurring: recurring,
+		Goals:     goals,
+		Budgets:   budgets,
+		Alerts:    alerts,
+		Webhooks:  webhooks,
+		Ledger:    ledger,
+	}, errResp
+}
+
+// FileName is the name the archive is downloaded as.
+func (takeout *Takeout) FileName() string {
+	return fmt.Sprintf("money_manager_takeout_%s.zip", takeout.Ledger.ExportedAt.Format("20060102"))
+}
+
+// Write writes the zip archive. The accounts, transactions and entries are in
+// ledger.json in the layout of the JSON export.
+func (takeout *Takeout) Write(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", takeout.Profile},
+		{"roles.json", takeout.Roles},
+		{"sessions.json", takeout.Sessions},
+		{"audit_log.json", takeout.AuditLog},
+		{"recurring.json", takeout.Recurring},
+		{"goals.json", takeout.Goals},
+		{"budgets.json", takeout.Budgets},
+		{"alert_rules.json", takeout.Alerts},
+		{"webhooks.json", takeout.Webhooks},
+	}
+	for _, file := range files {
+		fileWriter, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: takeout.Ledger.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(fileWriter)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	fileWriter, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     "ledger.json",
+		Method:   zip.Deflate,
+		Modified: takeout.Ledger.ExportedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if err := takeout.Ledger.Write(fileWriter); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
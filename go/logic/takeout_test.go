@@ -0,0 +1,65 @@
+package logic
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+func TestTakeout_Write(t *testing.T) {
+	export, _ := testExport(ExportFormatJSON)
+	takeout := &Takeout{
+		Profile:  TakeoutProfile{ID: uuid.New(), Username: "alice", Email: "alice@example.com"},
+		Roles:    []database.Role{{ID: uuid.New(), Name: "user"}},
+		AuditLog: []*database.AuditEvent{{ID: uuid.New(), Action: AuditTakeout}},
+		Ledger:   export,
+	}
+
+	var buffer bytes.Buffer
+	if err := takeout.Write(&buffer); err != nil {
+		t.Fatalf("Expected takeout to succeed, but got %s", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("Expected valid zip archive, but got %s", err)
+	}
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name], _ = io.ReadAll(reader)
+		reader.Close()
+	}
+
+	for _, name := range []string{"profile.json", "roles.json", "sessions.json", "audit_log.json", "recurring.json", "goals.json", "budgets.json", "alert_rules.json", "webhooks.json", "ledger.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in takeout", name)
+		}
+	}
+
+	var profile map[string]any
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+		t.Fatalf("Expected valid profile, but got %s", err)
+	}
+	if profile["username"] != "alice" {
+		t.Errorf("Expected username alice, but got %v", profile["username"])
+	}
+	if _, ok := profile["password"]; ok {
+		t.Errorf("Expected profile without password hash")
+	}
+
+	var ledger struct {
+		Entries []*database.MoneyEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(files["ledger.json"], &ledger); err != nil || len(ledger.Entries) != 2 {
+		t.Errorf("Expected ledger with 2 entries, but got %d (%v)", len(ledger.Entries), err)
+	}
+}
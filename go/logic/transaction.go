@@ -0,0 +1,281 @@
+package logic
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+// Ratio used for the balance entry of a transaction when the user has no
+// previous entry to inherit it from.
+var defaultRatio = money.MustParseRate("0.5")
+
+type TransactionForUpdate struct {
+	ID       uuid.UUID    `json:"id"`
+	Amount   money.Amount `json:"amount"`
+	Category string       `json:"category"`
+	Payee    string       `json:"payee"`
+	Note     string       `json:"note"`
+	Date     time.Time    `json:"date"`
+}
+
+type CategorySummary struct {
+	Category string       `json:"category"`
+	Income   money.Amount `json:"income"`
+	Spending money.Amount `json:"spending"`
+	Count    int          `json:"count"`
+}
+
+// InsertTransaction stores the transaction and inserts a balance entry at the
+// transaction date that moves the balance at that date by the transaction
+// amount, so the budget chain is derived from transactions the same way it is
+// from manual snapshots. Both are written in a single database transaction.
+func InsertTransaction(store database.LedgerStore, transaction *database.Transaction, userID *uuid.UUID) (*database.Transaction, ErrorResponse) {
+	account, errResp := resolveAccount(store, userID, &transaction.AccountID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	transaction.ID = uuid.New()
+	transaction.AccountID = account.ID
+	transaction.UserID = *userID
+	transaction.Category = strings.TrimSpace(transaction.Category)
+	if transaction.Date.IsZero() {
+		transaction.Date = time.Now()
+	}
+
+	entry := database.MoneyEntry{
+		AccountID:     account.ID,
+		TransactionID: &transaction.ID,
+		EffectiveAt:   transaction.Date,
+	}
+	_, errResp = insertDelta(store, transaction, &entry, transaction.Amount, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	return GetTransactionByID(store, userID, &transaction.ID)
+}
+
+func GetTransactionByID(store database.TransactionStore, actorID *uuid.UUID, transactionID *uuid.UUID) (*database.Transaction, ErrorResponse) {
+	transaction, err := store.SelectTransactionByIDDB(transactionID)
+	if err != nil {
+		fmt.Println("Error retrieving transaction:", err)
+		return nil, ErrorResponse{
+			Message: "Transaction not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if transaction.UserID != *actorID {
+		return nil, ErrorResponse{
+			Message: "Forbidden: cannot access another user's transaction",
+			Code:    http.StatusForbidden,
+		}
+	}
+
+	return transaction, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func GetAllTransactions(store database.TransactionStore, userID *uuid.UUID) ([]*database.Transaction, ErrorResponse) {
+	transactions, err := store.SelectUserTransactionsDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving transactions:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve transactions",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return transactions, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// UpdateTransaction updates the transaction and moves the balance entry
+// created for it to the new date and amount. The later entries are shifted by
+// the difference, all in a single database transaction.
+func UpdateTransaction(store database.LedgerStore, actorID *uuid.UUID, transactionForUpdate *TransactionForUpdate) (*database.Transaction, ErrorResponse) {
+	transaction, errResp := GetTransactionByID(store, actorID, &transactionForUpdate.ID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	oldAmount := transaction.Amount
+	oldDate := transaction.Date
+	transaction.Amount = transactionForUpdate.Amount
+	transaction.Category = strings.TrimSpace(transactionForUpdate.Category)
+	transaction.Payee = transactionForUpdate.Payee
+	transaction.Note = transactionForUpdate.Note
+	if !transactionForUpdate.Date.IsZero() {
+		transaction.Date = transactionForUpdate.Date
+	}
+
+	entry, errResp := transactionEntry(store, &transaction.ID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	var previous *database.MoneyEntry
+	var chain []*database.MoneyEntry
+	ledgerChanged := entry != nil && (transaction.Amount != oldAmount || !transaction.Date.Equal(oldDate))
+	err := store.UpdateChainDB(&transaction.AccountID, func(entries []*database.MoneyEntry) (*database.LedgerChange, error) {
+		change := &database.LedgerChange{UpdatedTransaction: transaction}
+		if ledgerChanged {
+			previous = newestEntry(entries)
+			chain, change.UpdatedEntries = moveDeltaEntry(entries, &entry.ID, oldAmount, transaction.Amount, transaction.Date)
+		}
+		return change, nil
+	})
+	if err != nil {
+		fmt.Println("Error updating transaction:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to update transaction",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	if ledgerChanged {
+		for _, e := range chain {
+			if e.ID == entry.ID {
+				entry = e
+			}
+		}
+		publishBalanceEvent(&BalanceEvent{
+			Type:      BalanceUpdated,
+			UserID:    *actorID,
+			AccountID: entry.AccountID,
+			Entry:     entry,
+			Previous:  previous,
+			Latest:    newestEntry(chain),
+			Entries:   chain,
+			At:        time.Now(),
+		})
+	}
+
+	return transaction, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// DeleteTransaction removes the transaction together with the balance entry
+// created for it and shifts the later entries back by its amount, all in a
+// single database transaction.
+func DeleteTransaction(store database.LedgerStore, actorID *uuid.UUID, transactionID *uuid.UUID) ErrorResponse {
+	transaction, errResp := GetTransactionByID(store, actorID, transactionID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+
+	entry, errResp := transactionEntry(store, transactionID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+
+	var previous *database.MoneyEntry
+	var chain []*database.MoneyEntry
+	err := store.UpdateChainDB(&transaction.AccountID, func(entries []*database.MoneyEntry) (*database.LedgerChange, error) {
+		change := &database.LedgerChange{DeletedTransactionID: transactionID}
+		if entry != nil {
+			previous = newestEntry(entries)
+			change.DeletedEntryID = &entry.ID
+			chain, change.UpdatedEntries = removeDeltaEntry(entries, &entry.ID, transaction.Amount)
+		}
+		return change, nil
+	})
+	if err != nil {
+		fmt.Println("Error deleting transaction:", err)
+		return ErrorResponse{
+			Message: "Failed to delete transaction",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	if entry != nil {
+		publishBalanceEvent(&BalanceEvent{
+			Type:      BalanceDeleted,
+			UserID:    *actorID,
+			AccountID: entry.AccountID,
+			Entry:     entry,
+			Previous:  previous,
+			Latest:    newestEntry(chain),
+			Entries:   chain,
+			At:        time.Now(),
+		})
+	}
+
+	return ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// transactionEntry returns the balance entry created for the transaction, or
+// nil if it has none.
+func transactionEntry(store database.MoneyStore, transactionID *uuid.UUID) (*database.MoneyEntry, ErrorResponse) {
+	entry, err := store.SelectMoneyByTransactionIDDB(transactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorResponse{Message: "", Code: http.StatusOK}
+	}
+	if err != nil {
+		fmt.Println("Error retrieving balance for transaction:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve balance for transaction",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	return entry, ErrorResponse{Message: "", Code: http.StatusOK}
+}
+
+func GetCategorySummary(store database.TransactionStore, userID *uuid.UUID) ([]CategorySummary, ErrorResponse) {
+	transactions, errResp := GetAllTransactions(store, userID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	return summarizeByCategory(transactions), errResp
+}
+
+// summarizeByCategory totals income and spending per category, biggest
+// spending first. Spending is reported as a positive amount.
+func summarizeByCategory(transactions []*database.Transaction) []CategorySummary {
+	summaries := []CategorySummary{}
+	indices := map[string]int{}
+	for _, transaction := range transactions {
+		index, ok := indices[transaction.Category]
+		if !ok {
+			index = len(summaries)
+			indices[transaction.Category] = index
+			summaries = append(summaries, CategorySummary{Category: transaction.Category})
+		}
+
+		if transaction.Amount < 0 {
+			summaries[index].Spending -= transaction.Amount
+		} else {
+			summaries[index].Income += transaction.Amount
+		}
+		summaries[index].Count++
+	}
+
+	slices.SortFunc(summaries, func(a, b CategorySummary) int {
+		if a.Spending != b.Spending {
+			return cmp.Compare(b.Spending, a.Spending)
+		}
+		return strings.Compare(a.Category, b.Category)
+	})
+
+	return summaries
+}
@@ -0,0 +1,92 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/Leander-s/money_manager/money"
+	"github.com/google/uuid"
+)
+
+func TestSummarizeByCategory(t *testing.T) {
+	transactions := []*database.Transaction{
+		{Amount: money.FromInt(-50), Category: "groceries"},
+		{Amount: money.FromInt(3000), Category: "salary"},
+		{Amount: money.FromInt(-900), Category: "rent"},
+		{Amount: money.FromInt(-25), Category: "groceries"},
+		{Amount: money.FromInt(10), Category: "groceries"},
+	}
+
+	expected := []CategorySummary{
+		{Category: "rent", Income: money.FromInt(0), Spending: money.FromInt(900), Count: 1},
+		{Category: "groceries", Income: money.FromInt(10), Spending: money.FromInt(75), Count: 3},
+		{Category: "salary", Income: money.FromInt(3000), Spending: money.FromInt(0), Count: 1},
+	}
+
+	summaries := summarizeByCategory(transactions)
+
+	if len(summaries) != len(expected) {
+		t.Fatalf("Expected %d categories, but got %d", len(expected), len(summaries))
+	}
+	for i, summary := range summaries {
+		if summary != expected[i] {
+			t.Errorf("Expected summary %+v, but got %+v", expected[i], summary)
+		}
+	}
+}
+
+func transactionChain(start time.Time) []*database.MoneyEntry {
+	ratio := money.MustParseRate("0.5")
+	// 1000, then a -50 transaction, then a snapshot of 900, newest first
+	return []*database.MoneyEntry{
+		{ID: uuid.New(), Balance: money.FromInt(900), Budget: money.FromInt(-100), Ratio: ratio, EffectiveAt: start.AddDate(0, 0, 2)},
+		{ID: uuid.New(), Balance: money.FromInt(950), Budget: money.FromInt(-50), Ratio: ratio, EffectiveAt: start.AddDate(0, 0, 1)},
+		{ID: uuid.New(), Balance: money.FromInt(1000), Budget: money.FromInt(0), Ratio: ratio, EffectiveAt: start},
+	}
+}
+
+func TestRemoveDeltaEntry(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := transactionChain(start)
+	laterID, entryID := entries[0].ID, entries[1].ID
+
+	chain, updated := removeDeltaEntry(entries, &entryID, money.FromInt(-50))
+
+	if len(chain) != 2 || chain[0].ID != laterID {
+		t.Fatalf("Expected the entry to be removed, but got %d entries", len(chain))
+	}
+	if chain[0].Balance != money.FromInt(950) || chain[0].Budget != money.FromInt(-50) {
+		t.Errorf("Expected the later entry to be shifted to 950 with budget -50, but got %s and %s", chain[0].Balance, chain[0].Budget)
+	}
+	if len(updated) != 1 || updated[0].ID != laterID {
+		t.Errorf("Expected only the later entry to be updated, but got %d entries", len(updated))
+	}
+}
+
+func TestMoveDeltaEntry(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := transactionChain(start)
+	laterID, entryID := entries[0].ID, entries[1].ID
+
+	// The transaction becomes -80 and moves after the snapshot
+	chain, updated := moveDeltaEntry(entries, &entryID, money.FromInt(-50), money.FromInt(-80), start.AddDate(0, 0, 3))
+
+	expectedIDs := []uuid.UUID{entryID, laterID, chain[2].ID}
+	expectedBalances := []money.Amount{money.FromInt(870), money.FromInt(950), money.FromInt(1000)}
+	expectedBudgets := []money.Amount{money.FromInt(-130), money.FromInt(-50), money.FromInt(0)}
+	for i, entry := range chain {
+		if entry.ID != expectedIDs[i] {
+			t.Errorf("Expected entry %s at index %d, but got %s", expectedIDs[i], i, entry.ID)
+		}
+		if entry.Balance != expectedBalances[i] || entry.Budget != expectedBudgets[i] {
+			t.Errorf("Expected balance %s and budget %s, but got %s and %s", expectedBalances[i], expectedBudgets[i], entry.Balance, entry.Budget)
+		}
+	}
+	if !chain[0].EffectiveAt.Equal(start.AddDate(0, 0, 3)) {
+		t.Errorf("Expected the entry to move to the new date, but got %s", chain[0].EffectiveAt)
+	}
+	if len(updated) != 2 {
+		t.Errorf("Expected the moved and the later entry to be updated, but got %d entries", len(updated))
+	}
+}
@@ -0,0 +1,523 @@
+package logic
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+// Statuses of webhook deliveries
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Headers sent with every webhook request. The signature is the hex encoded
+// HMAC-SHA256 of the timestamp, a dot and the body, keyed with the secret.
+const (
+	WebhookHeaderID        = "X-Webhook-ID"
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// Events a webhook can subscribe to
+var webhookEvents = []string{BalanceCreated, BalanceUpdated, BalanceDeleted}
+
+// Attempts after which a delivery is given up
+const webhookMaxAttempts = 8
+
+// Delay before the first retry, doubled with every further attempt
+const webhookRetryDelay = 30 * time.Second
+
+// Time an endpoint has to respond
+const webhookTimeout = 10 * time.Second
+
+// Time a claimed delivery is not attempted by anyone else, longer than an
+// attempt can take
+const webhookLease = time.Minute
+
+// Most deliveries attempted per scheduler run and returned in the log
+const (
+	webhookDeliveryBatch = 100
+	webhookLogLimit      = 100
+)
+
+// Addresses webhooks may not reach besides loopback, private, link-local,
+// unspecified and multicast ones
+var webhookBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// webhookClient posts webhooks and alert webhooks. Its dialer checks every
+// address a host resolves to, so neither DNS changes after validation nor
+// redirects reach the server's own network. It never uses a proxy, which
+// would dial on its behalf.
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: webhookTimeout,
+			Control: checkWebhookDial,
+		}).DialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+type WebhookForUpdate struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookPayload is the JSON body of a webhook request. Entry is the entry
+// that was created, updated or deleted, Latest the newest entry of the
+// account after the change, nil if the account has no entries left.
+type WebhookPayload struct {
+	ID        uuid.UUID            `json:"id"`
+	Type      string               `json:"type"`
+	CreatedAt time.Time            `json:"created_at"`
+	AccountID uuid.UUID            `json:"account_id"`
+	Entry     *database.MoneyEntry `json:"entry"`
+	Latest    *database.MoneyEntry `json:"latest"`
+}
+
+// CreateWebhook registers an endpoint for the user with a new secret. This is
+// the only time the secret is returned. Without events the webhook subscribes
+// to all of them.
+func CreateWebhook(store database.WebhookStore, userID *uuid.UUID, webhookForUpdate *WebhookForUpdate) (*database.Webhook, ErrorResponse) {
+	webhook := &database.Webhook{
+		URL:    strings.TrimSpace(webhookForUpdate.URL),
+		Events: webhookForUpdate.Events,
+		UserID: *userID,
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		fmt.Println("Error generating webhook secret:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to create webhook",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	webhook.Secret = secret
+
+	webhook.ID, err = store.InsertWebhookDB(webhook)
+	if err != nil {
+		fmt.Println("Error inserting webhook:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to insert webhook",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	created, errResp := GetWebhookByID(store, userID, &webhook.ID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+	created.Secret = secret
+	return created, errResp
+}
+
+func GetWebhooks(store database.WebhookStore, userID *uuid.UUID) ([]*database.Webhook, ErrorResponse) {
+	webhooks, err := store.SelectUserWebhooksDB(userID)
+	if err != nil {
+		fmt.Println("Error retrieving webhooks:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve webhooks",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+// GetWebhookByID returns the webhook without its secret.
+func GetWebhookByID(store database.WebhookStore, actorID *uuid.UUID, webhookID *uuid.UUID) (*database.Webhook, ErrorResponse) {
+	webhook, err := store.SelectWebhookByIDDB(webhookID)
+	if err != nil {
+		fmt.Println("Error retrieving webhook:", err)
+		return nil, ErrorResponse{
+			Message: "Webhook not found",
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	if webhook.UserID != *actorID {
+		return nil, ErrorResponse{
+			Message: "Forbidden: cannot access another user's webhook",
+			Code:    http.StatusForbidden,
+		}
+	}
+
+	webhook.Secret = ""
+	return webhook, ErrorResponse{
+		Message: "",
+		Code:    http.StatusOK,
+	}
+}
+
+func UpdateWebhook(store database.WebhookStore, actorID *uuid.UUID, webhookID *uuid.UUID, webhookForUpdate *WebhookForUpdate) (*database.Webhook, ErrorResponse) {
+	webhook, errResp := GetWebhookByID(store, actorID, webhookID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	webhook.URL = strings.TrimSpace(webhookForUpdate.URL)
+	webhook.Events = webhookForUpdate.Events
+	if err := validateWebhook(webhook); err != nil {
+		return nil, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		}
+	}
+
+	if err := store.UpdateWebhookDB(webhook); err != nil {
+		fmt.Println("Error updating webhook:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to update webhook",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return webhook, errResp
+}
+
+func DeleteWebhook(store database.WebhookStore, actorID *uuid.UUID, webhookID *uuid.UUID) ErrorResponse {
+	_, errResp := GetWebhookByID(store, actorID, webhookID)
+	if errResp.Code != http.StatusOK {
+		return errResp
+	}
+
+	if err := store.DeleteWebhookDB(webhookID); err != nil {
+		fmt.Println("Error deleting webhook:", err)
+		return ErrorResponse{
+			Message: "Failed to delete webhook",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return errResp
+}
+
+// GetWebhookDeliveries returns the latest deliveries of the webhook, newest
+// first.
+func GetWebhookDeliveries(store database.WebhookStore, actorID *uuid.UUID, webhookID *uuid.UUID) ([]*database.WebhookDelivery, ErrorResponse) {
+	_, errResp := GetWebhookByID(store, actorID, webhookID)
+	if errResp.Code != http.StatusOK {
+		return nil, errResp
+	}
+
+	deliveries, err := store.SelectWebhookDeliveriesDB(webhookID, webhookLogLimit)
+	if err != nil {
+		fmt.Println("Error retrieving webhook deliveries:", err)
+		return nil, ErrorResponse{
+			Message: "Failed to retrieve webhook deliveries",
+			Code:    http.StatusInternalServerError,
+		}
+	}
+
+	return deliveries, errResp
+}
+
+// validateWebhook checks the URL and events and removes duplicate events.
+func validateWebhook(webhook *database.Webhook) error {
+	if err := validateWebhookURL(webhook.URL); err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+
+	if len(webhook.Events) == 0 {
+		webhook.Events = slices.Clone(webhookEvents)
+		return nil
+	}
+	events := []string{}
+	for _, event := range webhook.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !slices.Contains(webhookEvents, event) {
+			return fmt.Errorf("invalid event %q", event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	webhook.Events = events
+	return nil
+}
+
+// validateWebhookURL checks that the URL is http or https and does not name
+// an address webhooks may not reach. Host names are checked when dialing.
+func validateWebhookURL(rawURL string) error {
+	webhookURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if webhookURL.Scheme != "http" && webhookURL.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	host := webhookURL.Hostname()
+	if host == "" {
+		return errors.New("host is required")
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return errors.New("host is not allowed")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && webhookAddressBlocked(addr) {
+		return errors.New("address is not allowed")
+	}
+	return nil
+}
+
+// checkWebhookDial refuses connections to addresses webhooks may not reach.
+// The address is the one the host resolved to.
+func checkWebhookDial(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if webhookAddressBlocked(addrPort.Addr()) {
+		return fmt.Errorf("address %s is not allowed", addrPort.Addr())
+	}
+	return nil
+}
+
+func webhookAddressBlocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range webhookBlockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// NewWebhookDispatcher returns a listener that queues a delivery for every
+// webhook of the user subscribed to the event. The deliveries are stored
+// before the listener returns and attempted right away in the background.
+// Deliveries that fail are retried by DeliverDueWebhooks.
+func NewWebhookDispatcher(store database.WebhookDeliveryStore) BalanceListener {
+	return func(event *BalanceEvent) {
+		deliveries := enqueueWebhookDeliveries(store, event)
+		if len(deliveries) == 0 {
+			return
+		}
+		go func() {
+			for _, delivery := range deliveries {
+				attemptWebhookDelivery(store, webhookClient, delivery, time.Now())
+			}
+		}()
+	}
+}
+
+func enqueueWebhookDeliveries(store database.WebhookStore, event *BalanceEvent) []*database.WebhookDelivery {
+	webhooks, err := store.SelectUserWebhooksDB(&event.UserID)
+	if err != nil {
+		fmt.Println("Error retrieving webhooks:", err)
+		return nil
+	}
+
+	payload, err := json.Marshal(WebhookPayload{
+		ID:        uuid.New(),
+		Type:      event.Type,
+		CreatedAt: event.At,
+		AccountID: event.AccountID,
+		Entry:     event.Entry,
+		Latest:    event.Latest,
+	})
+	if err != nil {
+		fmt.Println("Error encoding webhook payload:", err)
+		return nil
+	}
+
+	deliveries := []*database.WebhookDelivery{}
+	for _, webhook := range webhooks {
+		if !slices.Contains(webhook.Events, event.Type) {
+			continue
+		}
+		delivery := &database.WebhookDelivery{
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        WebhookDeliveryPending,
+			NextAttemptAt: event.At,
+			WebhookID:     &webhook.ID,
+		}
+		if delivery.ID, err = store.InsertWebhookDeliveryDB(delivery); err != nil {
+			fmt.Println("Error queueing webhook delivery:", err)
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
+
+// DeliverDueWebhooks attempts the pending deliveries that are due. Returns
+// the number of deliveries that succeeded.
+func DeliverDueWebhooks(store database.WebhookDeliveryStore, now time.Time) int {
+	deliveries, err := store.SelectDueWebhookDeliveriesDB(now, webhookDeliveryBatch)
+	if err != nil {
+		fmt.Println("Error retrieving due webhook deliveries:", err)
+		return 0
+	}
+
+	count := 0
+	for _, delivery := range deliveries {
+		if attemptWebhookDelivery(store, webhookClient, delivery, now) {
+			count++
+		}
+	}
+	return count
+}
+
+// attemptWebhookDelivery claims the delivery, posts it and stores the
+// outcome. Returns whether it was delivered.
+func attemptWebhookDelivery(store database.WebhookDeliveryStore, client *http.Client, delivery *database.WebhookDelivery, now time.Time) bool {
+	claimed, err := store.ClaimWebhookDeliveryDB(&delivery.ID, now, now.Add(webhookLease))
+	if err != nil {
+		fmt.Println("Error claiming webhook delivery:", err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	status := 0
+	webhook, err := deliveryTarget(store, delivery)
+	if err == nil {
+		status, err = postWebhook(client, webhook, delivery, now)
+	}
+	recordWebhookAttempt(delivery, status, err, now)
+
+	if err := store.UpdateWebhookDeliveryDB(delivery); err != nil {
+		fmt.Println("Error updating webhook delivery:", err)
+	}
+	return delivery.Status == WebhookDeliveryDelivered
+}
+
+// deliveryTarget returns the webhook the delivery goes to. Deliveries of an
+// alert rule go to the rule's webhook URL, signed with the rule's secret.
+func deliveryTarget(store database.WebhookDeliveryStore, delivery *database.WebhookDelivery) (*database.Webhook, error) {
+	if delivery.AlertRuleID == nil {
+		return store.SelectWebhookByIDDB(delivery.WebhookID)
+	}
+
+	rule, err := store.SelectAlertRuleByIDDB(delivery.AlertRuleID)
+	if err != nil {
+		return nil, err
+	}
+	if rule.WebhookURL == "" {
+		return nil, errors.New("alert rule has no webhook")
+	}
+	return &database.Webhook{
+		ID:     rule.ID,
+		URL:    rule.WebhookURL,
+		Secret: rule.WebhookSecret,
+		UserID: rule.UserID,
+	}, nil
+}
+
+// postWebhook sends the signed payload to the webhook. Returns the status
+// code of the response, and an error if there was none or it was not a 2xx.
+func postWebhook(client *http.Client, webhook *database.Webhook, delivery *database.WebhookDelivery, now time.Time) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookHeaderID, delivery.ID.String())
+	request.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	request.Header.Set(WebhookHeaderTimestamp, timestamp)
+	request.Header.Set(WebhookHeaderSignature, WebhookSignature(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// WebhookSignature signs a payload as sent in the signature header.
+// Receivers compute it with their copy of the secret and compare.
+func WebhookSignature(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// recordWebhookAttempt updates the delivery with the outcome of an attempt.
+// A failed delivery is retried with exponential backoff until it runs out of
+// attempts.
+func recordWebhookAttempt(delivery *database.WebhookDelivery, status int, err error, now time.Time) {
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = nil
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = WebhookDeliveryDelivered
+		delivery.LastError = ""
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.Status = WebhookDeliveryPending
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(webhookRetryDelay << (delivery.Attempts - 1))
+	}
+}
@@ -0,0 +1,184 @@
+package logic
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Leander-s/money_manager/db"
+	"github.com/google/uuid"
+)
+
+func TestPostWebhook_Signature(t *testing.T) {
+	webhook := &database.Webhook{Secret: "secret"}
+	delivery := &database.WebhookDelivery{
+		ID:        uuid.New(),
+		EventType: BalanceCreated,
+		Payload:   `{"type":"balance.created"}`,
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	webhook.URL = server.URL
+
+	status, err := postWebhook(server.Client(), webhook, delivery, now)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("Expected status %d, but got %d", http.StatusNoContent, status)
+	}
+
+	if string(body) != delivery.Payload {
+		t.Errorf("Expected body %s, but got %s", delivery.Payload, body)
+	}
+	if got := received.Header.Get(WebhookHeaderEvent); got != BalanceCreated {
+		t.Errorf("Expected event %s, but got %s", BalanceCreated, got)
+	}
+	if got := received.Header.Get(WebhookHeaderID); got != delivery.ID.String() {
+		t.Errorf("Expected delivery ID %s, but got %s", delivery.ID, got)
+	}
+	timestamp := received.Header.Get(WebhookHeaderTimestamp)
+	if timestamp != "1709294400" {
+		t.Errorf("Expected timestamp 1709294400, but got %s", timestamp)
+	}
+	expected := WebhookSignature("secret", timestamp, body)
+	if got := received.Header.Get(WebhookHeaderSignature); got != expected {
+		t.Errorf("Expected signature %s, but got %s", expected, got)
+	}
+	if expected == WebhookSignature("other", timestamp, body) {
+		t.Errorf("Expected signatures with different secrets to differ")
+	}
+}
+
+func TestPostWebhook_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	webhook := &database.Webhook{URL: server.URL, Secret: "secret"}
+	delivery := &database.WebhookDelivery{ID: uuid.New(), Payload: "{}"}
+	status, err := postWebhook(server.Client(), webhook, delivery, time.Now())
+	if err == nil {
+		t.Errorf("Expected an error for status %d", status)
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, but got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestRecordWebhookAttempt(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	delivery := &database.WebhookDelivery{Status: WebhookDeliveryPending}
+
+	expectedDelays := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, delay := range expectedDelays {
+		recordWebhookAttempt(delivery, http.StatusInternalServerError, errors.New("unexpected status 500"), now)
+		if delivery.Attempts != i+1 {
+			t.Errorf("Expected %d attempts, but got %d", i+1, delivery.Attempts)
+		}
+		if delivery.Status != WebhookDeliveryPending {
+			t.Errorf("Expected status %s, but got %s", WebhookDeliveryPending, delivery.Status)
+		}
+		if got := delivery.NextAttemptAt.Sub(now); got != delay {
+			t.Errorf("Expected retry after %s, but got %s", delay, got)
+		}
+	}
+	if delivery.ResponseStatus == nil || *delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Errorf("Expected response status %d, but got %v", http.StatusInternalServerError, delivery.ResponseStatus)
+	}
+
+	recordWebhookAttempt(delivery, http.StatusOK, nil, now)
+	if delivery.Status != WebhookDeliveryDelivered {
+		t.Errorf("Expected status %s, but got %s", WebhookDeliveryDelivered, delivery.Status)
+	}
+	if delivery.LastError != "" {
+		t.Errorf("Expected no error, but got %s", delivery.LastError)
+	}
+
+	failing := &database.WebhookDelivery{Status: WebhookDeliveryPending, Attempts: webhookMaxAttempts - 1}
+	recordWebhookAttempt(failing, 0, errors.New("connection refused"), now)
+	if failing.Status != WebhookDeliveryFailed {
+		t.Errorf("Expected status %s, but got %s", WebhookDeliveryFailed, failing.Status)
+	}
+	if failing.ResponseStatus != nil {
+		t.Errorf("Expected no response status, but got %d", *failing.ResponseStatus)
+	}
+}
+
+func TestCheckWebhookDial(t *testing.T) {
+	blocked := []string{"127.0.0.1:80", "[::1]:443", "10.1.2.3:80", "172.16.0.1:80", "192.168.0.10:80",
+		"169.254.169.254:80", "[fe80::1]:80", "0.0.0.0:80", "100.64.0.1:80", "[::ffff:127.0.0.1]:80"}
+	for _, address := range blocked {
+		if err := checkWebhookDial("tcp", address, nil); err == nil {
+			t.Errorf("Expected dialing %s to be refused, but got no error", address)
+		}
+	}
+
+	allowed := []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:443"}
+	for _, address := range allowed {
+		if err := checkWebhookDial("tcp", address, nil); err != nil {
+			t.Errorf("Expected dialing %s to be allowed, but got %v", address, err)
+		}
+	}
+}
+
+func TestWebhookClient_RefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no request to reach the server")
+	}))
+	defer server.Close()
+
+	response, err := webhookClient.Post(server.URL, "application/json", nil)
+	if err == nil {
+		response.Body.Close()
+		t.Fatalf("Expected the request to be refused, but got status %d", response.StatusCode)
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	webhook := &database.Webhook{URL: "https://example.com/hook"}
+	if err := validateWebhook(webhook); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(webhook.Events) != len(webhookEvents) {
+		t.Errorf("Expected all %d events, but got %v", len(webhookEvents), webhook.Events)
+	}
+
+	webhook = &database.Webhook{URL: "http://hooks.example.com:8123/hook", Events: []string{"Balance.Deleted", BalanceDeleted}}
+	if err := validateWebhook(webhook); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(webhook.Events) != 1 || webhook.Events[0] != BalanceDeleted {
+		t.Errorf("Expected events [%s], but got %v", BalanceDeleted, webhook.Events)
+	}
+
+	invalid := []*database.Webhook{
+		{URL: "example.com/hook"},
+		{URL: "ftp://example.com/hook"},
+		{URL: "file:///etc/passwd"},
+		{URL: "http://localhost:8123/hook"},
+		{URL: "http://127.0.0.1/hook"},
+		{URL: "http://[::1]/hook"},
+		{URL: "http://10.0.0.5/hook"},
+		{URL: "http://169.254.169.254/latest/meta-data"},
+		{URL: "http://[::ffff:192.168.1.1]/hook"},
+		{URL: "https://example.com/hook", Events: []string{"balance.moved"}},
+	}
+	for _, webhook := range invalid {
+		if err := validateWebhook(webhook); err == nil {
+			t.Errorf("Expected webhook %+v to be invalid, but got no error", webhook)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+ALTER TABLE money DROP COLUMN IF EXISTS transaction_id;
+DROP INDEX IF EXISTS transactions_user_id_date_idx;
+DROP TABLE IF EXISTS transactions;
@@ -0,0 +1,15 @@
+CREATE TABLE transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    amount NUMERIC(15, 2) NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    payee VARCHAR(100) NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS transactions_user_id_date_idx ON transactions(user_id, date DESC);
+
+ALTER TABLE money
+ADD COLUMN transaction_id UUID REFERENCES transactions(id) ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS account_id;
+DROP INDEX IF EXISTS money_account_id_created_at_idx;
+ALTER TABLE money DROP COLUMN IF EXISTS account_id;
+DROP INDEX IF EXISTS accounts_user_id_idx;
+DROP TABLE IF EXISTS accounts;
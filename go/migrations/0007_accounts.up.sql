@@ -0,0 +1,29 @@
+CREATE TABLE accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS accounts_user_id_idx ON accounts(user_id);
+
+-- Every existing user gets a default account holding their current entries
+INSERT INTO accounts (name, user_id) SELECT 'Default', id FROM users;
+
+DELETE FROM money WHERE user_id IS NULL;
+
+ALTER TABLE money
+ADD COLUMN account_id UUID REFERENCES accounts(id) ON DELETE CASCADE;
+
+UPDATE money SET account_id = accounts.id FROM accounts WHERE accounts.user_id = money.user_id;
+
+ALTER TABLE money ALTER COLUMN account_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS money_account_id_created_at_idx ON money(account_id, created_at DESC);
+
+ALTER TABLE transactions
+ADD COLUMN account_id UUID REFERENCES accounts(id) ON DELETE CASCADE;
+
+UPDATE transactions SET account_id = accounts.id FROM accounts WHERE accounts.user_id = transactions.user_id;
+
+ALTER TABLE transactions ALTER COLUMN account_id SET NOT NULL;
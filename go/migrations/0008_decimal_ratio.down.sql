@@ -0,0 +1,7 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    ALTER TABLE money
+    ALTER COLUMN ratio TYPE FLOAT USING ratio::FLOAT;
+COMMIT;
@@ -0,0 +1,16 @@
+ALTER TABLE accounts
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR';
+
+ALTER TABLE money
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR';
+
+UPDATE money SET currency = accounts.currency FROM accounts WHERE accounts.id = money.account_id;
+
+-- One unit of base buys rate units of quote on the given date
+CREATE TABLE exchange_rates (
+    base CHAR(3) NOT NULL,
+    quote CHAR(3) NOT NULL,
+    date DATE NOT NULL,
+    rate NUMERIC(18, 6) NOT NULL,
+    PRIMARY KEY (base, quote, date)
+);
@@ -0,0 +1,9 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    DROP INDEX IF EXISTS money_account_id_effective_at_idx;
+    CREATE INDEX money_account_id_created_at_idx ON money(account_id, created_at DESC);
+
+    ALTER TABLE money DROP COLUMN effective_at;
+COMMIT;
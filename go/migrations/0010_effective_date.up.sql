@@ -0,0 +1,15 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    ALTER TABLE money
+    ADD COLUMN effective_at TIMESTAMPTZ;
+
+    UPDATE money SET effective_at = created_at;
+
+    ALTER TABLE money ALTER COLUMN effective_at SET NOT NULL;
+    ALTER TABLE money ALTER COLUMN effective_at SET DEFAULT CURRENT_TIMESTAMP;
+
+    DROP INDEX IF EXISTS money_account_id_created_at_idx;
+    CREATE INDEX money_account_id_effective_at_idx ON money(account_id, effective_at DESC, created_at DESC);
+COMMIT;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS money_recurring_id_effective_at_idx;
+ALTER TABLE money DROP COLUMN IF EXISTS recurring_id;
+DROP INDEX IF EXISTS recurring_next_run_idx;
+DROP TABLE IF EXISTS recurring;
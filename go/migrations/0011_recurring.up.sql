@@ -0,0 +1,24 @@
+CREATE TABLE recurring (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    description VARCHAR(100) NOT NULL DEFAULT '',
+    amount NUMERIC(15, 2) NOT NULL,
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+    interval INT NOT NULL DEFAULT 1 CHECK (interval > 0),
+    day_of_month INT CHECK (day_of_month BETWEEN 1 AND 31),
+    start_date TIMESTAMPTZ NOT NULL,
+    end_date TIMESTAMPTZ,
+    -- Index of the next occurrence counted from start_date
+    occurrence INT NOT NULL DEFAULT 0,
+    next_run TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS recurring_next_run_idx ON recurring(next_run);
+
+ALTER TABLE money
+ADD COLUMN recurring_id UUID REFERENCES recurring(id) ON DELETE SET NULL;
+
+-- Each occurrence of a schedule is materialized at most once
+CREATE UNIQUE INDEX IF NOT EXISTS money_recurring_id_effective_at_idx ON money(recurring_id, effective_at);
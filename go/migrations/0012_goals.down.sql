@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS goals_user_id_idx;
+DROP TABLE IF EXISTS goals;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS budgets_user_id_idx;
+DROP INDEX IF EXISTS budgets_account_id_category_period_idx;
+DROP TABLE IF EXISTS budgets;
@@ -0,0 +1,15 @@
+CREATE TABLE budgets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    category VARCHAR(50) NOT NULL,
+    period VARCHAR(10) NOT NULL CHECK (period IN ('weekly', 'monthly')),
+    limit_amount NUMERIC(15, 2) NOT NULL CHECK (limit_amount > 0),
+    rollover VARCHAR(10) NOT NULL DEFAULT 'none' CHECK (rollover IN ('none', 'unused', 'full')),
+    start_date TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+-- One budget per category and period on each account
+CREATE UNIQUE INDEX IF NOT EXISTS budgets_account_id_category_period_idx ON budgets(account_id, category, period);
+CREATE INDEX IF NOT EXISTS budgets_user_id_idx ON budgets(user_id);
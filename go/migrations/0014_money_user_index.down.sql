@@ -0,0 +1,6 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    DROP INDEX IF EXISTS money_user_id_effective_at_idx;
+COMMIT;
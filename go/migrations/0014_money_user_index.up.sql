@@ -0,0 +1,7 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    -- Keyset pagination over all entries of a user
+    CREATE INDEX IF NOT EXISTS money_user_id_effective_at_idx ON money(user_id, effective_at DESC, created_at DESC, id DESC);
+COMMIT;
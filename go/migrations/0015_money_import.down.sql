@@ -0,0 +1,10 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    DROP INDEX IF EXISTS money_account_id_import_ref_idx;
+
+    ALTER TABLE money
+    DROP COLUMN import_ref,
+    DROP COLUMN description;
+COMMIT;
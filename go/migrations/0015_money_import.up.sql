@@ -0,0 +1,11 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    ALTER TABLE money
+    ADD COLUMN description VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN import_ref VARCHAR(64);
+
+    -- Importing the same statement row twice is rejected
+    CREATE UNIQUE INDEX money_account_id_import_ref_idx ON money(account_id, import_ref);
+COMMIT;
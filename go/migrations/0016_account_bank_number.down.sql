@@ -0,0 +1,2 @@
+ALTER TABLE accounts
+DROP COLUMN bank_account;
@@ -0,0 +1,4 @@
+-- Number of the account at the bank, e.g. an IBAN, used to match imported
+-- statements to accounts
+ALTER TABLE accounts
+ADD COLUMN bank_account VARCHAR(64) NOT NULL DEFAULT '';
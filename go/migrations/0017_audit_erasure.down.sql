@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_erasure_scheduled_at_idx;
+ALTER TABLE users
+DROP COLUMN erasure_scheduled_at;
+
+DROP INDEX IF EXISTS audit_log_user_id_created_at_idx;
+DROP TABLE IF EXISTS audit_log;
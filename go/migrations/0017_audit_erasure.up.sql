@@ -0,0 +1,16 @@
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action VARCHAR(50) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_created_at_idx ON audit_log(user_id, created_at);
+
+-- Time at which a requested erasure of the user is carried out, the user can
+-- cancel it until then
+ALTER TABLE users
+ADD COLUMN erasure_scheduled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_erasure_scheduled_at_idx ON users(erasure_scheduled_at) WHERE erasure_scheduled_at IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS digests_next_send_at_idx;
+DROP TABLE IF EXISTS digests;
@@ -0,0 +1,10 @@
+-- Opt-in summary emails, at most one subscription per user
+CREATE TABLE digests (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('weekly', 'monthly')),
+    next_send_at TIMESTAMPTZ NOT NULL,
+    last_sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS digests_next_send_at_idx ON digests(next_send_at);
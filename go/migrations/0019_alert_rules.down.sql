@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS alert_rules_user_id_idx;
+DROP TABLE IF EXISTS alert_rules;
@@ -0,0 +1,14 @@
+CREATE TABLE alert_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('budget_negative', 'budget_below', 'balance_below')),
+    threshold NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    notify_email BOOLEAN NOT NULL DEFAULT TRUE,
+    webhook_url TEXT NOT NULL DEFAULT '',
+    last_fired_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Rules without an account apply to all accounts of the user
+    account_id UUID REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS alert_rules_user_id_idx ON alert_rules(user_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS webhook_deliveries_pending_idx;
+DROP INDEX IF EXISTS webhook_deliveries_webhook_id_idx;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS webhooks_user_id_idx;
+DROP TABLE IF EXISTS webhooks;
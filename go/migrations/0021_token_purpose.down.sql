@@ -0,0 +1,9 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    DROP INDEX IF EXISTS tokens_user_id_purpose_idx;
+
+    ALTER TABLE tokens
+    DROP COLUMN purpose;
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    -- Existing tokens cannot be told apart, so everyone logs in again and
+    -- requests new verification and reset links
+    DELETE FROM tokens;
+
+    ALTER TABLE tokens
+    ADD COLUMN purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('session', 'email_verification', 'password_reset'));
+
+    CREATE INDEX IF NOT EXISTS tokens_user_id_purpose_idx ON tokens(user_id, purpose);
+COMMIT;
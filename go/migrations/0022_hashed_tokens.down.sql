@@ -0,0 +1,13 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    DELETE FROM tokens;
+
+    ALTER TABLE tokens
+    DROP COLUMN token_hash,
+    DROP COLUMN prefix;
+
+    ALTER TABLE tokens
+    ADD COLUMN token UUID PRIMARY KEY;
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    -- Plaintext tokens are credentials, so they are invalidated rather than
+    -- hashed in place
+    DELETE FROM tokens;
+
+    ALTER TABLE tokens
+    DROP COLUMN token;
+
+    -- Tokens are looked up by their public prefix and checked against the
+    -- SHA-256 hash of the whole token
+    ALTER TABLE tokens
+    ADD COLUMN prefix VARCHAR(12) PRIMARY KEY,
+    ADD COLUMN token_hash CHAR(64) NOT NULL;
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    DROP INDEX IF EXISTS tokens_session_id_idx;
+
+    ALTER TABLE tokens
+    DROP COLUMN session_id;
+COMMIT;
+
+DROP INDEX IF EXISTS sessions_user_id_idx;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,25 @@
+-- Login sessions, one per device. Deleting a session deletes its token.
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_name VARCHAR(100) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions(user_id);
+
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    -- Session tokens issued before sessions existed have none to belong to
+    DELETE FROM tokens WHERE purpose = 'session';
+
+    ALTER TABLE tokens
+    ADD COLUMN session_id UUID REFERENCES sessions(id) ON DELETE CASCADE;
+
+    CREATE INDEX IF NOT EXISTS tokens_session_id_idx ON tokens(session_id);
+COMMIT;
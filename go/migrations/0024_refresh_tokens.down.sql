@@ -0,0 +1,15 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    DELETE FROM tokens WHERE purpose = 'refresh';
+
+    ALTER TABLE tokens
+    DROP COLUMN used_at;
+
+    ALTER TABLE tokens
+    DROP CONSTRAINT IF EXISTS tokens_purpose_check;
+
+    ALTER TABLE tokens
+    ADD CONSTRAINT tokens_purpose_check CHECK (purpose IN ('session', 'email_verification', 'password_reset'));
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    ALTER TABLE tokens
+    DROP CONSTRAINT IF EXISTS tokens_purpose_check;
+
+    ALTER TABLE tokens
+    ADD CONSTRAINT tokens_purpose_check CHECK (purpose IN ('session', 'refresh', 'email_verification', 'password_reset'));
+
+    -- A refresh token is used once. Presenting it again revokes its session.
+    ALTER TABLE tokens
+    ADD COLUMN used_at TIMESTAMPTZ;
+COMMIT;
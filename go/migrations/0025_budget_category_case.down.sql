@@ -0,0 +1,8 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    DROP INDEX IF EXISTS budgets_account_id_category_period_idx;
+
+    CREATE UNIQUE INDEX IF NOT EXISTS budgets_account_id_category_period_idx ON budgets(account_id, category, period);
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    -- Transactions match budgets regardless of case, so categories differing
+    -- only in case are the same budget
+    DROP INDEX IF EXISTS budgets_account_id_category_period_idx;
+
+    CREATE UNIQUE INDEX IF NOT EXISTS budgets_account_id_category_period_idx ON budgets(account_id, lower(category), period);
+COMMIT;
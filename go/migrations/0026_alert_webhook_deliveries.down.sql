@@ -0,0 +1,20 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    DELETE FROM webhook_deliveries WHERE alert_rule_id IS NOT NULL;
+
+    DROP INDEX IF EXISTS webhook_deliveries_alert_rule_id_idx;
+
+    ALTER TABLE webhook_deliveries
+    DROP CONSTRAINT IF EXISTS webhook_deliveries_target_check;
+
+    ALTER TABLE webhook_deliveries
+    DROP COLUMN alert_rule_id;
+
+    ALTER TABLE webhook_deliveries
+    ALTER COLUMN webhook_id SET NOT NULL;
+
+    ALTER TABLE alert_rules
+    DROP COLUMN webhook_secret;
+COMMIT;
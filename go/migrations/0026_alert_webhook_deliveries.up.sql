@@ -0,0 +1,22 @@
+BEGIN;
+    SET LOCAL lock_timeout = '5s';
+    SET LOCAL statement_timeout = '30s';
+
+    -- Alert webhooks are signed like other webhooks. Rules created before
+    -- get a secret when they are next updated.
+    ALTER TABLE alert_rules
+    ADD COLUMN webhook_secret VARCHAR(64) NOT NULL DEFAULT '';
+
+    -- Alert webhooks are delivered through the same queue, a delivery goes
+    -- either to a webhook or to an alert rule's webhook
+    ALTER TABLE webhook_deliveries
+    ALTER COLUMN webhook_id DROP NOT NULL;
+
+    ALTER TABLE webhook_deliveries
+    ADD COLUMN alert_rule_id UUID REFERENCES alert_rules(id) ON DELETE CASCADE;
+
+    ALTER TABLE webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_target_check CHECK ((webhook_id IS NULL) <> (alert_rule_id IS NULL));
+
+    CREATE INDEX IF NOT EXISTS webhook_deliveries_alert_rule_id_idx ON webhook_deliveries(alert_rule_id, created_at);
+COMMIT;
@@ -0,0 +1,246 @@
+// Package money provides fixed-point types for monetary amounts and the rates
+// they are multiplied with. Both round-trip exactly through Postgres NUMERIC
+// columns and JSON numbers, so no value ever passes through a float64.
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount is a monetary value in cents.
+type Amount int64
+
+// Rate is a multiplier with six decimal places, used for the budget ratio and
+// exchange rates.
+type Rate int64
+
+const (
+	amountScale = 2
+	rateScale   = 6
+	rateUnit    = 1_000_000
+)
+
+// FromInt returns the amount of whole units, e.g. FromInt(12) is 12.00.
+func FromInt(units int64) Amount {
+	return Amount(units * 100)
+}
+
+// ParseAmount parses a decimal string such as "-1234.5". Digits beyond the
+// cents are rounded half away from zero, which is what Postgres does when
+// storing into a NUMERIC(15, 2) column.
+func ParseAmount(s string) (Amount, error) {
+	value, err := parseFixed(s, amountScale)
+	return Amount(value), err
+}
+
+// MustParseAmount is like ParseAmount but panics on invalid input.
+func MustParseAmount(s string) Amount {
+	amount, err := ParseAmount(s)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// MulRate multiplies the amount by the rate and rounds the result half away
+// from zero to whole cents.
+func (a Amount) MulRate(r Rate) Amount {
+	product := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(r)))
+	return Amount(roundQuo(product, big.NewInt(rateUnit)).Int64())
+}
+
+// MulRat multiplies the amount by an exact fraction and rounds the result half
+// away from zero to whole cents. Used for currency conversion, where the
+// factor may be the quotient of two rates.
+func (a Amount) MulRat(r *big.Rat) Amount {
+	product := new(big.Int).Mul(big.NewInt(int64(a)), r.Num())
+	return Amount(roundQuo(product, r.Denom()).Int64())
+}
+
+// Float64 returns the amount as a float for display purposes only.
+func (a Amount) Float64() float64 {
+	return float64(a) / 100
+}
+
+func (a Amount) String() string {
+	return formatFixed(int64(a), amountScale)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and numeric strings.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalFixed(data, amountScale)
+	if err != nil {
+		return err
+	}
+	*a = Amount(value)
+	return nil
+}
+
+func (a *Amount) Scan(src any) error {
+	value, err := scanFixed(src, amountScale)
+	if err != nil {
+		return err
+	}
+	*a = Amount(value)
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// ParseRate parses a decimal string such as "0.5". Digits beyond the sixth
+// decimal place are rounded half away from zero.
+func ParseRate(s string) (Rate, error) {
+	value, err := parseFixed(s, rateScale)
+	return Rate(value), err
+}
+
+// MustParseRate is like ParseRate but panics on invalid input.
+func MustParseRate(s string) Rate {
+	rate, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return rate
+}
+
+// Rat returns the rate as an exact fraction.
+func (r Rate) Rat() *big.Rat {
+	return big.NewRat(int64(r), rateUnit)
+}
+
+// Float64 returns the rate as a float for display purposes only.
+func (r Rate) Float64() float64 {
+	return float64(r) / rateUnit
+}
+
+func (r Rate) String() string {
+	return formatFixed(int64(r), rateScale)
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(strings.TrimRight(strings.TrimRight(r.String(), "0"), ".")), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and numeric strings.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalFixed(data, rateScale)
+	if err != nil {
+		return err
+	}
+	*r = Rate(value)
+	return nil
+}
+
+func (r *Rate) Scan(src any) error {
+	value, err := scanFixed(src, rateScale)
+	if err != nil {
+		return err
+	}
+	*r = Rate(value)
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// parseFixed parses a decimal string into an integer scaled by 10^scale.
+func parseFixed(s string, scale int) (int64, error) {
+	s = strings.TrimSpace(s)
+	if !isDecimal(s) {
+		return 0, fmt.Errorf("invalid decimal %q", s)
+	}
+	rat, _ := new(big.Rat).SetString(s)
+
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	numerator := new(big.Int).Mul(rat.Num(), factor)
+	value := roundQuo(numerator, rat.Denom())
+	if !value.IsInt64() {
+		return 0, fmt.Errorf("decimal %q out of range", s)
+	}
+	return value.Int64(), nil
+}
+
+// isDecimal reports whether s is a plain decimal number like "-12.30".
+func isDecimal(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	integer, fraction, _ := strings.Cut(s, ".")
+	if integer == "" && fraction == "" {
+		return false
+	}
+	for _, c := range integer + fraction {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// roundQuo divides x by y and rounds half away from zero. y must be positive.
+func roundQuo(x *big.Int, y *big.Int) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(x, y, new(big.Int))
+	doubled := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2))
+	if doubled.Cmp(y) >= 0 {
+		if x.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return quotient
+}
+
+func formatFixed(value int64, scale int) string {
+	sign := ""
+	digits := strconv.FormatUint(uint64(value), 10)
+	if value < 0 {
+		sign = "-"
+		digits = strconv.FormatUint(uint64(-value), 10)
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+func unmarshalFixed(data []byte, scale int) (int64, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return 0, nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	return parseFixed(string(data), scale)
+}
+
+func scanFixed(src any, scale int) (int64, error) {
+	switch v := src.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return parseFixed(v, scale)
+	case []byte:
+		return parseFixed(string(v), scale)
+	case int64:
+		return parseFixed(strconv.FormatInt(v, 10), scale)
+	case float64:
+		// Shortest representation that round-trips, e.g. 0.1 instead of
+		// 0.1000000000000000055511151231257827
+		return parseFixed(strconv.FormatFloat(v, 'f', -1, 64), scale)
+	default:
+		return 0, errors.New("unsupported type for decimal value")
+	}
+}
@@ -0,0 +1,118 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Amount
+	}{
+		{"1234.56", 123456},
+		{"-0.1", -10},
+		{"12", 1200},
+		{".5", 50},
+		{"0.005", 1},
+		{"-0.005", -1},
+		{"0.0049", 0},
+	}
+
+	for _, test := range tests {
+		amount, err := ParseAmount(test.input)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", test.input, err)
+			continue
+		}
+		if amount != test.expected {
+			t.Errorf("Expected %q to parse to %d, but got %d", test.input, test.expected, amount)
+		}
+	}
+
+	for _, input := range []string{"", "abc", "1e3", "1/2", "1.2.3", "-"} {
+		if _, err := ParseAmount(input); err == nil {
+			t.Errorf("Expected error parsing %q", input)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := map[Amount]string{
+		123456: "1234.56",
+		-10:    "-0.10",
+		5:      "0.05",
+		0:      "0.00",
+	}
+
+	for amount, expected := range tests {
+		if amount.String() != expected {
+			t.Errorf("Expected %d to format as %q, but got %q", amount, expected, amount.String())
+		}
+	}
+}
+
+func TestAmountMulRate(t *testing.T) {
+	tests := []struct {
+		amount   Amount
+		rate     Rate
+		expected Amount
+	}{
+		{MustParseAmount("200.00"), MustParseRate("0.5"), MustParseAmount("100.00")},
+		{MustParseAmount("0.01"), MustParseRate("0.5"), MustParseAmount("0.01")},
+		{MustParseAmount("-0.01"), MustParseRate("0.5"), MustParseAmount("-0.01")},
+		{MustParseAmount("10.00"), MustParseRate("0.333333"), MustParseAmount("3.33")},
+		{MustParseAmount("0.03"), MustParseRate("0.5"), MustParseAmount("0.02")},
+	}
+
+	for _, test := range tests {
+		result := test.amount.MulRate(test.rate)
+		if result != test.expected {
+			t.Errorf("Expected %s * %s = %s, but got %s", test.amount, test.rate, test.expected, result)
+		}
+	}
+}
+
+func TestAmountJSONRoundTrip(t *testing.T) {
+	var decoded struct {
+		Number Amount `json:"number"`
+		String Amount `json:"string"`
+	}
+	err := json.Unmarshal([]byte(`{"number": 1000.1, "string": "-0.30"}`), &decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded.Number != 100010 || decoded.String != -30 {
+		t.Errorf("Expected 100010 and -30, but got %d and %d", decoded.Number, decoded.String)
+	}
+
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(encoded) != `{"number":1000.10,"string":-0.30}` {
+		t.Errorf("Unexpected encoding %s", encoded)
+	}
+}
+
+func TestScan(t *testing.T) {
+	var amount Amount
+	if err := amount.Scan("15.20"); err != nil || amount != 1520 {
+		t.Errorf("Expected 1520, but got %d (%v)", amount, err)
+	}
+
+	var rate Rate
+	if err := rate.Scan(0.1); err != nil || rate != 100000 {
+		t.Errorf("Expected 100000, but got %d (%v)", rate, err)
+	}
+}
+
+func TestAmountMulRat(t *testing.T) {
+	// 100 USD to EUR at 1 EUR = 1.0823 USD
+	factor := new(big.Rat).Inv(MustParseRate("1.0823").Rat())
+	result := MustParseAmount("100.00").MulRat(factor)
+	if result != MustParseAmount("92.40") {
+		t.Errorf("Expected 92.40, but got %s", result)
+	}
+}
@@ -6,12 +6,17 @@ import (
 	"os"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/Leander-s/money_manager/api"
 	"github.com/Leander-s/money_manager/db"
 	"github.com/Leander-s/money_manager/logic"
 )
 
+// How often background jobs such as recurring entries, digests, webhook
+// retries and reloading the access token keyset are run
+const schedulerInterval = time.Minute
+
 func initContext() (ctx *api.Context) {
 	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
 	fmt.Println("Allowed Origins:", allowedOrigins)
@@ -41,6 +46,32 @@ func initContext() (ctx *api.Context) {
 	}
 	fmt.Println("Successfully loaded Brevo config")
 
+	if ratesFile := os.Getenv("EXCHANGE_RATES_FILE"); ratesFile != "" {
+		count, err := logic.LoadExchangeRatesFile(&db, ratesFile)
+		if err != nil {
+			fmt.Println("Error loading exchange rates:", err)
+			panic(err)
+		}
+		fmt.Println("Successfully loaded", count, "exchange rates from", ratesFile)
+	}
+
+	// Access tokens are signed instead of stored when a keyset is configured
+	var accessTokens *logic.AccessTokenSigner
+	if keysetFile := os.Getenv("ACCESS_TOKEN_KEYSET_FILE"); keysetFile != "" {
+		accessTokens, err = logic.NewAccessTokenSigner(keysetFile, os.Getenv("ACCESS_TOKEN_REVOCATION_FILE"))
+		if err != nil {
+			fmt.Println("Error loading access token keyset:", err)
+			panic(err)
+		}
+		fmt.Println("Successfully loaded access token keyset from", keysetFile)
+	}
+
+	trustedProxies, err := api.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		fmt.Println("Error parsing trusted proxies:", err)
+		panic(err)
+	}
+
 	ctx = &api.Context{
 		Db:             &db,
 		AllowedOrigins: allowedOrigins,
@@ -48,6 +79,9 @@ func initContext() (ctx *api.Context) {
 		HostAddress:    os.Getenv("HOST_ADDRESS"),
 		FronendAddress: os.Getenv("FRONTEND_ADDRESS"),
 		NoUsers:        noUsers,
+		Events:         logic.NewBalanceHub(),
+		AccessTokens:   accessTokens,
+		TrustedProxies: trustedProxies,
 	}
 
 	if ctx.HostAddress == "http://localhost:8080" {
@@ -62,22 +96,86 @@ func runServer(ctx *api.Context) {
 
 	mux.Handle("/", http.HandlerFunc(ctx.RootHandler))
 
-	// Budget handler is not used
-	// TODO: remove or implement
+	// Budget handler to get all category budgets or create a new one
 	mux.Handle("/budget", ctx.WithAuth(http.HandlerFunc(ctx.BudgetHandler)))
+	mux.Handle("/budget/id/", ctx.WithAuth(http.HandlerFunc(ctx.BudgetHandlerByID)))
+	// Spent and remaining budget of the current period, or of all periods of one budget
+	mux.Handle("/budget/status", ctx.WithAuth(http.HandlerFunc(ctx.BudgetStatusHandler)))
+	mux.Handle("/budget/status/", ctx.WithAuth(http.HandlerFunc(ctx.BudgetStatusHandler)))
 	// Balance handler to get all balances or insert a new one
 	mux.Handle("/balance", ctx.WithAuth(http.HandlerFunc(ctx.BalanceHandler)))
 	// Balance handler to get the n last balances
 	mux.Handle("/balance/count/", ctx.WithAuth(http.HandlerFunc(ctx.BalanceHandlerByCount)))
 	mux.Handle("/balance/id/", ctx.WithAuth(http.HandlerFunc(ctx.BalanceHandlerByID)))
+	// Import of bank statements, optionally as a preview
+	mux.Handle("/balance/import", ctx.WithAuth(http.HandlerFunc(ctx.BalanceImportHandler)))
+	// Projected balance and budget for the next days
+	mux.Handle("/balance/forecast", ctx.WithAuth(http.HandlerFunc(ctx.BalanceForecastHandler)))
+
+	// Stream of balance changes made from any device
+	mux.Handle("/events", ctx.WithAuth(http.HandlerFunc(ctx.EventsHandler)))
+
+	// Account handler to get all accounts or create a new one
+	mux.Handle("/account", ctx.WithAuth(http.HandlerFunc(ctx.AccountHandler)))
+	mux.Handle("/account/id/", ctx.WithAuth(http.HandlerFunc(ctx.AccountHandlerByID)))
+	// Latest balance and budget summed up across all accounts
+	mux.Handle("/account/networth", ctx.WithAuth(http.HandlerFunc(ctx.NetWorthHandler)))
+
+	// Exchange rates used for reporting currencies, imported from CSV or ECB XML
+	mux.Handle("/exchange-rate", ctx.WithAuth(http.HandlerFunc(ctx.ExchangeRateHandler)))
+
+	// Recurring income and expense schedules
+	mux.Handle("/recurring", ctx.WithAuth(http.HandlerFunc(ctx.RecurringHandler)))
+	mux.Handle("/recurring/id/", ctx.WithAuth(http.HandlerFunc(ctx.RecurringHandlerByID)))
+
+	// Savings goal handlers and their progress
+	mux.Handle("/goal", ctx.WithAuth(http.HandlerFunc(ctx.GoalHandler)))
+	mux.Handle("/goal/id/", ctx.WithAuth(http.HandlerFunc(ctx.GoalHandlerByID)))
+	mux.Handle("/goal/progress", ctx.WithAuth(http.HandlerFunc(ctx.GoalProgressHandler)))
+	mux.Handle("/goal/progress/", ctx.WithAuth(http.HandlerFunc(ctx.GoalProgressHandler)))
+
+	// Transaction handler to get all transactions or insert a new one
+	mux.Handle("/transaction", ctx.WithAuth(http.HandlerFunc(ctx.TransactionHandler)))
+	mux.Handle("/transaction/id/", ctx.WithAuth(http.HandlerFunc(ctx.TransactionHandlerByID)))
+	// Transaction totals grouped by category
+	mux.Handle("/transaction/category", ctx.WithAuth(http.HandlerFunc(ctx.TransactionCategoryHandler)))
+
+	// Monthly or yearly inflow, outflow, budget and savings of an account
+	mux.Handle("/report", ctx.WithAuth(http.HandlerFunc(ctx.ReportHandler)))
+
+	// Weekly or monthly summary emails
+	mux.Handle("/digest", ctx.WithAuth(http.HandlerFunc(ctx.DigestHandler)))
+
+	// Alerts when the budget or balance of an account falls below a threshold
+	// and the log of their webhook deliveries
+	mux.Handle("/alert", ctx.WithAuth(http.HandlerFunc(ctx.AlertHandler)))
+	mux.Handle("/alert/id/", ctx.WithAuth(http.HandlerFunc(ctx.AlertHandlerByID)))
+	mux.Handle("/alert/deliveries/", ctx.WithAuth(http.HandlerFunc(ctx.AlertDeliveryHandler)))
+
+	// Webhooks receiving balance events and the log of their deliveries
+	mux.Handle("/webhook", ctx.WithAuth(http.HandlerFunc(ctx.WebhookHandler)))
+	mux.Handle("/webhook/id/", ctx.WithAuth(http.HandlerFunc(ctx.WebhookHandlerByID)))
+	mux.Handle("/webhook/deliveries/", ctx.WithAuth(http.HandlerFunc(ctx.WebhookDeliveryHandler)))
+
+	// Export of all entries, accounts and transactions as CSV, JSON or OFX
+	mux.Handle("/export", ctx.WithAuth(http.HandlerFunc(ctx.ExportHandler)))
+
+	// Takeout of all data of the user and scheduled erasure of the account
+	mux.Handle("/takeout", ctx.WithAuth(http.HandlerFunc(ctx.TakeoutHandler)))
+	mux.Handle("/erasure", ctx.WithAuth(http.HandlerFunc(ctx.ErasureHandler)))
 
 	// User handler to create a new user or get all users
 	mux.Handle("/user", ctx.WithAuth(http.HandlerFunc(ctx.UserHandler)))
 	// User handler to get, update or delete a user by ID
 	mux.Handle("/user/", ctx.WithAuth(http.HandlerFunc(ctx.UserHandlerByID)))
 
+	// Sessions of the user on all devices, to list and log them out
+	mux.Handle("/session", ctx.WithAuth(http.HandlerFunc(ctx.SessionHandler)))
+	mux.Handle("/session/", ctx.WithAuth(http.HandlerFunc(ctx.SessionHandlerByID)))
+
 	// Handlers for authentication
 	mux.HandleFunc("/login", ctx.LoginHandler)
+	mux.HandleFunc("/token/refresh", ctx.RefreshHandler)
 	mux.HandleFunc("/register", ctx.RegisterHandler)
 	mux.HandleFunc("/verify-email/", ctx.VerifyEmailHandler)
 	mux.HandleFunc("/reset-password", ctx.ResetPasswordHandler)
@@ -85,6 +183,12 @@ func runServer(ctx *api.Context) {
 
 	muxWithCORS := withCORS(mux, ctx.AllowedOrigins)
 
+	logic.OnBalanceChange(logic.NewAlertNotifier(ctx.Db, ctx.MailConfig))
+	logic.OnBalanceChange(logic.NewWebhookDispatcher(ctx.Db))
+	logic.OnBalanceChange(ctx.Events.Publish)
+
+	go runScheduler(ctx, schedulerInterval)
+
 	Port := os.Getenv("PORT")
 	err := http.ListenAndServe("0.0.0.0:"+Port, muxWithCORS)
 	if err != nil {
@@ -93,6 +197,52 @@ func runServer(ctx *api.Context) {
 	}
 }
 
+// runScheduler runs the background jobs once on startup and then every
+// interval until the process exits.
+func runScheduler(ctx *api.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		runScheduledJobs(ctx)
+		<-ticker.C
+	}
+}
+
+func runScheduledJobs(ctx *api.Context) {
+	if ctx.AccessTokens != nil {
+		reloaded, err := ctx.AccessTokens.Reload()
+		if err != nil {
+			fmt.Println("Error reloading access token keyset:", err)
+		} else if reloaded {
+			fmt.Println("Reloaded access token keyset")
+		}
+	}
+
+	// Expired tokens and the sessions they leave behind
+	logic.DeleteExpiredTokens(ctx.Db)
+
+	count := logic.MaterializeRecurring(ctx.Db, time.Now())
+	if count > 0 {
+		fmt.Println("Materialized", count, "recurring entries")
+	}
+
+	sent := logic.SendDueDigests(ctx.Db, ctx.MailConfig, time.Now())
+	if sent > 0 {
+		fmt.Println("Sent", sent, "digests")
+	}
+
+	delivered := logic.DeliverDueWebhooks(ctx.Db, time.Now())
+	if delivered > 0 {
+		fmt.Println("Delivered", delivered, "webhooks")
+	}
+
+	erased := logic.ExecuteDueErasures(ctx.Db, time.Now())
+	if erased > 0 {
+		fmt.Println("Erased", erased, "users after their grace period")
+	}
+}
+
 func withCORS(next http.Handler, allowedOrigins string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// allow your Angular dev server
@@ -103,6 +253,7 @@ func withCORS(next http.Handler, allowedOrigins string) http.Handler {
 		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor, Content-Disposition")
 
 		// handle preflight (OPTIONS) requests quickly
 		if r.Method == http.MethodOptions {